@@ -15,7 +15,7 @@ import (
 type zoneAddr struct {
 	Zone      string
 	Port      string
-	Transport string     // dns, tls or grpc
+	Transport string     // dns, tls, grpc, https or quic
 	IPNet     *net.IPNet // if reverse zone this hold the IPNet
 	Address   string     // used for bound zoneAddr - validation of overlapping
 }
@@ -52,6 +52,8 @@ func normalizeZone(str string) (zoneAddr, error) {
 			port = transport.GRPCPort
 		case transport.HTTPS:
 			port = transport.HTTPSPort
+		case transport.QUIC:
+			port = transport.QUICPort
 		}
 	}
 
@@ -85,7 +87,18 @@ func newOverlapZone() *zoneOverlap {
 // registerAndCheck adds a new zoneAddr for validation, it returns information about existing or overlapping with already registered
 // we consider that an unbound address is overlapping all bound addresses for same zone, same port
 func (zo *zoneOverlap) registerAndCheck(z zoneAddr) (existingZone *zoneAddr, overlappingZone *zoneAddr) {
+	existingZone, overlappingZone = zo.check(z)
+	if existingZone != nil || overlappingZone != nil {
+		return existingZone, overlappingZone
+	}
+	// there is no overlap, keep the current zoneAddr for future checks
+	zo.registeredAddr[z] = z
+	zo.unboundOverlap[zoneAddr{Zone: z.Zone, Address: "", Port: z.Port, Transport: z.Transport}] = z
+	return nil, nil
+}
 
+// check is like registerAndCheck, but it does not register z.
+func (zo *zoneOverlap) check(z zoneAddr) (existingZone *zoneAddr, overlappingZone *zoneAddr) {
 	if exist, ok := zo.registeredAddr[z]; ok {
 		// exact same zone already registered
 		return &exist, nil
@@ -101,8 +114,5 @@ func (zo *zoneOverlap) registerAndCheck(z zoneAddr) (existingZone *zoneAddr, ove
 			return nil, &uz
 		}
 	}
-	// there is no overlap, keep the current zoneAddr for future checks
-	zo.registeredAddr[z] = z
-	zo.unboundOverlap[uz] = z
 	return nil, nil
 }
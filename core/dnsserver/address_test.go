@@ -28,6 +28,10 @@ func TestNormalizeZone(t *testing.T) {
 		{"https://.:8443", "https://.:8443", false},
 		{"https://..", "://:", true},
 		{"https://.:", "://:", true},
+		{"quic://.", "quic://.:853", false},
+		{"quic://.:8853", "quic://.:8853", false},
+		{"quic://..", "://:", true},
+		{"quic://.:", "://:", true},
 	} {
 		addr, err := normalizeZone(test.input)
 		actual := addr.String()
@@ -90,6 +94,7 @@ func TestSplitProtocolHostPort(t *testing.T) {
 		{"dns://:53", "dns", "", "53", false},
 		{"dns://127.0.0.1:4005", "dns", "127.0.0.1", "4005", false},
 		{"[ffe0:34ab:1]:4005", "", "ffe0:34ab:1", "4005", false},
+		{"quic://[::]:853", "quic", "::", "853", false},
 
 		// port part is mandatory
 		{"dns://", "dns", "", "", true},
@@ -1,10 +1,14 @@
 package dnsserver
 
 import (
+	"context"
 	"crypto/tls"
 	"fmt"
+	"time"
 
 	"github.com/coredns/coredns/plugin"
+	"github.com/coredns/coredns/plugin/pkg/proxyproto"
+	"github.com/coredns/coredns/request"
 
 	"github.com/mholt/caddy"
 )
@@ -29,7 +33,7 @@ type Config struct {
 	Debug bool
 
 	// The transport we implement, normally just "dns" over TCP/UDP, but could be
-	// DNS-over-TLS or DNS-over-gRPC.
+	// DNS-over-TLS, DNS-over-gRPC, DNS-over-HTTPS or DNS-over-QUIC.
 	Transport string
 
 	// If this function is not nil it will be used to further filter access
@@ -37,15 +41,51 @@ type Config struct {
 	// on a non-octet boundary, i.e. /17
 	FilterFunc func(string) bool
 
-	// TLSConfig when listening for encrypted connections (gRPC, DNS-over-TLS).
+	// ViewName is the name of the view this server block is part of, empty when no view is used.
+	ViewName string
+
+	// If ViewFunc is not nil it is called for every query to see if this server block should
+	// handle it. Several server blocks may serve the same zone on the same address when they
+	// have a view, they are tried in the order they are defined.
+	ViewFunc func(context.Context, request.Request) bool
+
+	// TLSConfig when listening for encrypted connections (gRPC, DNS-over-TLS, DNS-over-QUIC).
 	TLSConfig *tls.Config
 
+	// ProxyProtocol when the PROXY protocol headers of a load balancer should be read (DNS,
+	// DNS-over-TLS, DNS-over-HTTPS).
+	ProxyProtocol *proxyproto.Config
+
+	// TCP configures the handling of DNS (TCP) and DNS-over-TLS connections, when nil the
+	// defaults of Go DNS are used.
+	TCP *TCPConfig
+
+	// Timeout bounds the time a query may spend in the plugin chain, once it passes the context
+	// of the query is canceled. Zero means no timeout.
+	Timeout time.Duration
+
+	// NumSockets is the number of sockets that listen on the address of a DNS (TCP/UDP) server,
+	// each served by its own goroutines. When set, UDP messages are also read and written in
+	// batches where this is supported. The default of 0 opens a single socket.
+	NumSockets int
+
+	// Fingerprint identifies the configuration of this zone: Configs with the same fingerprint have
+	// the same key and come from the same server block, see Fingerprint. Plugins use it to keep
+	// state across reloads.
+	Fingerprint string
+
 	// Plugin stack.
 	Plugin []plugin.Plugin
 
 	// Compiled plugin stack.
 	pluginChain plugin.Handler
 
+	// The handlers of the compiled plugin stack, in the order they see a query.
+	chain []plugin.Handler
+
+	// The plugin that collects metadata before the plugin chain is called, if any.
+	metaCollector MetadataCollector
+
 	// Plugin interested in announcing that they exist, so other plugin can call methods
 	// on them should register themselves here. The name should be the name as return by the
 	// Handler's Name method.
@@ -71,3 +111,10 @@ func GetConfig(c *caddy.Controller) *Config {
 	ctx.saveConfig(key, &Config{ListenHosts: []string{""}})
 	return GetConfig(c)
 }
+
+// GetConfigs returns the Configs of all server blocks. The plugin handlers of a Config are only
+// registered once the servers have been made, so use this from an OnStartup function to inspect those.
+func GetConfigs(c *caddy.Controller) []*Config {
+	ctx := c.Context().(*dnsContext)
+	return ctx.configs
+}
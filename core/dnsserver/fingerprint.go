@@ -0,0 +1,41 @@
+package dnsserver
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"sort"
+
+	"github.com/mholt/caddy/caddyfile"
+)
+
+// Fingerprint returns a fingerprint of the server block sb, a hash of its keys and the tokens of its
+// directives. Comments, indentation and the order of the directives don't change it, the directives
+// are executed in the order of Directives anyway. Which tokens are on the same line does.
+func Fingerprint(sb caddyfile.ServerBlock) string {
+	h := sha256.New()
+	for _, k := range sb.Keys {
+		io.WriteString(h, k)
+		io.WriteString(h, " ")
+	}
+
+	dirs := make([]string, 0, len(sb.Tokens))
+	for d := range sb.Tokens {
+		dirs = append(dirs, d)
+	}
+	sort.Strings(dirs)
+
+	for _, d := range dirs {
+		line := -1
+		for _, t := range sb.Tokens[d] {
+			if t.Line != line {
+				io.WriteString(h, "\n")
+				line = t.Line
+			} else {
+				io.WriteString(h, " ")
+			}
+			io.WriteString(h, t.Text)
+		}
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
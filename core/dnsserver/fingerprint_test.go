@@ -0,0 +1,46 @@
+package dnsserver
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/mholt/caddy/caddyfile"
+)
+
+func TestFingerprint(t *testing.T) {
+	base := `example.org {
+    cache 30
+    forward . 8.8.8.8
+}`
+	tests := []struct {
+		input string
+		same  bool
+	}{
+		{base, true},
+		{"example.org {\n# comment\n\tforward . 8.8.8.8\n\n\tcache 30\n}", true},
+		{"example.org {\n    cache 60\n    forward . 8.8.8.8\n}", false},
+		{"example.org {\n    cache 30 {\n        success 100\n    }\n    forward . 8.8.8.8\n}", false},
+		{"example.org {\n    cache 30 {\n        success\n        100\n    }\n    forward . 8.8.8.8\n}", false},
+		{"example.org:1053 {\n    cache 30\n    forward . 8.8.8.8\n}", false},
+	}
+
+	want := fingerprint(t, base)
+	for i, tc := range tests {
+		if got := fingerprint(t, tc.input); (got == want) != tc.same {
+			t.Errorf("Test %d: expected same fingerprint to be %t", i, tc.same)
+		}
+	}
+
+	// Tokens on separate lines are different properties.
+	if fingerprint(t, tests[3].input) == fingerprint(t, tests[4].input) {
+		t.Errorf("Expected fingerprint to depend on the lines of the tokens")
+	}
+}
+
+func fingerprint(t *testing.T, input string) string {
+	sbs, err := caddyfile.Parse("Corefile", strings.NewReader(input), Directives)
+	if err != nil {
+		t.Fatalf("Failed to parse %q: %s", input, err)
+	}
+	return Fingerprint(sbs[0])
+}
@@ -12,6 +12,9 @@ import (
 	"golang.org/x/sys/unix"
 )
 
+// reuseport is true when several sockets can listen on the same address.
+const reuseport = true
+
 func reuseportControl(network, address string, c syscall.RawConn) error {
 	c.Control(func(fd uintptr) {
 		if err := unix.SetsockoptInt(int(fd), unix.SOL_SOCKET, unix.SO_REUSEPORT, 1); err != nil {
@@ -30,3 +33,22 @@ func listenPacket(network, addr string) (net.PacketConn, error) {
 	lc := net.ListenConfig{Control: reuseportControl}
 	return lc.ListenPacket(context.Background(), network, addr)
 }
+
+// socketBuffers returns the sizes of the receive and send buffers of the socket of c.
+func socketBuffers(c syscall.Conn) (rcvbuf, sndbuf int, err error) {
+	rc, err := c.SyscallConn()
+	if err != nil {
+		return 0, 0, err
+	}
+	var serr error
+	err = rc.Control(func(fd uintptr) {
+		if rcvbuf, serr = unix.GetsockoptInt(int(fd), unix.SOL_SOCKET, unix.SO_RCVBUF); serr != nil {
+			return
+		}
+		sndbuf, serr = unix.GetsockoptInt(int(fd), unix.SOL_SOCKET, unix.SO_SNDBUF)
+	})
+	if err != nil {
+		return 0, 0, err
+	}
+	return rcvbuf, sndbuf, serr
+}
@@ -2,10 +2,22 @@
 
 package dnsserver
 
-import "net"
+import (
+	"errors"
+	"net"
+	"syscall"
+)
+
+// reuseport is true when several sockets can listen on the same address.
+const reuseport = false
 
 func listen(network, addr string) (net.Listener, error) { return net.Listen(network, addr) }
 
 func listenPacket(network, addr string) (net.PacketConn, error) {
 	return net.ListenPacket(network, addr)
 }
+
+// socketBuffers returns an error, the buffer sizes are not available on this platform.
+func socketBuffers(c syscall.Conn) (rcvbuf, sndbuf int, err error) {
+	return 0, 0, errors.New("socket buffer sizes not supported")
+}
@@ -0,0 +1,57 @@
+package dnsserver
+
+import (
+	"encoding/binary"
+	"net"
+
+	"github.com/miekg/dns"
+	quic "github.com/quic-go/quic-go"
+)
+
+// DoQWriter is a dns.ResponseWriter that writes the reply to a QUIC stream, as specified in RFC 9250.
+type DoQWriter struct {
+	localAddr  net.Addr
+	remoteAddr net.Addr
+	stream     *quic.Stream
+	Msg        *dns.Msg
+}
+
+// Write writes b, prefixed with its 2-octet length, to the stream.
+func (w *DoQWriter) Write(b []byte) (int, error) {
+	n, err := w.stream.Write(addPrefix(b))
+	if n >= 2 {
+		n -= 2
+	}
+	return n, err
+}
+
+// WriteMsg packs m, writes it to the stream and closes the stream; a DoQ stream carries exactly
+// one reply.
+func (w *DoQWriter) WriteMsg(m *dns.Msg) error {
+	buf, err := m.Pack()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(buf); err != nil {
+		return err
+	}
+	return w.Close()
+}
+
+// Close sends the STREAM FIN to the client.
+func (w *DoQWriter) Close() error { return w.stream.Close() }
+
+// These methods implement the dns.ResponseWriter interface from Go DNS.
+func (w *DoQWriter) TsigStatus() error     { return nil }
+func (w *DoQWriter) TsigTimersOnly(b bool) { return }
+func (w *DoQWriter) Hijack()               { return }
+func (w *DoQWriter) LocalAddr() net.Addr   { return w.localAddr }
+func (w *DoQWriter) RemoteAddr() net.Addr  { return w.remoteAddr }
+
+// addPrefix adds the 2-octet length prefix DoQ (like DNS over TCP) uses on the wire.
+func addPrefix(b []byte) []byte {
+	m := make([]byte, 2+len(b))
+	binary.BigEndian.PutUint16(m, uint16(len(b)))
+	copy(m[2:], b)
+	return m
+}
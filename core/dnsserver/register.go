@@ -37,7 +37,32 @@ func init() {
 }
 
 func newContext(i *caddy.Instance) caddy.Context {
-	return &dnsContext{keysToConfigs: make(map[string]*Config)}
+	ctx := &dnsContext{keysToConfigs: make(map[string]*Config)}
+	if validateContext != nil {
+		validateContext(ctx)
+	}
+	return ctx
+}
+
+// validateContext is called with every new context while Validate runs.
+var validateContext func(*dnsContext)
+
+// Validate parses the Corefile in input, executes the setup function of every plugin and builds
+// the servers with their plugin chains. The servers don't listen and the OnStartup functions of
+// the plugins are not called.
+func Validate(input caddy.Input) error {
+	var ctx *dnsContext
+	validateContext = func(c *dnsContext) { ctx = c }
+	defer func() { validateContext = nil }()
+
+	if err := caddy.ValidateAndExecuteDirectives(input, nil, true); err != nil {
+		return err
+	}
+	if ctx == nil {
+		return fmt.Errorf("no %s server type configuration in %s", serverType, input.Path())
+	}
+	_, err := ctx.MakeServers()
+	return err
 }
 
 type dnsContext struct {
@@ -58,6 +83,8 @@ func (h *dnsContext) saveConfig(key string, cfg *Config) {
 func (h *dnsContext) InspectServerBlocks(sourceFile string, serverBlocks []caddyfile.ServerBlock) ([]caddyfile.ServerBlock, error) {
 	// Normalize and check all the zone names and check for duplicates
 	for ib, s := range serverBlocks {
+		// The fingerprint is taken before the keys are normalized, like the reload plugin does.
+		fp := Fingerprint(s)
 		for ik, k := range s.Keys {
 			za, err := normalizeZone(k)
 			if err != nil {
@@ -70,6 +97,7 @@ func (h *dnsContext) InspectServerBlocks(sourceFile string, serverBlocks []caddy
 				ListenHosts: []string{""},
 				Port:        za.Port,
 				Transport:   za.Transport,
+				Fingerprint: fp + ":" + za.String(),
 			}
 			keyConfig := keyForConfig(ib, ik)
 			if za.IPNet == nil {
@@ -115,11 +143,18 @@ func (h *dnsContext) MakeServers() ([]caddy.Server, error) {
 		// switch on addr
 		switch tr, _ := parse.Transport(addr); tr {
 		case transport.DNS:
-			s, err := NewServer(addr, group)
-			if err != nil {
-				return nil, err
+			n := numSockets(group)
+			if n > 1 && !reuseport {
+				return nil, fmt.Errorf("can not open %d sockets for %s: SO_REUSEPORT is not supported on this platform", n, addr)
+			}
+			for i := 0; i < n; i++ {
+				s, err := NewServer(addr, group)
+				if err != nil {
+					return nil, err
+				}
+				s.socket = i
+				servers = append(servers, s)
 			}
-			servers = append(servers, s)
 
 		case transport.TLS:
 			s, err := NewServerTLS(addr, group)
@@ -141,6 +176,13 @@ func (h *dnsContext) MakeServers() ([]caddy.Server, error) {
 				return nil, err
 			}
 			servers = append(servers, s)
+
+		case transport.QUIC:
+			s, err := NewServerQUIC(addr, group)
+			if err != nil {
+				return nil, err
+			}
+			servers = append(servers, s)
 		}
 
 	}
@@ -148,6 +190,17 @@ func (h *dnsContext) MakeServers() ([]caddy.Server, error) {
 	return servers, nil
 }
 
+// numSockets returns the number of sockets to open for the configs in group, at least 1.
+func numSockets(group []*Config) int {
+	n := 1
+	for _, c := range group {
+		if c.NumSockets > n {
+			n = c.NumSockets
+		}
+	}
+	return n
+}
+
 // AddPlugin adds a plugin to a site's plugin stack.
 func (c *Config) AddPlugin(m plugin.Plugin) {
 	c.Plugin = append(c.Plugin, m)
@@ -193,6 +246,10 @@ func (c *Config) Handlers() []plugin.Handler {
 	return hs
 }
 
+// Chain returns the handlers of the compiled plugin stack in the order they handle a query. Unlike
+// Handlers it also lists plugins that share a name. It returns nil until the servers have been made.
+func (c *Config) Chain() []plugin.Handler { return c.chain }
+
 func (h *dnsContext) validateZonesAndListeningAddresses() error {
 	//Validate Zone and addresses
 	checker := newOverlapZone()
@@ -200,8 +257,18 @@ func (h *dnsContext) validateZonesAndListeningAddresses() error {
 		for _, h := range conf.ListenHosts {
 			// Validate the overlapping of ZoneAddr
 			akey := zoneAddr{Transport: conf.Transport, Zone: conf.Zone, Address: h, Port: conf.Port}
-			existZone, overlapZone := checker.registerAndCheck(akey)
+			var existZone, overlapZone *zoneAddr
+			if conf.ViewFunc != nil {
+				// Server blocks with a view may share the zone and address, as long as they are
+				// defined before the server block without a view (that would handle all queries).
+				existZone, overlapZone = checker.check(akey)
+			} else {
+				existZone, overlapZone = checker.registerAndCheck(akey)
+			}
 			if existZone != nil {
+				if conf.ViewFunc != nil {
+					return fmt.Errorf("cannot serve %s in view %q - it is already defined without a view before it", akey.String(), conf.ViewName)
+				}
 				return fmt.Errorf("cannot serve %s - it is already defined", akey.String())
 			}
 			if overlapZone != nil {
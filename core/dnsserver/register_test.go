@@ -1,7 +1,11 @@
 package dnsserver
 
 import (
+	"context"
 	"testing"
+
+	"github.com/coredns/coredns/plugin"
+	"github.com/coredns/coredns/request"
 )
 
 func TestHandler(t *testing.T) {
@@ -30,6 +34,41 @@ func TestHandlers(t *testing.T) {
 	}
 }
 
+func TestChain(t *testing.T) {
+	tp := testPlugin{}
+	c := testConfig("dns", tp)
+	c.AddPlugin(func(next plugin.Handler) plugin.Handler { return tp })
+	if _, err := NewServer("127.0.0.1:53", []*Config{c}); err != nil {
+		t.Errorf("Expected no error for NewServer, got %s", err)
+	}
+	if hs := c.Chain(); len(hs) != 2 || hs[0] != tp || hs[1] != tp {
+		t.Errorf("Expected [testPlugin testPlugin] from Chain, got %v", hs)
+	}
+}
+
+func TestMakeServersNumSockets(t *testing.T) {
+	c := testConfig("dns", testPlugin{})
+	c.NumSockets = 3
+	h := &dnsContext{configs: []*Config{c}}
+	if !reuseport {
+		t.Skip("SO_REUSEPORT not supported")
+	}
+	servers, err := h.MakeServers()
+	if err != nil {
+		t.Fatalf("Expected no error for MakeServers, got %s", err)
+	}
+	if len(servers) != 3 {
+		t.Fatalf("Expected 3 servers, got %d", len(servers))
+	}
+	addrs := map[string]bool{}
+	for _, s := range servers {
+		addrs[s.(*Server).Address()] = true
+	}
+	if len(addrs) != 3 {
+		t.Errorf("Expected 3 distinct addresses, got %v", addrs)
+	}
+}
+
 func TestGroupingServers(t *testing.T) {
 	for i, test := range []struct {
 		configs        []*Config
@@ -119,3 +158,43 @@ func TestGroupingServers(t *testing.T) {
 		}
 	}
 }
+
+func TestValidateZonesAndListeningAddressesViews(t *testing.T) {
+	view := func(ctx context.Context, state request.Request) bool { return true }
+	for i, test := range []struct {
+		configs []*Config
+		failing bool
+	}{
+		// views before the server block without a view
+		{configs: []*Config{
+			{Transport: "dns", Zone: ".", Port: "53", ListenHosts: []string{""}, ViewName: "a", ViewFunc: view},
+			{Transport: "dns", Zone: ".", Port: "53", ListenHosts: []string{""}, ViewName: "b", ViewFunc: view},
+			{Transport: "dns", Zone: ".", Port: "53", ListenHosts: []string{""}},
+		}, failing: false},
+		// views only
+		{configs: []*Config{
+			{Transport: "dns", Zone: ".", Port: "53", ListenHosts: []string{""}, ViewName: "a", ViewFunc: view},
+			{Transport: "dns", Zone: ".", Port: "53", ListenHosts: []string{""}, ViewName: "b", ViewFunc: view},
+		}, failing: false},
+		// view after the server block without a view is never selected
+		{configs: []*Config{
+			{Transport: "dns", Zone: ".", Port: "53", ListenHosts: []string{""}},
+			{Transport: "dns", Zone: ".", Port: "53", ListenHosts: []string{""}, ViewName: "a", ViewFunc: view},
+		}, failing: true},
+		// two server blocks without a view
+		{configs: []*Config{
+			{Transport: "dns", Zone: ".", Port: "53", ListenHosts: []string{""}, ViewName: "a", ViewFunc: view},
+			{Transport: "dns", Zone: ".", Port: "53", ListenHosts: []string{""}},
+			{Transport: "dns", Zone: ".", Port: "53", ListenHosts: []string{""}},
+		}, failing: true},
+	} {
+		h := &dnsContext{configs: test.configs}
+		err := h.validateZonesAndListeningAddresses()
+		if test.failing && err == nil {
+			t.Errorf("Test %d, expected to fail but did not", i)
+		}
+		if !test.failing && err != nil {
+			t.Errorf("Test %d, expected no errors, but got: %v", i, err)
+		}
+	}
+}
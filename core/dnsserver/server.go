@@ -7,12 +7,16 @@ import (
 	"net"
 	"runtime"
 	"sync"
+	"syscall"
 	"time"
 
 	"github.com/coredns/coredns/plugin"
 	"github.com/coredns/coredns/plugin/metrics/vars"
+	"github.com/coredns/coredns/plugin/pkg/ede"
 	"github.com/coredns/coredns/plugin/pkg/edns"
 	"github.com/coredns/coredns/plugin/pkg/log"
+	"github.com/coredns/coredns/plugin/pkg/mmsg"
+	"github.com/coredns/coredns/plugin/pkg/proxyproto"
 	"github.com/coredns/coredns/plugin/pkg/rcode"
 	"github.com/coredns/coredns/plugin/pkg/trace"
 	"github.com/coredns/coredns/plugin/pkg/transport"
@@ -28,17 +32,25 @@ import (
 // the same address and the listener may be stopped for
 // graceful termination (POSIX only).
 type Server struct {
-	Addr string // Address we listen on
-
-	server [2]*dns.Server // 0 is a net.Listener, 1 is a net.PacketConn (a *UDPConn) in our case.
-	m      sync.Mutex     // protects the servers
-
-	zones       map[string]*Config // zones keyed by their address
-	dnsWg       sync.WaitGroup     // used to wait on outstanding connections
-	connTimeout time.Duration      // the maximum duration of a graceful shutdown
-	trace       trace.Trace        // the trace plugin for the server
-	debug       bool               // disable recover()
-	classChaos  bool               // allow non-INET class queries
+	Addr   string // Address we listen on
+	socket int    // index of the socket when several sockets listen on Addr
+
+	server    [2]*dns.Server // 0 is a net.Listener, 1 is a net.PacketConn (a *UDPConn) in our case.
+	tcpServer *tcpServer     // replaces server[tcp] when TCP connection handling is configured
+	udpServer *udpServer     // replaces server[udp] when UDP messages are read and written in batches
+	m         sync.Mutex     // protects the servers
+
+	zones       map[string][]*Config // zones keyed by their address, multiple configs when views are used
+	dnsWg       sync.WaitGroup       // used to wait on outstanding connections
+	connTimeout time.Duration        // the maximum duration of a graceful shutdown
+	trace       trace.Trace          // the trace plugin for the server
+	debug       bool                 // disable recover()
+	classChaos  bool                 // allow non-INET class queries
+	pad         bool                 // pad replies, set for encrypted transports
+
+	proxyProtocol *proxyproto.Config // read PROXY protocol headers, nil when disabled
+	tcpConfig     *TCPConfig         // handling of TCP connections, nil for the defaults of Go DNS
+	batch         bool               // read and write UDP messages in batches, when supported
 }
 
 // NewServer returns a new CoreDNS server and compiles all plugins in to it. By default CH class
@@ -47,7 +59,7 @@ func NewServer(addr string, group []*Config) (*Server, error) {
 
 	s := &Server{
 		Addr:        addr,
-		zones:       make(map[string]*Config),
+		zones:       make(map[string][]*Config),
 		connTimeout: 5 * time.Second, // TODO(miek): was configurable
 	}
 
@@ -64,8 +76,17 @@ func NewServer(addr string, group []*Config) (*Server, error) {
 			s.debug = true
 			log.D = true
 		}
-		// set the config per zone
-		s.zones[site.Zone] = site
+		if site.ProxyProtocol != nil {
+			s.proxyProtocol = site.ProxyProtocol
+		}
+		if site.TCP != nil {
+			s.tcpConfig = site.TCP
+		}
+		if site.NumSockets > 0 {
+			s.batch = true
+		}
+		// set the config per zone, configs with a view are tried in the order they are defined
+		s.zones[site.Zone] = append(s.zones[site.Zone], site)
 		// compile custom plugin for everything
 		if site.registry != nil {
 			// this config is already computed with the chain of plugin
@@ -80,6 +101,11 @@ func NewServer(addr string, group []*Config) (*Server, error) {
 			if handler, ok := site.registry["trace"]; ok {
 				s.trace = handler.(trace.Trace)
 			}
+			for _, handler := range site.registry {
+				if mc, ok := handler.(MetadataCollector); ok {
+					site.metaCollector = mc
+				}
+			}
 			continue
 		}
 		var stack plugin.Handler
@@ -88,6 +114,7 @@ func NewServer(addr string, group []*Config) (*Server, error) {
 
 			// register the *handler* also
 			site.registerHandler(stack)
+			site.chain = append([]plugin.Handler{stack}, site.chain...)
 
 			if s.trace == nil && stack.Name() == "trace" {
 				// we have to stash away the plugin, not the
@@ -100,6 +127,9 @@ func NewServer(addr string, group []*Config) (*Server, error) {
 			if _, ok := enableChaos[stack.Name()]; ok {
 				s.classChaos = true
 			}
+			if mc, ok := stack.(MetadataCollector); ok {
+				site.metaCollector = mc
+			}
 		}
 		site.pluginChain = stack
 	}
@@ -111,10 +141,17 @@ func NewServer(addr string, group []*Config) (*Server, error) {
 // This implements caddy.TCPServer interface.
 func (s *Server) Serve(l net.Listener) error {
 	s.m.Lock()
-	s.server[tcp] = &dns.Server{Listener: l, Net: "tcp", Handler: dns.HandlerFunc(func(w dns.ResponseWriter, r *dns.Msg) {
+	l = s.proxyListener(l)
+	h := dns.HandlerFunc(func(w dns.ResponseWriter, r *dns.Msg) {
 		ctx := context.WithValue(context.Background(), Key{}, s)
 		s.ServeDNS(ctx, w, r)
-	})}
+	})
+	if s.tcpConfig != nil {
+		s.tcpServer = newTCPServer(s.Addr, l, h, s.tcpConfig)
+		s.m.Unlock()
+		return s.tcpServer.serve()
+	}
+	s.server[tcp] = &dns.Server{Listener: l, Net: "tcp", Handler: h}
 	s.m.Unlock()
 
 	return s.server[tcp].ActivateAndServe()
@@ -124,10 +161,26 @@ func (s *Server) Serve(l net.Listener) error {
 // This implements caddy.UDPServer interface.
 func (s *Server) ServePacket(p net.PacketConn) error {
 	s.m.Lock()
-	s.server[udp] = &dns.Server{PacketConn: p, Net: "udp", Handler: dns.HandlerFunc(func(w dns.ResponseWriter, r *dns.Msg) {
+	h := dns.HandlerFunc(func(w dns.ResponseWriter, r *dns.Msg) {
 		ctx := context.WithValue(context.Background(), Key{}, s)
 		s.ServeDNS(ctx, w, r)
-	})}
+	})
+	// The PROXY protocol headers are read by our own udpServer, it also reads in batches.
+	if uc, ok := p.(*net.UDPConn); ok && (s.batch || s.proxyProtocol != nil) {
+		var c udpConn = singleConn{uc}
+		mc, err := mmsg.New(uc)
+		if err == nil {
+			c = mc
+		} else if s.batch {
+			log.With("server", s.Addr).Warningf("Failed to read UDP messages in batches: %s", err)
+		}
+		if err == nil || s.proxyProtocol != nil {
+			s.udpServer = newUDPServer(s.Addr, c, h, s.proxyProtocol)
+			s.m.Unlock()
+			return s.udpServer.serve()
+		}
+	}
+	s.server[udp] = &dns.Server{PacketConn: p, Net: "udp", Handler: h}
 	s.m.Unlock()
 
 	return s.server[udp].ActivateAndServe()
@@ -139,6 +192,7 @@ func (s *Server) Listen() (net.Listener, error) {
 	if err != nil {
 		return nil, err
 	}
+	s.reportBuffers("tcp", l)
 	return l, nil
 }
 
@@ -148,6 +202,7 @@ func (s *Server) ListenPacket() (net.PacketConn, error) {
 	if err != nil {
 		return nil, err
 	}
+	s.reportBuffers("udp", p)
 
 	return p, nil
 }
@@ -185,12 +240,46 @@ func (s *Server) Stop() (err error) {
 			err = s1.Shutdown()
 		}
 	}
+	if s.tcpServer != nil {
+		err = s.tcpServer.Shutdown()
+	}
+	if s.udpServer != nil {
+		err = s.udpServer.Shutdown()
+	}
 	s.m.Unlock()
 	return
 }
 
-// Address together with Stop() implement caddy.GracefulServer.
-func (s *Server) Address() string { return s.Addr }
+// proxyListener wraps l so the PROXY protocol headers are read, if enabled.
+func (s *Server) proxyListener(l net.Listener) net.Listener {
+	if s.proxyProtocol == nil {
+		return l
+	}
+	return proxyproto.NewListener(l, s.proxyProtocol)
+}
+
+// reportBuffers exports the sizes of the socket buffers of ln, a listener or a packet conn.
+func (s *Server) reportBuffers(proto string, ln interface{}) {
+	c, ok := ln.(syscall.Conn)
+	if !ok {
+		return
+	}
+	rcvbuf, sndbuf, err := socketBuffers(c)
+	if err != nil {
+		return
+	}
+	vars.SocketBuffer.WithLabelValues(s.Addr, proto, "receive").Set(float64(rcvbuf))
+	vars.SocketBuffer.WithLabelValues(s.Addr, proto, "send").Set(float64(sndbuf))
+}
+
+// Address together with Stop() implement caddy.GracefulServer. When several sockets listen on
+// Addr the index of the socket is added, so every server gets its own listener on a restart.
+func (s *Server) Address() string {
+	if s.socket == 0 {
+		return s.Addr
+	}
+	return fmt.Sprintf("%s#%d", s.Addr, s.socket)
+}
 
 // ServeDNS is the entry point for every request to the address that s
 // is bound to. It acts as a multiplexer for the requests zonename as
@@ -204,6 +293,9 @@ func (s *Server) ServeDNS(ctx context.Context, w dns.ResponseWriter, r *dns.Msg)
 		return
 	}
 
+	// Plugins may attach an extended DNS error to the context, it is added to the reply.
+	ctx = ede.NewContext(ctx)
+
 	if !s.debug {
 		defer func() {
 			// In case the user doesn't enable error plugin, we still
@@ -227,6 +319,7 @@ func (s *Server) ServeDNS(ctx context.Context, w dns.ResponseWriter, r *dns.Msg)
 
 	ctx, err := incrementDepthAndCheck(ctx)
 	if err != nil {
+		ede.Set(ctx, ede.Other, "query loop detected")
 		DefaultErrorFunc(ctx, w, r, dns.RcodeServerFailure)
 		return
 	}
@@ -237,9 +330,16 @@ func (s *Server) ServeDNS(ctx context.Context, w dns.ResponseWriter, r *dns.Msg)
 	var end bool
 
 	var dshandler *Config
+	var dsctx context.Context
 
 	// Wrap the response writer in a ScrubWriter so we automatically make the reply fit in the client's buffer.
-	w = request.NewScrubWriter(r, w)
+	// On encrypted transports the reply is padded as well.
+	if s.pad {
+		w = request.NewPaddingScrubWriter(r, w)
+	} else {
+		w = request.NewScrubWriter(r, w)
+	}
+	w = ede.NewWriter(ctx, r, w)
 
 	for {
 		l := len(q[off:])
@@ -251,36 +351,31 @@ func (s *Server) ServeDNS(ctx context.Context, w dns.ResponseWriter, r *dns.Msg)
 			}
 		}
 
-		if h, ok := s.zones[string(b[:l])]; ok {
+		if z, ok := s.zones[string(b[:l])]; ok {
 
 			// Set server's address in the context so plugins can reference back to this,
 			// This will makes those metrics unique.
 			ctx = context.WithValue(ctx, plugin.ServerCtx{}, s.Addr)
 
-			if r.Question[0].Qtype != dns.TypeDS {
-				if h.FilterFunc == nil {
-					rcode, _ := h.pluginChain.ServeDNS(ctx, w, r)
-					if !plugin.ClientWrite(rcode) {
-						DefaultErrorFunc(ctx, w, r, rcode)
-					}
-					return
+			for _, h := range z {
+				hctx, ok := h.selected(ctx, w, r)
+				if !ok {
+					continue
 				}
-				// FilterFunc is set, call it to see if we should use this handler.
-				// This is given to full query name.
-				if h.FilterFunc(q) {
-					rcode, _ := h.pluginChain.ServeDNS(ctx, w, r)
-					if !plugin.ClientWrite(rcode) {
-						DefaultErrorFunc(ctx, w, r, rcode)
-					}
+
+				if r.Question[0].Qtype != dns.TypeDS {
+					s.serveChain(hctx, h, w, r)
 					return
 				}
+				// The type is DS, keep the handler, but keep on searching as maybe we are serving
+				// the parent as well and the DS should be routed to it - this will probably *misroute* DS
+				// queries to a possibly grand parent, but there is no way for us to know at this point
+				// if there is an actually delegation from grandparent -> parent -> zone.
+				// In all fairness: direct DS queries should not be needed.
+				dshandler = h
+				dsctx = hctx
+				break
 			}
-			// The type is DS, keep the handler, but keep on searching as maybe we are serving
-			// the parent as well and the DS should be routed to it - this will probably *misroute* DS
-			// queries to a possibly grand parent, but there is no way for us to know at this point
-			// if there is an actually delegation from grandparent -> parent -> zone.
-			// In all fairness: direct DS queries should not be needed.
-			dshandler = h
 		}
 		off, end = dns.NextLabel(q, off)
 		if end {
@@ -290,34 +385,56 @@ func (s *Server) ServeDNS(ctx context.Context, w dns.ResponseWriter, r *dns.Msg)
 
 	if r.Question[0].Qtype == dns.TypeDS && dshandler != nil && dshandler.pluginChain != nil {
 		// DS request, and we found a zone, use the handler for the query.
-		rcode, _ := dshandler.pluginChain.ServeDNS(ctx, w, r)
-		if !plugin.ClientWrite(rcode) {
-			DefaultErrorFunc(ctx, w, r, rcode)
-		}
+		s.serveChain(dsctx, dshandler, w, r)
 		return
 	}
 
 	// Wildcard match, if we have found nothing try the root zone as a last resort.
-	if h, ok := s.zones["."]; ok && h.pluginChain != nil {
+	if z, ok := s.zones["."]; ok {
 
 		// See comment above.
 		ctx = context.WithValue(ctx, plugin.ServerCtx{}, s.Addr)
 
-		rcode, _ := h.pluginChain.ServeDNS(ctx, w, r)
-		if !plugin.ClientWrite(rcode) {
-			DefaultErrorFunc(ctx, w, r, rcode)
+		for _, h := range z {
+			if h.pluginChain == nil {
+				continue
+			}
+			hctx, ok := h.selected(ctx, w, r)
+			if !ok {
+				continue
+			}
+
+			s.serveChain(hctx, h, w, r)
+			return
 		}
-		return
 	}
 
 	// Still here? Error out with REFUSED.
 	DefaultErrorFunc(ctx, w, r, dns.RcodeRefused)
 }
 
+// serveChain calls the plugin chain of h for r, with a deadline when h has a timeout. Once the
+// deadline has passed the context is canceled, which stops the plugins that wait on it.
+func (s *Server) serveChain(ctx context.Context, h *Config, w dns.ResponseWriter, r *dns.Msg) {
+	if h.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, h.Timeout)
+		defer cancel()
+	}
+
+	rcode, _ := h.pluginChain.ServeDNS(ctx, w, r)
+	if ctx.Err() == context.DeadlineExceeded {
+		vars.RequestTimeouts.WithLabelValues(s.Addr, h.Zone).Inc()
+	}
+	if !plugin.ClientWrite(rcode) {
+		DefaultErrorFunc(ctx, w, r, rcode)
+	}
+}
+
 // OnStartupComplete lists the sites served by this server
 // and any relevant information, assuming Quiet is false.
 func (s *Server) OnStartupComplete() {
-	if Quiet {
+	if Quiet || s.socket > 0 {
 		return
 	}
 
@@ -345,6 +462,7 @@ func DefaultErrorFunc(ctx context.Context, w dns.ResponseWriter, r *dns.Msg, rc
 	answer.SetRcode(r, rc)
 
 	state.SizeAndDo(answer)
+	ede.Add(ctx, r, answer)
 
 	vars.Report(ctx, state, vars.Dropped, rcode.ToString(rc), answer.Len(), time.Now())
 
@@ -13,6 +13,8 @@ import (
 	"github.com/coredns/coredns/plugin/pkg/doh"
 	"github.com/coredns/coredns/plugin/pkg/response"
 	"github.com/coredns/coredns/plugin/pkg/transport"
+
+	"github.com/miekg/dns"
 )
 
 // ServerHTTPS represents an instance of a DNS-over-HTTPS server.
@@ -29,12 +31,15 @@ func NewServerHTTPS(addr string, group []*Config) (*ServerHTTPS, error) {
 	if err != nil {
 		return nil, err
 	}
+	s.pad = true
 	// The *tls* plugin must make sure that multiple conflicting
 	// TLS configuration return an error: it can only be specified once.
 	var tlsConfig *tls.Config
-	for _, conf := range s.zones {
-		// Should we error if some configs *don't* have TLS?
-		tlsConfig = conf.TLSConfig
+	for _, z := range s.zones {
+		for _, conf := range z {
+			// Should we error if some configs *don't* have TLS?
+			tlsConfig = conf.TLSConfig
+		}
 	}
 
 	sh := &ServerHTTPS{Server: s, tlsConfig: tlsConfig, httpsServer: new(http.Server)}
@@ -49,6 +54,8 @@ func (s *ServerHTTPS) Serve(l net.Listener) error {
 	s.listenAddr = l.Addr()
 	s.m.Unlock()
 
+	// The PROXY header precedes the TLS handshake.
+	l = s.proxyListener(l)
 	if s.tlsConfig != nil {
 		l = tls.NewListener(l, s.tlsConfig)
 	}
@@ -96,15 +103,29 @@ func (s *ServerHTTPS) Stop() error {
 }
 
 // ServeHTTP is the handler that gets the HTTP request and converts to the dns format, calls the plugin
-// chain, converts it back and write it to the client.
+// chain, converts it back and write it to the client. Both RFC 8484 requests and the JSON API are served.
 func (s *ServerHTTPS) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 
-	if r.URL.Path != doh.Path {
+	if r.URL.Path != doh.Path && r.URL.Path != doh.JSONPath {
 		http.Error(w, "", http.StatusNotFound)
 		return
 	}
 
-	msg, err := doh.RequestToMsg(r)
+	json := doh.IsJSON(r)
+
+	var (
+		msg *dns.Msg
+		err error
+	)
+	switch {
+	case json:
+		msg, err = doh.RequestToMsgJSON(r)
+	case r.URL.Path == doh.Path:
+		msg, err = doh.RequestToMsg(r)
+	default:
+		http.Error(w, "", http.StatusNotFound)
+		return
+	}
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
@@ -119,13 +140,32 @@ func (s *ServerHTTPS) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	// We should expect a packet to be returned that we can send to the client.
 	s.ServeDNS(context.Background(), dw, msg)
 
-	buf, _ := dw.Msg.Pack()
+	if dw.Msg == nil {
+		http.Error(w, "No response", http.StatusInternalServerError)
+		return
+	}
+
+	var (
+		buf  []byte
+		mime string
+	)
+	if json {
+		buf, err = doh.MsgToJSON(dw.Msg)
+		mime = doh.JSONMimeType
+	} else {
+		buf, err = dw.Msg.Pack()
+		mime = doh.MimeType
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
 
 	mt, _ := response.Typify(dw.Msg, time.Now().UTC())
 	age := dnsutil.MinimalTTL(dw.Msg, mt)
 
-	w.Header().Set("Content-Type", doh.MimeType)
-	w.Header().Set("Cache-Control", fmt.Sprintf("max-age=%f", age.Seconds()))
+	w.Header().Set("Content-Type", mime)
+	w.Header().Set("Cache-Control", fmt.Sprintf("max-age=%d", uint32(age.Seconds())))
 	w.Header().Set("Content-Length", strconv.Itoa(len(buf)))
 	w.WriteHeader(http.StatusOK)
 
@@ -0,0 +1,73 @@
+package dnsserver
+
+import (
+	"context"
+	"encoding/base64"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/coredns/coredns/plugin"
+	"github.com/coredns/coredns/plugin/pkg/doh"
+	"github.com/coredns/coredns/plugin/test"
+
+	"github.com/miekg/dns"
+)
+
+var answerPlugin = plugin.HandlerFunc(func(ctx context.Context, w dns.ResponseWriter, r *dns.Msg) (int, error) {
+	m := new(dns.Msg)
+	m.SetReply(r)
+	m.Answer = []dns.RR{test.A("example.com. 300 IN A 127.0.0.1"), test.A("example.com. 60 IN A 127.0.0.2")}
+	w.WriteMsg(m)
+	return dns.RcodeSuccess, nil
+})
+
+func TestServeHTTP(t *testing.T) {
+	s, err := NewServerHTTPS("127.0.0.1:443", []*Config{testConfig("https", answerPlugin)})
+	if err != nil {
+		t.Fatalf("Expected no error for NewServerHTTPS, got %s", err)
+	}
+
+	m := new(dns.Msg)
+	m.SetQuestion("example.com.", dns.TypeA)
+	buf, _ := m.Pack()
+	b64 := base64.RawURLEncoding.EncodeToString(buf)
+
+	tests := []struct {
+		url    string
+		status int
+		mime   string
+	}{
+		{"/dns-query?dns=" + b64, http.StatusOK, doh.MimeType},
+		{"/dns-query?name=example.com&type=A", http.StatusOK, doh.JSONMimeType},
+		{"/resolve?name=example.com", http.StatusOK, doh.JSONMimeType},
+		{"/dns-query?dns=invalid", http.StatusBadRequest, ""},
+		{"/resolve", http.StatusBadRequest, ""},
+		{"/other?dns=" + b64, http.StatusNotFound, ""},
+	}
+
+	for i, tc := range tests {
+		req := httptest.NewRequest(http.MethodGet, "https://example.com"+tc.url, nil)
+		rec := httptest.NewRecorder()
+		s.ServeHTTP(rec, req)
+
+		if rec.Code != tc.status {
+			t.Errorf("Test %d: expected status %d, got %d", i, tc.status, rec.Code)
+			continue
+		}
+		if tc.status != http.StatusOK {
+			continue
+		}
+		if x := rec.Header().Get("Content-Type"); x != tc.mime {
+			t.Errorf("Test %d: expected Content-Type %s, got %s", i, tc.mime, x)
+		}
+		// The max-age is the minimum TTL of the answer.
+		if x := rec.Header().Get("Cache-Control"); x != "max-age=60" {
+			t.Errorf("Test %d: expected Cache-Control %s, got %s", i, "max-age=60", x)
+		}
+		if tc.mime == doh.JSONMimeType && !strings.Contains(rec.Body.String(), `"data":"127.0.0.1"`) {
+			t.Errorf("Test %d: expected answer in JSON response, got %s", i, rec.Body.String())
+		}
+	}
+}
@@ -0,0 +1,212 @@
+package dnsserver
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net"
+	"time"
+
+	"github.com/coredns/coredns/plugin/pkg/log"
+	"github.com/coredns/coredns/plugin/pkg/transport"
+
+	"github.com/miekg/dns"
+	quic "github.com/quic-go/quic-go"
+)
+
+// ServerQUIC represents an instance of a DNS-over-QUIC server (RFC 9250).
+type ServerQUIC struct {
+	*Server
+	packetConn   net.PacketConn
+	quicListener *quic.Listener
+	quicConfig   *quic.Config
+	tlsConfig    *tls.Config
+}
+
+// NewServerQUIC returns a new CoreDNS QUIC server and compiles all plugins in to it.
+func NewServerQUIC(addr string, group []*Config) (*ServerQUIC, error) {
+	s, err := NewServer(addr, group)
+	if err != nil {
+		return nil, err
+	}
+	s.pad = true
+	// The *tls* plugin must make sure that multiple conflicting
+	// TLS configuration return an error: it can only be specified once.
+	var tlsConfig *tls.Config
+	for _, z := range s.zones {
+		for _, conf := range z {
+			// Should we error if some configs *don't* have TLS?
+			tlsConfig = conf.TLSConfig
+		}
+	}
+	// QUIC can't be spoken without TLS.
+	if tlsConfig == nil {
+		return nil, fmt.Errorf("no TLS configuration for %s, use the tls plugin", addr)
+	}
+	tlsConfig = tlsConfig.Clone()
+	tlsConfig.NextProtos = []string{doqALPN}
+
+	quicConfig := &quic.Config{
+		MaxIdleTimeout:        doqIdleTimeout,
+		MaxIncomingStreams:    math.MaxUint16,
+		MaxIncomingUniStreams: -1, // DoQ only uses bidirectional streams.
+	}
+
+	return &ServerQUIC{Server: s, tlsConfig: tlsConfig, quicConfig: quicConfig}, nil
+}
+
+// Serve implements caddy.TCPServer interface.
+func (s *ServerQUIC) Serve(l net.Listener) error { return nil }
+
+// ServePacket implements caddy.UDPServer interface.
+func (s *ServerQUIC) ServePacket(p net.PacketConn) error {
+	s.m.Lock()
+	ql, err := quic.Listen(p, s.tlsConfig, s.quicConfig)
+	if err != nil {
+		s.m.Unlock()
+		return err
+	}
+	s.packetConn = p
+	s.quicListener = ql
+	s.m.Unlock()
+
+	for {
+		conn, err := ql.Accept(context.Background())
+		if err != nil {
+			if err == quic.ErrServerClosed {
+				return nil
+			}
+			return err
+		}
+		go s.serveQUICConnection(conn)
+	}
+}
+
+// Listen implements caddy.TCPServer interface.
+func (s *ServerQUIC) Listen() (net.Listener, error) { return nil, nil }
+
+// ListenPacket implements caddy.UDPServer interface.
+func (s *ServerQUIC) ListenPacket() (net.PacketConn, error) {
+	p, err := listenPacket("udp", s.Addr[len(transport.QUIC+"://"):])
+	if err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// OnStartupComplete lists the sites served by this server
+// and any relevant information, assuming Quiet is false.
+func (s *ServerQUIC) OnStartupComplete() {
+	if Quiet {
+		return
+	}
+
+	out := startUpZones(transport.QUIC+"://", s.Addr, s.zones)
+	if out != "" {
+		fmt.Print(out)
+	}
+	return
+}
+
+// Stop stops the server. It blocks until the server is totally stopped.
+func (s *ServerQUIC) Stop() error {
+	s.m.Lock()
+	defer s.m.Unlock()
+	if s.quicListener != nil {
+		s.quicListener.Close()
+	}
+	if s.packetConn != nil {
+		return s.packetConn.Close()
+	}
+	return nil
+}
+
+// Shutdown stops the server (non gracefully).
+func (s *ServerQUIC) Shutdown() error { return s.Stop() }
+
+// serveQUICConnection accepts the streams of a QUIC connection, each stream carries a single query.
+func (s *ServerQUIC) serveQUICConnection(conn *quic.Conn) {
+	for {
+		stream, err := conn.AcceptStream(context.Background())
+		if err != nil {
+			// Idle timeout or the client closed the connection, nothing to be done.
+			return
+		}
+		go s.serveQUICStream(conn, stream)
+	}
+}
+
+// serveQUICStream reads the query from stream, and calls ServeDNS. The reply is written back by
+// the DoQWriter.
+func (s *ServerQUIC) serveQUICStream(conn *quic.Conn, stream *quic.Stream) {
+	stream.SetReadDeadline(time.Now().Add(doqReadTimeout))
+
+	buf, err := readDoQMessage(stream)
+	if err != nil {
+		log.Debugf("Failed to read DoQ query from %s: %s", conn.RemoteAddr(), err)
+		conn.CloseWithError(doqProtocolError, "")
+		return
+	}
+
+	msg := new(dns.Msg)
+	if err := msg.Unpack(buf); err != nil {
+		conn.CloseWithError(doqProtocolError, "")
+		return
+	}
+	if !validDoQQuery(msg) {
+		conn.CloseWithError(doqProtocolError, "")
+		return
+	}
+
+	w := &DoQWriter{localAddr: conn.LocalAddr(), remoteAddr: conn.RemoteAddr(), stream: stream, Msg: msg}
+
+	ctx := context.WithValue(context.Background(), Key{}, s.Server)
+	s.ServeDNS(ctx, w, msg)
+}
+
+// readDoQMessage reads a single length prefixed DNS message from r.
+func readDoQMessage(r io.Reader) ([]byte, error) {
+	l := make([]byte, 2)
+	if _, err := io.ReadFull(r, l); err != nil {
+		return nil, err
+	}
+	length := binary.BigEndian.Uint16(l)
+	if length == 0 {
+		return nil, errors.New("zero length DoQ message")
+	}
+
+	buf := make([]byte, length)
+	if _, err := io.ReadFull(r, buf); err != nil {
+		return nil, err
+	}
+	return buf, nil
+}
+
+// validDoQQuery checks the constraints RFC 9250 puts on queries: the message ID must be zero
+// and the edns-tcp-keepalive option must not be present.
+func validDoQQuery(m *dns.Msg) bool {
+	if m.Id != 0 {
+		return false
+	}
+	if o := m.IsEdns0(); o != nil {
+		for _, opt := range o.Option {
+			if opt.Option() == dns.EDNS0TCPKEEPALIVE {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+const (
+	doqALPN        = "doq"
+	doqIdleTimeout = 30 * time.Second
+	doqReadTimeout = 2 * time.Second
+
+	// Error codes as defined in RFC 9250, Section 4.3.
+	doqProtocolError quic.ApplicationErrorCode = 0x2
+)
@@ -0,0 +1,104 @@
+package dnsserver
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+	"path/filepath"
+	"testing"
+	"time"
+
+	ctls "github.com/coredns/coredns/plugin/pkg/tls"
+	"github.com/coredns/coredns/plugin/test"
+
+	"github.com/miekg/dns"
+	quic "github.com/quic-go/quic-go"
+)
+
+func TestServeQUIC(t *testing.T) {
+	dir, rmFunc, err := test.WritePEMFiles("")
+	if err != nil {
+		t.Fatalf("Could not write PEM files: %s", err)
+	}
+	defer rmFunc()
+
+	c := testConfig("quic", answerPlugin)
+	c.TLSConfig, err = ctls.NewTLSConfig(filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem"), "")
+	if err != nil {
+		t.Fatalf("Failed to create TLS config: %s", err)
+	}
+	s, err := NewServerQUIC("quic://127.0.0.1:0", []*Config{c})
+	if err != nil {
+		t.Fatalf("Expected no error for NewServerQUIC, got %s", err)
+	}
+
+	p, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %s", err)
+	}
+	go s.ServePacket(p)
+	defer s.Stop()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	conn, err := quic.DialAddr(ctx, p.LocalAddr().String(), &tls.Config{InsecureSkipVerify: true, NextProtos: []string{doqALPN}}, nil)
+	if err != nil {
+		t.Fatalf("Failed to dial: %s", err)
+	}
+	defer conn.CloseWithError(0, "")
+
+	m := new(dns.Msg)
+	m.SetQuestion("example.com.", dns.TypeA)
+	m.Id = 0
+
+	// Two queries on one connection, each on its own stream.
+	for i := 0; i < 2; i++ {
+		stream, err := conn.OpenStreamSync(ctx)
+		if err != nil {
+			t.Fatalf("Test %d: failed to open stream: %s", i, err)
+		}
+		buf, _ := m.Pack()
+		if _, err := stream.Write(addPrefix(buf)); err != nil {
+			t.Fatalf("Test %d: failed to write query: %s", i, err)
+		}
+		stream.Close()
+
+		stream.SetReadDeadline(time.Now().Add(5 * time.Second))
+		buf, err = readDoQMessage(stream)
+		if err != nil {
+			t.Fatalf("Test %d: failed to read reply: %s", i, err)
+		}
+		r := new(dns.Msg)
+		if err := r.Unpack(buf); err != nil {
+			t.Fatalf("Test %d: failed to unpack reply: %s", i, err)
+		}
+		if r.Id != 0 {
+			t.Errorf("Test %d: expected ID 0, got %d", i, r.Id)
+		}
+		if len(r.Answer) != 2 {
+			t.Errorf("Test %d: expected 2 answers, got %d", i, len(r.Answer))
+		}
+	}
+}
+
+func TestValidDoQQuery(t *testing.T) {
+	m := new(dns.Msg)
+	m.SetQuestion("example.com.", dns.TypeA)
+	m.Id = 0
+	if !validDoQQuery(m) {
+		t.Errorf("Expected query with ID 0 to be valid")
+	}
+
+	m.Id = 1
+	if validDoQQuery(m) {
+		t.Errorf("Expected query with ID 1 to be invalid")
+	}
+
+	m.Id = 0
+	m.SetEdns0(4096, false)
+	o := m.IsEdns0()
+	o.Option = append(o.Option, &dns.EDNS0_TCP_KEEPALIVE{Code: dns.EDNS0TCPKEEPALIVE})
+	if validDoQQuery(m) {
+		t.Errorf("Expected query with edns-tcp-keepalive to be invalid")
+	}
+}
@@ -2,10 +2,14 @@ package dnsserver
 
 import (
 	"context"
+	"crypto/tls"
 	"testing"
 
 	"github.com/coredns/coredns/plugin"
+	"github.com/coredns/coredns/plugin/pkg/dnstest"
+	"github.com/coredns/coredns/plugin/pkg/ede"
 	"github.com/coredns/coredns/plugin/test"
+	"github.com/coredns/coredns/request"
 
 	"github.com/miekg/dns"
 )
@@ -46,6 +50,51 @@ func TestNewServer(t *testing.T) {
 	if err != nil {
 		t.Errorf("Expected no error for NewServerTLS, got %s", err)
 	}
+
+	_, err = NewServerQUIC("127.0.0.1:53", []*Config{testConfig("quic", testPlugin{})})
+	if err == nil {
+		t.Errorf("Expected error for NewServerQUIC without TLS config, got none")
+	}
+
+	c := testConfig("quic", testPlugin{})
+	c.TLSConfig = &tls.Config{}
+	sq, err := NewServerQUIC("127.0.0.1:53", []*Config{c})
+	if err != nil {
+		t.Errorf("Expected no error for NewServerQUIC, got %s", err)
+	}
+	if sq != nil && sq.tlsConfig.NextProtos[0] != "doq" {
+		t.Errorf("Expected ALPN %q, got %v", "doq", sq.tlsConfig.NextProtos)
+	}
+}
+
+type edePlugin struct{}
+
+func (ep edePlugin) ServeDNS(ctx context.Context, w dns.ResponseWriter, r *dns.Msg) (int, error) {
+	ede.Set(ctx, ede.NoReachableAuthority, "no healthy upstreams")
+	return dns.RcodeServerFailure, nil
+}
+
+func (ep edePlugin) Name() string { return "edeplugin" }
+
+func TestServeDNSExtendedError(t *testing.T) {
+	s, err := NewServer("127.0.0.1:53", []*Config{testConfig("dns", edePlugin{})})
+	if err != nil {
+		t.Fatalf("Expected no error for NewServer, got %s", err)
+	}
+
+	m := new(dns.Msg)
+	m.SetQuestion("example.com.", dns.TypeA)
+	m.SetEdns0(4096, false)
+	rec := dnstest.NewRecorder(&test.ResponseWriter{})
+	s.ServeDNS(context.Background(), rec, m)
+
+	if rec.Msg.Rcode != dns.RcodeServerFailure {
+		t.Errorf("Expected SERVFAIL, got %s", dns.RcodeToString[rec.Msg.Rcode])
+	}
+	o := rec.Msg.IsEdns0()
+	if o == nil || len(o.Option) != 1 || o.Option[0].Option() != ede.OptionCode {
+		t.Errorf("Expected an extended error option in the reply, got %v", o)
+	}
 }
 
 func TestIncrementDepthAndCheck(t *testing.T) {
@@ -80,3 +129,101 @@ func BenchmarkCoreServeDNS(b *testing.B) {
 		s.ServeDNS(ctx, w, m)
 	}
 }
+
+type viewPlugin struct{ name string }
+
+func (vp viewPlugin) ServeDNS(ctx context.Context, w dns.ResponseWriter, r *dns.Msg) (int, error) {
+	m := new(dns.Msg)
+	m.SetReply(r)
+	m.Answer = []dns.RR{test.TXT(r.Question[0].Name + " 0 IN TXT " + vp.name)}
+	w.WriteMsg(m)
+	return 0, nil
+}
+
+func (vp viewPlugin) Name() string { return "viewplugin" }
+
+func TestServeDNSView(t *testing.T) {
+	// test.ResponseWriter uses 10.240.0.1 as the client address.
+	internal := testConfig("dns", viewPlugin{"internal"})
+	internal.ViewName = "internal"
+	internal.ViewFunc = func(ctx context.Context, state request.Request) bool { return state.IP() == "10.240.0.1" }
+
+	other := testConfig("dns", viewPlugin{"other"})
+	other.ViewName = "other"
+	other.ViewFunc = func(ctx context.Context, state request.Request) bool { return false }
+
+	external := testConfig("dns", viewPlugin{"external"})
+
+	for i, tc := range []struct {
+		configs  []*Config
+		expected string
+	}{
+		{[]*Config{internal, external}, "internal"},
+		{[]*Config{other, external}, "external"},
+		{[]*Config{other, internal, external}, "internal"},
+		{[]*Config{other}, ""},
+	} {
+		s, err := NewServer("127.0.0.1:53", tc.configs)
+		if err != nil {
+			t.Fatalf("Test %d: expected no error for NewServer, got %s", i, err)
+		}
+
+		m := new(dns.Msg)
+		m.SetQuestion("www.example.com.", dns.TypeTXT)
+		rec := dnstest.NewRecorder(&test.ResponseWriter{})
+		s.ServeDNS(context.TODO(), rec, m)
+
+		if tc.expected == "" {
+			if rec.Msg.Rcode != dns.RcodeRefused {
+				t.Errorf("Test %d: expected REFUSED, got %s", i, dns.RcodeToString[rec.Msg.Rcode])
+			}
+			continue
+		}
+		if len(rec.Msg.Answer) != 1 {
+			t.Fatalf("Test %d: expected 1 answer, got %d", i, len(rec.Msg.Answer))
+		}
+		if txt := rec.Msg.Answer[0].(*dns.TXT).Txt[0]; txt != tc.expected {
+			t.Errorf("Test %d: expected view %q, got %q", i, tc.expected, txt)
+		}
+	}
+}
+
+type collectKey struct{}
+
+// collectPlugin is a MetadataCollector that records which server block collected the metadata.
+type collectPlugin struct {
+	testPlugin
+	name string
+}
+
+func (cp collectPlugin) Collect(ctx context.Context, state request.Request) context.Context {
+	return context.WithValue(ctx, collectKey{}, cp.name)
+}
+
+func TestServeDNSViewCollect(t *testing.T) {
+	// Each view sees the metadata collected by its own server block.
+	viewFunc := func(name string) func(context.Context, request.Request) bool {
+		return func(ctx context.Context, state request.Request) bool {
+			return ctx.Value(collectKey{}) == name
+		}
+	}
+	other := testConfig("dns", collectPlugin{name: "other"})
+	other.ViewName = "other"
+	other.ViewFunc = viewFunc("external")
+	external := testConfig("dns", collectPlugin{name: "external"})
+	external.ViewName = "external"
+	external.ViewFunc = viewFunc("external")
+
+	s, err := NewServer("127.0.0.1:53", []*Config{other, external})
+	if err != nil {
+		t.Fatalf("Expected no error for NewServer, got %s", err)
+	}
+
+	m := new(dns.Msg)
+	m.SetQuestion("www.example.com.", dns.TypeTXT)
+	rec := dnstest.NewRecorder(&test.ResponseWriter{})
+	s.ServeDNS(context.TODO(), rec, m)
+	if rec.Msg != nil && rec.Msg.Rcode == dns.RcodeRefused {
+		t.Errorf("Expected the external view to be selected, got REFUSED")
+	}
+}
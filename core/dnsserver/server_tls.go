@@ -23,12 +23,15 @@ func NewServerTLS(addr string, group []*Config) (*ServerTLS, error) {
 	if err != nil {
 		return nil, err
 	}
+	s.pad = true
 	// The *tls* plugin must make sure that multiple conflicting
 	// TLS configuration return an error: it can only be specified once.
 	var tlsConfig *tls.Config
-	for _, conf := range s.zones {
-		// Should we error if some configs *don't* have TLS?
-		tlsConfig = conf.TLSConfig
+	for _, z := range s.zones {
+		for _, conf := range z {
+			// Should we error if some configs *don't* have TLS?
+			tlsConfig = conf.TLSConfig
+		}
 	}
 
 	return &ServerTLS{Server: s, tlsConfig: tlsConfig}, nil
@@ -38,15 +41,24 @@ func NewServerTLS(addr string, group []*Config) (*ServerTLS, error) {
 func (s *ServerTLS) Serve(l net.Listener) error {
 	s.m.Lock()
 
+	// The PROXY header precedes the TLS handshake.
+	l = s.proxyListener(l)
 	if s.tlsConfig != nil {
 		l = tls.NewListener(l, s.tlsConfig)
 	}
 
-	// Only fill out the TCP server for this one.
-	s.server[tcp] = &dns.Server{Listener: l, Net: "tcp-tls", Handler: dns.HandlerFunc(func(w dns.ResponseWriter, r *dns.Msg) {
+	h := dns.HandlerFunc(func(w dns.ResponseWriter, r *dns.Msg) {
 		ctx := context.Background()
 		s.ServeDNS(ctx, w, r)
-	})}
+	})
+	if s.tcpConfig != nil {
+		s.tcpServer = newTCPServer(s.Addr, l, h, s.tcpConfig)
+		s.m.Unlock()
+		return s.tcpServer.serve()
+	}
+
+	// Only fill out the TCP server for this one.
+	s.server[tcp] = &dns.Server{Listener: l, Net: "tcp-tls", Handler: h}
 	s.m.Unlock()
 
 	return s.server[tcp].ActivateAndServe()
@@ -0,0 +1,351 @@
+package dnsserver
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/coredns/coredns/plugin/metrics/vars"
+	"github.com/coredns/coredns/plugin/pkg/log"
+
+	"github.com/miekg/dns"
+)
+
+// TCPConfig configures how the connections of a DNS (TCP) or DNS-over-TLS server are handled, see
+// RFC 7766. Zero timeouts disable the timeout.
+type TCPConfig struct {
+	// Pipeline is the number of queries of a single connection that are processed concurrently.
+	// Replies are sent in the order they are ready, not in the order the queries came in.
+	Pipeline int
+	// IdleTimeout is the time a connection without outstanding queries may be idle.
+	IdleTimeout time.Duration
+	// ReadTimeout is the time allowed to read the first query of a connection, and to read the
+	// rest of a query once its length has been read.
+	ReadTimeout time.Duration
+	// MaxConnections is the maximum number of open connections, 0 means no limit.
+	MaxConnections int
+}
+
+// tcpServer serves the connections accepted by a listener with a TCPConfig. It replaces the
+// dns.Server, which handles the queries of a connection one by one.
+type tcpServer struct {
+	addr    string // server address, used as metrics label
+	l       net.Listener
+	handler dns.Handler
+	config  *TCPConfig
+	conns   chan struct{} // limits the number of open connections, nil when unlimited
+
+	done     chan struct{}         // closed when Shutdown is called, no new queries are handled
+	handlers sync.WaitGroup        // queries that are being handled
+	mu       sync.Mutex            // orders adding handlers and connections and closing done
+	open     map[*tcpConn]struct{} // the open connections
+	once     sync.Once
+}
+
+func newTCPServer(addr string, l net.Listener, handler dns.Handler, config *TCPConfig) *tcpServer {
+	t := &tcpServer{addr: addr, l: l, handler: handler, config: config, done: make(chan struct{}), open: make(map[*tcpConn]struct{})}
+	if config.MaxConnections > 0 {
+		t.conns = make(chan struct{}, config.MaxConnections)
+	}
+	return t
+}
+
+// serve accepts connections until the listener is closed.
+func (t *tcpServer) serve() error {
+	for {
+		conn, err := t.l.Accept()
+		if err != nil {
+			select {
+			case <-t.done:
+				return nil
+			default:
+			}
+			if ne, ok := err.(net.Error); ok && ne.Temporary() {
+				time.Sleep(tcpAcceptBackoff)
+				continue
+			}
+			return err
+		}
+
+		if t.conns != nil {
+			select {
+			case t.conns <- struct{}{}:
+			default:
+				vars.TCPConnectionsRejected.WithLabelValues(t.addr).Inc()
+				conn.Close()
+				continue
+			}
+		}
+		go t.serveConn(conn)
+	}
+}
+
+// Shutdown closes the listener and stops reading queries from the open connections. It waits, up
+// to tcpShutdownTimeout, for the queries that are being handled and then closes the connections.
+func (t *tcpServer) Shutdown() error {
+	var err error
+	t.once.Do(func() {
+		t.mu.Lock()
+		close(t.done)
+		// Wake up the connections waiting for a query, they see done and return.
+		for c := range t.open {
+			c.SetReadDeadline(time.Now())
+		}
+		t.mu.Unlock()
+		err = t.l.Close()
+
+		handled := make(chan struct{})
+		go func() {
+			t.handlers.Wait()
+			close(handled)
+		}()
+		select {
+		case <-handled:
+		case <-time.After(tcpShutdownTimeout):
+		}
+
+		t.mu.Lock()
+		for c := range t.open {
+			c.Close()
+		}
+		t.mu.Unlock()
+	})
+	return err
+}
+
+// track adds c to the open connections, it returns false when the server is shutting down.
+func (t *tcpServer) track(c *tcpConn) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	select {
+	case <-t.done:
+		return false
+	default:
+	}
+	t.open[c] = struct{}{}
+	return true
+}
+
+func (t *tcpServer) untrack(c *tcpConn) {
+	t.mu.Lock()
+	delete(t.open, c)
+	t.mu.Unlock()
+}
+
+// handle adds a query to the handlers, it returns false when the server is shutting down.
+func (t *tcpServer) handle() bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	select {
+	case <-t.done:
+		return false
+	default:
+	}
+	t.handlers.Add(1)
+	return true
+}
+
+// stopping returns true when Shutdown has been called.
+func (t *tcpServer) stopping() bool {
+	select {
+	case <-t.done:
+		return true
+	default:
+		return false
+	}
+}
+
+// serveConn reads the queries of conn and handles up to config.Pipeline of them concurrently.
+func (t *tcpServer) serveConn(conn net.Conn) {
+	vars.TCPConnections.WithLabelValues(t.addr).Inc()
+	c := &tcpConn{Conn: conn}
+
+	pipeline := t.config.Pipeline
+	if pipeline < 1 {
+		pipeline = 1
+	}
+	sem := make(chan struct{}, pipeline)
+	var wg sync.WaitGroup
+
+	defer func() {
+		wg.Wait()
+		conn.Close()
+		t.untrack(c)
+		vars.TCPConnections.WithLabelValues(t.addr).Dec()
+		if t.conns != nil {
+			<-t.conns
+		}
+	}()
+	if !t.track(c) {
+		return
+	}
+
+	timeout := t.config.ReadTimeout
+	for {
+		buf, err := t.readQuery(c, timeout)
+		if err != nil {
+			if err != io.EOF {
+				log.With("server", t.addr).Debugf("Closing TCP connection from %s: %s", conn.RemoteAddr(), err)
+			}
+			return
+		}
+		timeout = t.config.IdleTimeout
+
+		req := new(dns.Msg)
+		if err := req.Unpack(buf); err != nil {
+			// We can't reply without a message ID, and the stream may be out of sync.
+			return
+		}
+
+		sem <- struct{}{}
+		if !t.handle() {
+			<-sem
+			return
+		}
+		wg.Add(1)
+		c.outstanding(1)
+		vars.TCPPipelinedQueries.WithLabelValues(t.addr).Inc()
+		go func() {
+			defer func() {
+				vars.TCPPipelinedQueries.WithLabelValues(t.addr).Dec()
+				c.outstanding(-1)
+				t.handlers.Done()
+				wg.Done()
+				<-sem
+			}()
+			t.handler.ServeDNS(&tcpWriter{c: c}, req)
+		}()
+	}
+}
+
+// readQuery reads the next length prefixed query from c. The first two octets must arrive within
+// timeout, unless queries are still outstanding, the rest of the query within the read timeout.
+func (t *tcpServer) readQuery(c *tcpConn, timeout time.Duration) ([]byte, error) {
+	l := make([]byte, 2)
+	for {
+		c.SetReadDeadline(deadline(timeout))
+		// Checked after setting the deadline, as Shutdown sets one to wake us up.
+		if t.stopping() {
+			return nil, errServerClosed
+		}
+		n, err := io.ReadFull(c, l)
+		if err == nil {
+			break
+		}
+		if t.stopping() {
+			return nil, errServerClosed
+		}
+		if ne, ok := err.(net.Error); ok && ne.Timeout() && n == 0 {
+			// RFC 7766, Section 6.2.3: don't close a connection that has outstanding queries.
+			if c.busy() {
+				continue
+			}
+			vars.TCPTimeouts.WithLabelValues(t.addr, "idle").Inc()
+			return nil, err
+		}
+		if ne, ok := err.(net.Error); ok && ne.Timeout() {
+			vars.TCPTimeouts.WithLabelValues(t.addr, "read").Inc()
+		}
+		return nil, err
+	}
+
+	length := binary.BigEndian.Uint16(l)
+	if length == 0 {
+		return nil, errors.New("zero length query")
+	}
+
+	buf := make([]byte, length)
+	c.SetReadDeadline(deadline(t.config.ReadTimeout))
+	if _, err := io.ReadFull(c, buf); err != nil {
+		if ne, ok := err.(net.Error); ok && ne.Timeout() {
+			vars.TCPTimeouts.WithLabelValues(t.addr, "read").Inc()
+		}
+		return nil, err
+	}
+	return buf, nil
+}
+
+// deadline returns the deadline for timeout, a zero timeout doesn't have one.
+func deadline(timeout time.Duration) time.Time {
+	if timeout == 0 {
+		return time.Time{}
+	}
+	return time.Now().Add(timeout)
+}
+
+// tcpConn is a connection with pipelined queries. Writes of replies are serialized.
+type tcpConn struct {
+	net.Conn
+
+	wmu sync.Mutex // serializes the writes of replies
+
+	mu      sync.Mutex
+	pending int // queries that are being handled
+}
+
+func (c *tcpConn) outstanding(n int) {
+	c.mu.Lock()
+	c.pending += n
+	c.mu.Unlock()
+}
+
+func (c *tcpConn) busy() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.pending > 0
+}
+
+// write writes b, prefixed with its 2-octet length, to the connection in one go, so replies
+// don't interleave.
+func (c *tcpConn) write(b []byte) (int, error) {
+	c.wmu.Lock()
+	defer c.wmu.Unlock()
+
+	c.SetWriteDeadline(time.Now().Add(tcpWriteTimeout))
+	n, err := c.Conn.Write(addPrefix(b))
+	if n >= 2 {
+		n -= 2
+	}
+	return n, err
+}
+
+// tcpWriter is the dns.ResponseWriter for a query received on a tcpConn.
+type tcpWriter struct {
+	c *tcpConn
+}
+
+// Write writes the message b to the connection.
+func (w *tcpWriter) Write(b []byte) (int, error) {
+	return w.c.write(b)
+}
+
+// WriteMsg packs m and writes it to the connection.
+func (w *tcpWriter) WriteMsg(m *dns.Msg) error {
+	buf, err := m.Pack()
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(buf)
+	return err
+}
+
+// Close closes the connection, this also aborts the other queries on it.
+func (w *tcpWriter) Close() error { return w.c.Close() }
+
+// These methods implement the dns.ResponseWriter interface from Go DNS.
+func (w *tcpWriter) TsigStatus() error     { return nil }
+func (w *tcpWriter) TsigTimersOnly(b bool) { return }
+func (w *tcpWriter) Hijack()               { return }
+func (w *tcpWriter) LocalAddr() net.Addr   { return w.c.LocalAddr() }
+func (w *tcpWriter) RemoteAddr() net.Addr  { return w.c.RemoteAddr() }
+
+const (
+	tcpWriteTimeout    = 2 * time.Second
+	tcpAcceptBackoff   = 5 * time.Millisecond
+	tcpShutdownTimeout = 5 * time.Second
+)
+
+var errServerClosed = errors.New("server closed")
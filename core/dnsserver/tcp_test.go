@@ -0,0 +1,216 @@
+package dnsserver
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+// slowPlugin answers every query, but waits for release before answering queries for slow.example.com.
+type slowPlugin struct {
+	release chan struct{}
+}
+
+func (sp slowPlugin) ServeDNS(ctx context.Context, w dns.ResponseWriter, r *dns.Msg) (int, error) {
+	if r.Question[0].Name == "slow.example.com." {
+		<-sp.release
+	}
+	m := new(dns.Msg)
+	m.SetReply(r)
+	w.WriteMsg(m)
+	return dns.RcodeSuccess, nil
+}
+
+func (sp slowPlugin) Name() string { return "slowplugin" }
+
+func startTCPServer(t *testing.T, config *TCPConfig, p slowPlugin) (*Server, string) {
+	c := testConfig("dns", p)
+	c.TCP = config
+	s, err := NewServer("dns://127.0.0.1:0", []*Config{c})
+	if err != nil {
+		t.Fatalf("Expected no error for NewServer, got %s", err)
+	}
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %s", err)
+	}
+	go s.Serve(l)
+	return s, l.Addr().String()
+}
+
+func TestTCPPipeline(t *testing.T) {
+	p := slowPlugin{release: make(chan struct{})}
+	s, addr := startTCPServer(t, &TCPConfig{Pipeline: 2, IdleTimeout: time.Second, ReadTimeout: time.Second}, p)
+	defer s.Stop()
+
+	co, err := dns.Dial("tcp", addr)
+	if err != nil {
+		t.Fatalf("Failed to dial: %s", err)
+	}
+	defer co.Close()
+
+	slow := new(dns.Msg)
+	slow.SetQuestion("slow.example.com.", dns.TypeA)
+	fast := new(dns.Msg)
+	fast.SetQuestion("fast.example.com.", dns.TypeA)
+
+	if err := co.WriteMsg(slow); err != nil {
+		t.Fatalf("Failed to write query: %s", err)
+	}
+	if err := co.WriteMsg(fast); err != nil {
+		t.Fatalf("Failed to write query: %s", err)
+	}
+
+	co.SetReadDeadline(time.Now().Add(2 * time.Second))
+	r, err := co.ReadMsg()
+	if err != nil {
+		t.Fatalf("Failed to read reply: %s", err)
+	}
+	if r.Id != fast.Id {
+		t.Errorf("Expected the reply to the second query first, got %s", r.Question[0].Name)
+	}
+
+	close(p.release)
+	r, err = co.ReadMsg()
+	if err != nil {
+		t.Fatalf("Failed to read reply: %s", err)
+	}
+	if r.Id != slow.Id {
+		t.Errorf("Expected the reply to the first query, got %s", r.Question[0].Name)
+	}
+}
+
+func TestTCPMaxConnections(t *testing.T) {
+	p := slowPlugin{release: make(chan struct{})}
+	s, addr := startTCPServer(t, &TCPConfig{Pipeline: 1, IdleTimeout: time.Second, ReadTimeout: time.Second, MaxConnections: 1}, p)
+	defer s.Stop()
+
+	m := new(dns.Msg)
+	m.SetQuestion("example.com.", dns.TypeA)
+
+	co, err := dns.Dial("tcp", addr)
+	if err != nil {
+		t.Fatalf("Failed to dial: %s", err)
+	}
+	defer co.Close()
+	if _, err := exchange(co, m); err != nil {
+		t.Fatalf("Expected reply on the first connection, got %s", err)
+	}
+
+	co1, err := dns.Dial("tcp", addr)
+	if err != nil {
+		t.Fatalf("Failed to dial: %s", err)
+	}
+	defer co1.Close()
+	co1.SetDeadline(time.Now().Add(2 * time.Second))
+	if _, err := exchange(co1, m); err == nil {
+		t.Errorf("Expected the second connection to be closed")
+	}
+}
+
+func TestTCPIdleTimeout(t *testing.T) {
+	p := slowPlugin{release: make(chan struct{})}
+	s, addr := startTCPServer(t, &TCPConfig{Pipeline: 1, IdleTimeout: 50 * time.Millisecond, ReadTimeout: time.Second}, p)
+	defer s.Stop()
+
+	m := new(dns.Msg)
+	m.SetQuestion("example.com.", dns.TypeA)
+
+	co, err := dns.Dial("tcp", addr)
+	if err != nil {
+		t.Fatalf("Failed to dial: %s", err)
+	}
+	defer co.Close()
+	if _, err := exchange(co, m); err != nil {
+		t.Fatalf("Expected reply, got %s", err)
+	}
+
+	time.Sleep(200 * time.Millisecond)
+	co.SetDeadline(time.Now().Add(2 * time.Second))
+	if _, err := exchange(co, m); err == nil {
+		t.Errorf("Expected the idle connection to be closed")
+	}
+}
+
+func TestTCPStop(t *testing.T) {
+	p := slowPlugin{release: make(chan struct{})}
+	s, addr := startTCPServer(t, &TCPConfig{Pipeline: 1, IdleTimeout: time.Second, ReadTimeout: time.Second}, p)
+
+	co, err := dns.Dial("tcp", addr)
+	if err != nil {
+		t.Fatalf("Failed to dial: %s", err)
+	}
+	defer co.Close()
+
+	slow := new(dns.Msg)
+	slow.SetQuestion("slow.example.com.", dns.TypeA)
+	if err := co.WriteMsg(slow); err != nil {
+		t.Fatalf("Failed to write query: %s", err)
+	}
+	time.Sleep(50 * time.Millisecond)
+
+	stopped := make(chan struct{})
+	go func() {
+		s.Stop()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+		t.Fatalf("Expected Stop to wait for the outstanding query")
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	close(p.release)
+	co.SetReadDeadline(time.Now().Add(2 * time.Second))
+	if _, err := co.ReadMsg(); err != nil {
+		t.Errorf("Expected reply to the outstanding query, got %s", err)
+	}
+	select {
+	case <-stopped:
+	case <-time.After(2 * time.Second):
+		t.Errorf("Expected Stop to return once the query was answered")
+	}
+}
+
+func TestTCPStopOpenConnection(t *testing.T) {
+	p := slowPlugin{release: make(chan struct{})}
+	s, addr := startTCPServer(t, &TCPConfig{Pipeline: 2, IdleTimeout: time.Minute, ReadTimeout: time.Minute}, p)
+
+	m := new(dns.Msg)
+	m.SetQuestion("example.com.", dns.TypeA)
+
+	co, err := dns.Dial("tcp", addr)
+	if err != nil {
+		t.Fatalf("Failed to dial: %s", err)
+	}
+	defer co.Close()
+	if _, err := exchange(co, m); err != nil {
+		t.Fatalf("Expected reply, got %s", err)
+	}
+
+	stopped := make(chan struct{})
+	go func() {
+		s.Stop()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+	case <-time.After(2 * time.Second):
+		t.Fatalf("Expected Stop to close the idle connection")
+	}
+
+	co.SetDeadline(time.Now().Add(2 * time.Second))
+	if _, err := exchange(co, m); err == nil {
+		t.Errorf("Expected no more queries to be handled after Stop")
+	}
+}
+
+func exchange(co *dns.Conn, m *dns.Msg) (*dns.Msg, error) {
+	if err := co.WriteMsg(m); err != nil {
+		return nil, err
+	}
+	return co.ReadMsg()
+}
@@ -0,0 +1,276 @@
+package dnsserver
+
+import (
+	"net"
+	"sync"
+	"time"
+
+	"github.com/coredns/coredns/plugin/metrics/vars"
+	"github.com/coredns/coredns/plugin/pkg/log"
+	"github.com/coredns/coredns/plugin/pkg/mmsg"
+	"github.com/coredns/coredns/plugin/pkg/proxyproto"
+
+	"github.com/miekg/dns"
+)
+
+// udpServer serves the queries of a UDP socket, it reads queries and writes replies in batches
+// of up to udpBatchSize messages per system call. It replaces the dns.Server, which reads and
+// writes a single message per system call and can't read PROXY protocol headers.
+type udpServer struct {
+	addr    string // server address, used as metrics label
+	c       udpConn
+	handler dns.Handler
+	proxy   *proxyproto.Config // read PROXY protocol headers, nil when disabled
+
+	out      chan mmsg.Message // replies that wait to be written
+	stopping chan struct{}     // closed when Shutdown is called, no new queries are handled
+	done     chan struct{}     // closed when the replies of the handled queries are written
+	written  chan struct{}     // closed when write returns
+	handlers sync.WaitGroup    // queries that are being handled
+	mu       sync.Mutex        // orders adding handlers and closing stopping
+	once     sync.Once
+	drops    uint32 // the drop counter of the socket, as last reported
+}
+
+func newUDPServer(addr string, c udpConn, handler dns.Handler, proxy *proxyproto.Config) *udpServer {
+	u := &udpServer{
+		addr:     addr,
+		c:        c,
+		handler:  handler,
+		proxy:    proxy,
+		out:      make(chan mmsg.Message, udpBatchSize),
+		stopping: make(chan struct{}),
+		done:     make(chan struct{}),
+		written:  make(chan struct{}),
+	}
+	go u.write()
+	return u
+}
+
+// serve reads queries until the socket is closed and handles each of them in its own goroutine.
+func (u *udpServer) serve() error {
+	ms := make([]mmsg.Message, udpBatchSize)
+	for i := range ms {
+		ms[i].Buf = make([]byte, udpBufSize)
+	}
+	for {
+		n, err := u.c.ReadBatch(ms)
+		if err != nil {
+			select {
+			case <-u.done:
+				return nil
+			default:
+			}
+			if ne, ok := err.(net.Error); ok && ne.Temporary() {
+				time.Sleep(udpReadBackoff)
+				continue
+			}
+			return err
+		}
+		u.reportDrops()
+
+		for _, m := range ms[:n] {
+			buf, raddr := m.Buf[:m.N], m.Addr
+			if u.proxy != nil {
+				var ok bool
+				if buf, raddr, ok = u.proxy.Packet(buf, raddr); !ok {
+					continue
+				}
+			}
+			// Drop what is not a query: too short to hold a header, or a reply.
+			if len(buf) < headerSize || buf[2]&qrBit != 0 {
+				continue
+			}
+			req := new(dns.Msg)
+			if err := req.Unpack(buf); err != nil {
+				continue
+			}
+			if !u.handle() {
+				continue
+			}
+			w := &udpWriter{u: u, addr: m.Addr, raddr: raddr, dst: m.Dst}
+			go func() {
+				defer u.handlers.Done()
+				u.handler.ServeDNS(w, req)
+			}()
+		}
+	}
+}
+
+// handle adds a query to the handlers, it returns false when the server is shutting down.
+func (u *udpServer) handle() bool {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	select {
+	case <-u.stopping:
+		return false
+	default:
+	}
+	u.handlers.Add(1)
+	return true
+}
+
+// write writes the replies in batches until the server is shut down, then it writes the replies
+// that are still queued. A batch holds the replies that are ready, it doesn't wait for more.
+func (u *udpServer) write() {
+	defer close(u.written)
+
+	batch := make([]mmsg.Message, 0, udpBatchSize)
+	for {
+		select {
+		case m := <-u.out:
+			batch = append(batch[:0], m)
+		case <-u.done:
+			select {
+			case m := <-u.out:
+				batch = append(batch[:0], m)
+			default:
+				return
+			}
+		}
+	more:
+		for len(batch) < udpBatchSize {
+			select {
+			case m := <-u.out:
+				batch = append(batch, m)
+			default:
+				break more
+			}
+		}
+
+		for ms := batch; len(ms) > 0; {
+			n, err := u.c.WriteBatch(ms)
+			if err != nil {
+				// Skip the reply that failed, the others may still be written.
+				log.With("server", u.addr).Debugf("Failed to write reply to %s: %s", ms[n].Addr, err)
+				n++
+			}
+			ms = ms[n:]
+		}
+	}
+}
+
+// reportDrops adds the queries the kernel dropped since the last call to the metrics.
+func (u *udpServer) reportDrops() {
+	d := u.c.Drops()
+	if d == u.drops {
+		return
+	}
+	vars.UDPDrops.WithLabelValues(u.addr).Add(float64(d - u.drops))
+	u.drops = d
+}
+
+// Shutdown stops handling new queries and waits, up to udpShutdownTimeout, for the queries that
+// are being handled. It closes the socket once their replies are written.
+func (u *udpServer) Shutdown() error {
+	u.once.Do(func() {
+		u.mu.Lock()
+		close(u.stopping)
+		u.mu.Unlock()
+
+		handled := make(chan struct{})
+		go func() {
+			u.handlers.Wait()
+			close(handled)
+		}()
+		select {
+		case <-handled:
+		case <-time.After(udpShutdownTimeout):
+		}
+
+		close(u.done)
+	})
+	<-u.written
+	return u.c.Close()
+}
+
+// udpWriter is the dns.ResponseWriter for a query received by a udpServer.
+type udpWriter struct {
+	u     *udpServer
+	addr  *net.UDPAddr // the address the query came from, the reply is sent here
+	raddr *net.UDPAddr // the address of the client, differs from addr behind a PROXY protocol proxy
+	dst   net.IP       // the address the query was sent to, nil when unknown
+}
+
+// Write queues the message b to be written to the client.
+func (w *udpWriter) Write(b []byte) (int, error) {
+	m := mmsg.Message{Buf: append([]byte(nil), b...), N: len(b), Addr: w.addr, Dst: w.dst}
+	select {
+	case w.u.out <- m:
+		return len(b), nil
+	case <-w.u.done:
+		return 0, errServerClosed
+	}
+}
+
+// WriteMsg packs m and queues it to be written to the client.
+func (w *udpWriter) WriteMsg(m *dns.Msg) error {
+	buf, err := m.Pack()
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(buf)
+	return err
+}
+
+// LocalAddr returns the address the query was sent to, or the address of the socket when that is unknown.
+func (w *udpWriter) LocalAddr() net.Addr {
+	la := w.u.c.LocalAddr()
+	if w.dst == nil {
+		return la
+	}
+	return &net.UDPAddr{IP: w.dst, Port: la.(*net.UDPAddr).Port}
+}
+
+// These methods implement the dns.ResponseWriter interface from Go DNS.
+func (w *udpWriter) Close() error          { return nil }
+func (w *udpWriter) TsigStatus() error     { return nil }
+func (w *udpWriter) TsigTimersOnly(b bool) { return }
+func (w *udpWriter) Hijack()               { return }
+func (w *udpWriter) RemoteAddr() net.Addr  { return w.raddr }
+
+// udpConn reads and writes batches of UDP messages, see mmsg.Conn.
+type udpConn interface {
+	ReadBatch(ms []mmsg.Message) (int, error)
+	WriteBatch(ms []mmsg.Message) (int, error)
+	Drops() uint32
+	LocalAddr() net.Addr
+	Close() error
+}
+
+// singleConn is a udpConn that reads and writes a single message per system call, for when
+// batches aren't supported. The replies are sent from the address of the socket.
+type singleConn struct {
+	*net.UDPConn
+}
+
+// ReadBatch reads one message into ms[0].
+func (c singleConn) ReadBatch(ms []mmsg.Message) (int, error) {
+	n, addr, err := c.ReadFromUDP(ms[0].Buf)
+	if err != nil {
+		return 0, err
+	}
+	ms[0].N, ms[0].Addr, ms[0].Dst = n, addr, nil
+	return 1, nil
+}
+
+// WriteBatch writes the message ms[0].
+func (c singleConn) WriteBatch(ms []mmsg.Message) (int, error) {
+	if _, err := c.WriteToUDP(ms[0].Buf[:ms[0].N], ms[0].Addr); err != nil {
+		return 0, err
+	}
+	return 1, nil
+}
+
+// Drops returns 0, the drops are not known.
+func (c singleConn) Drops() uint32 { return 0 }
+
+const (
+	udpBatchSize = 64
+	udpBufSize   = 4096 // larger queries are truncated, and fail to unpack
+	headerSize   = 12
+	qrBit        = 1 << 7 // in the third octet of the header
+
+	udpReadBackoff     = 5 * time.Millisecond
+	udpShutdownTimeout = 5 * time.Second
+)
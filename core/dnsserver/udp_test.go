@@ -0,0 +1,198 @@
+package dnsserver
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/coredns/coredns/plugin/pkg/proxyproto"
+
+	"github.com/miekg/dns"
+)
+
+func TestUDPBatch(t *testing.T) {
+	p := slowPlugin{release: make(chan struct{})}
+	c := testConfig("dns", p)
+	c.NumSockets = 1
+	s, err := NewServer("dns://127.0.0.1:0", []*Config{c})
+	if err != nil {
+		t.Fatalf("Expected no error for NewServer, got %s", err)
+	}
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %s", err)
+	}
+	go s.ServePacket(pc)
+	defer s.Stop()
+
+	co, err := dns.Dial("udp", pc.LocalAddr().String())
+	if err != nil {
+		t.Fatalf("Failed to dial: %s", err)
+	}
+	defer co.Close()
+
+	slow := new(dns.Msg)
+	slow.SetQuestion("slow.example.com.", dns.TypeA)
+	if err := co.WriteMsg(slow); err != nil {
+		t.Fatalf("Failed to write query: %s", err)
+	}
+	// Queries are handled concurrently, these are answered before the slow one.
+	queries := map[uint16]bool{}
+	for i := 0; i < 10; i++ {
+		m := new(dns.Msg)
+		m.SetQuestion("fast.example.com.", dns.TypeA)
+		queries[m.Id] = true
+		if err := co.WriteMsg(m); err != nil {
+			t.Fatalf("Failed to write query: %s", err)
+		}
+	}
+
+	co.SetReadDeadline(time.Now().Add(2 * time.Second))
+	for len(queries) > 0 {
+		r, err := co.ReadMsg()
+		if err != nil {
+			t.Fatalf("Failed to read reply: %s", err)
+		}
+		if !queries[r.Id] {
+			t.Fatalf("Expected a reply to a fast query, got %s with ID %d", r.Question[0].Name, r.Id)
+		}
+		delete(queries, r.Id)
+	}
+
+	close(p.release)
+	r, err := co.ReadMsg()
+	if err != nil {
+		t.Fatalf("Failed to read reply: %s", err)
+	}
+	if r.Id != slow.Id {
+		t.Errorf("Expected the reply to the slow query, got ID %d", r.Id)
+	}
+}
+
+func TestUDPStop(t *testing.T) {
+	p := slowPlugin{release: make(chan struct{})}
+	c := testConfig("dns", p)
+	c.NumSockets = 1
+	s, err := NewServer("dns://127.0.0.1:0", []*Config{c})
+	if err != nil {
+		t.Fatalf("Expected no error for NewServer, got %s", err)
+	}
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %s", err)
+	}
+	go s.ServePacket(pc)
+
+	co, err := dns.Dial("udp", pc.LocalAddr().String())
+	if err != nil {
+		t.Fatalf("Failed to dial: %s", err)
+	}
+	defer co.Close()
+
+	slow := new(dns.Msg)
+	slow.SetQuestion("slow.example.com.", dns.TypeA)
+	if err := co.WriteMsg(slow); err != nil {
+		t.Fatalf("Failed to write query: %s", err)
+	}
+	time.Sleep(50 * time.Millisecond)
+
+	stopped := make(chan struct{})
+	go func() {
+		s.Stop()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+		t.Fatalf("Expected Stop to wait for the query that is being handled")
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	close(p.release)
+	co.SetReadDeadline(time.Now().Add(2 * time.Second))
+	if _, err := co.ReadMsg(); err != nil {
+		t.Errorf("Expected reply to the query that was being handled, got %s", err)
+	}
+	select {
+	case <-stopped:
+	case <-time.After(2 * time.Second):
+		t.Errorf("Expected Stop to return once the query was answered")
+	}
+}
+
+// addrPlugin answers every query with a TXT record that holds the remote address of the query.
+type addrPlugin struct{}
+
+func (ap addrPlugin) ServeDNS(ctx context.Context, w dns.ResponseWriter, r *dns.Msg) (int, error) {
+	m := new(dns.Msg)
+	m.SetReply(r)
+	m.Answer = []dns.RR{&dns.TXT{Hdr: dns.RR_Header{Name: r.Question[0].Name, Rrtype: dns.TypeTXT, Class: dns.ClassINET}, Txt: []string{w.RemoteAddr().String()}}}
+	w.WriteMsg(m)
+	return dns.RcodeSuccess, nil
+}
+
+func (ap addrPlugin) Name() string { return "addrplugin" }
+
+func TestUDPProxyProtocol(t *testing.T) {
+	c := testConfig("dns", addrPlugin{})
+	c.ProxyProtocol = &proxyproto.Config{Allow: []*net.IPNet{{IP: net.ParseIP("127.0.0.0"), Mask: net.CIDRMask(8, 32)}}}
+	s, err := NewServer("dns://127.0.0.1:0", []*Config{c})
+	if err != nil {
+		t.Fatalf("Expected no error for NewServer, got %s", err)
+	}
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %s", err)
+	}
+	go s.ServePacket(pc)
+	defer s.Stop()
+
+	co, err := net.Dial("udp", pc.LocalAddr().String())
+	if err != nil {
+		t.Fatalf("Failed to dial: %s", err)
+	}
+	defer co.Close()
+
+	// A version 2 header of a UDP over IPv4 query from 192.0.2.1:53124 to 192.0.2.53:53.
+	header := []byte("\r\n\r\n\x00\r\nQUIT\n\x21\x12\x00\x0c\xc0\x00\x02\x01\xc0\x00\x02\x35\xcf\x84\x00\x35")
+
+	// The query without a header is dropped, the proxy must send one.
+	m := new(dns.Msg)
+	m.SetQuestion("example.com.", dns.TypeTXT)
+	buf, _ := m.Pack()
+	co.Write(buf)
+	m.Id++
+	buf, _ = m.Pack()
+	co.Write(append(header, buf...))
+
+	co.SetReadDeadline(time.Now().Add(2 * time.Second))
+	b := make([]byte, dns.MinMsgSize)
+	n, err := co.Read(b)
+	if err != nil {
+		t.Fatalf("Failed to read reply: %s", err)
+	}
+	r := new(dns.Msg)
+	if err := r.Unpack(b[:n]); err != nil {
+		t.Fatalf("Failed to unpack reply: %s", err)
+	}
+	if r.Id != m.Id {
+		t.Fatalf("Expected the reply to the query with a header, got ID %d", r.Id)
+	}
+	if len(r.Answer) != 1 || r.Answer[0].(*dns.TXT).Txt[0] != "192.0.2.1:53124" {
+		t.Errorf("Expected the client address from the header, got %v", r.Answer)
+	}
+}
+
+func TestAddress(t *testing.T) {
+	s, err := NewServer("dns://:53", []*Config{testConfig("dns", testPlugin{})})
+	if err != nil {
+		t.Fatalf("Expected no error for NewServer, got %s", err)
+	}
+	if x := s.Address(); x != "dns://:53" {
+		t.Errorf("Expected address %s, got %s", "dns://:53", x)
+	}
+	s.socket = 2
+	if x := s.Address(); x != "dns://:53#2" {
+		t.Errorf("Expected address %s, got %s", "dns://:53#2", x)
+	}
+}
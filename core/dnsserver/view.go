@@ -0,0 +1,50 @@
+package dnsserver
+
+import (
+	"context"
+
+	"github.com/coredns/coredns/request"
+
+	"github.com/miekg/dns"
+)
+
+// MetadataCollector is implemented by the plugin that collects the metadata of all metadata
+// providers. The server calls Collect for each server block it considers for a query, so the
+// metadata can be used by the view of that server block.
+type MetadataCollector interface {
+	Collect(ctx context.Context, state request.Request) context.Context
+}
+
+// ViewKey is the context key for the name of the view that was selected for a query.
+type ViewKey struct{}
+
+// WithView returns the name of the view that selected the server block handling this query. If no
+// view was used the empty string is returned.
+func WithView(ctx context.Context) string {
+	if v, ok := ctx.Value(ViewKey{}).(string); ok {
+		return v
+	}
+	return ""
+}
+
+// selected returns true if the server block described by c must handle the query r. The
+// FilterFunc is checked for non-DS queries, and if c has a view, the view must match as well. The
+// metadata is collected with c's own collector, so the view and the plugins of c see the metadata of
+// their server block. The returned context holds the collected metadata and the view name.
+func (c *Config) selected(ctx context.Context, w dns.ResponseWriter, r *dns.Msg) (context.Context, bool) {
+	if c.FilterFunc != nil && r.Question[0].Qtype != dns.TypeDS && !c.FilterFunc(r.Question[0].Name) {
+		return ctx, false
+	}
+
+	state := request.Request{W: w, Req: r}
+	if c.metaCollector != nil {
+		ctx = c.metaCollector.Collect(ctx, state)
+	}
+	if c.ViewFunc == nil {
+		return ctx, true
+	}
+	if !c.ViewFunc(ctx, state) {
+		return ctx, false
+	}
+	return context.WithValue(ctx, ViewKey{}, c.ViewName), true
+}
@@ -12,6 +12,10 @@ package dnsserver
 var Directives = []string{
 	"metadata",
 	"tls",
+	"proxyproto",
+	"tcp",
+	"multisocket",
+	"timeout",
 	"reload",
 	"nsid",
 	"root",
@@ -19,11 +23,16 @@ var Directives = []string{
 	"debug",
 	"trace",
 	"health",
+	"ready",
 	"pprof",
+	"admin",
 	"prometheus",
 	"errors",
 	"log",
 	"dnstap",
+	"acl",
+	"cookie",
+	"rrl",
 	"chaos",
 	"loadbalance",
 	"cache",
@@ -45,4 +54,5 @@ var Directives = []string{
 	"erratic",
 	"whoami",
 	"on",
+	"view",
 }
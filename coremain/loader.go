@@ -0,0 +1,112 @@
+package coremain
+
+import (
+	"bytes"
+	"fmt"
+	"net/url"
+	"strings"
+	"sync"
+
+	clog "github.com/coredns/coredns/plugin/pkg/log"
+
+	"github.com/mholt/caddy"
+)
+
+// A Loader loads the Corefile from somewhere else than a file. It is selected with -conf
+// SCHEME://..., where SCHEME is the scheme it is registered for.
+type Loader interface {
+	// Load returns the Corefile.
+	Load(serverType string) (caddy.Input, error)
+	// Watch calls changed with the new Corefile every time it changes, until stop is closed. It may
+	// also call changed with the Corefile that is running.
+	Watch(serverType string, stop <-chan struct{}, changed func(caddy.Input))
+}
+
+// RegisterLoader registers the function that creates a Loader for the -conf values with scheme.
+func RegisterLoader(scheme string, newLoader func(u *url.URL) (Loader, error)) {
+	loaders[scheme] = newLoader
+}
+
+var loaders = make(map[string]func(u *url.URL) (Loader, error))
+
+// The loader for -conf, created once as the Corefile is loaded again on every reload.
+var (
+	loader     Loader
+	loaderErr  error
+	loaderOnce sync.Once
+)
+
+// confURLLoader returns the Loader for conf, or nil when conf isn't a URL.
+func confURLLoader(conf string) (Loader, error) {
+	if !strings.Contains(conf, "://") {
+		return nil, nil
+	}
+	loaderOnce.Do(func() {
+		u, err := parseConfURL(conf)
+		if err != nil {
+			loaderErr = err
+			return
+		}
+		newLoader, ok := loaders[u.Scheme]
+		if !ok {
+			loaderErr = fmt.Errorf("no Corefile loader for %q", u.Scheme)
+			return
+		}
+		loader, loaderErr = newLoader(u)
+	})
+	return loader, loaderErr
+}
+
+// parseConfURL parses the URL conf. Its host may list several hosts, separated by commas, each with
+// a port, which url.Parse doesn't allow.
+func parseConfURL(conf string) (*url.URL, error) {
+	i := strings.Index(conf, "://")
+	rest := conf[i+len("://"):]
+	host := rest
+	if j := strings.IndexAny(rest, "/?"); j >= 0 {
+		host = rest[:j]
+	}
+	u, err := url.Parse(conf[:i+len("://")] + rest[len(host):])
+	if err != nil {
+		return nil, err
+	}
+	u.Host = host
+	return u, nil
+}
+
+// watchCorefile restarts the running instance every time l sees a new Corefile. This is the same
+// restart the reload plugin does.
+func watchCorefile(l Loader, serverType string, stop <-chan struct{}) {
+	l.Watch(serverType, stop, func(corefile caddy.Input) {
+		instance := running()
+		if instance == nil || bytes.Equal(instance.Caddyfile().Body(), corefile.Body()) {
+			return
+		}
+		clog.Infof("Corefile %s changed, reloading", corefile.Path())
+		if _, err := instance.Restart(corefile); err != nil {
+			clog.Errorf("Corefile changed but reload failed: %s", err)
+		}
+	})
+}
+
+// The running instance, it is replaced on every restart.
+var (
+	instanceMu sync.Mutex
+	instance   *caddy.Instance
+)
+
+func running() *caddy.Instance {
+	instanceMu.Lock()
+	defer instanceMu.Unlock()
+	return instance
+}
+
+func trackInstance(event caddy.EventName, info interface{}) error {
+	if event != caddy.InstanceStartupEvent {
+		return nil
+	}
+	instanceMu.Lock()
+	instance = info.(*caddy.Instance)
+	instanceMu.Unlock()
+	return nil
+}
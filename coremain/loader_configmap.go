@@ -0,0 +1,125 @@
+package coremain
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	clog "github.com/coredns/coredns/plugin/pkg/log"
+
+	"github.com/mholt/caddy"
+	api "k8s.io/api/core/v1"
+	meta "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+func init() { RegisterLoader("configmap", newConfigMapLoader) }
+
+// configMapLoader loads the Corefile from a Kubernetes ConfigMap: configmap://NAMESPACE/NAME[/KEY],
+// where KEY defaults to Corefile. The Kubernetes API is reached from within the cluster, or with
+// the kubeconfig file in the query parameter kubeconfig.
+type configMapLoader struct {
+	conf      string
+	namespace string
+	name      string
+	key       string
+	client    kubernetes.Interface
+}
+
+func newConfigMapLoader(u *url.URL) (Loader, error) {
+	c, err := configMapLoaderArgs(u)
+	if err != nil {
+		return nil, err
+	}
+	cc, err := clientcmd.BuildConfigFromFlags("", u.Query().Get("kubeconfig"))
+	if err != nil {
+		return nil, err
+	}
+	cc.Timeout = loaderTimeout
+	if c.client, err = kubernetes.NewForConfig(cc); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// configMapLoaderArgs returns a configMapLoader, without a client, for u.
+func configMapLoaderArgs(u *url.URL) (*configMapLoader, error) {
+	path := strings.Split(strings.Trim(u.Path, "/"), "/")
+	if u.Host == "" || path[0] == "" || len(path) > 2 {
+		return nil, fmt.Errorf("ConfigMap Corefile must be configmap://NAMESPACE/NAME[/KEY]: %s", u)
+	}
+	c := &configMapLoader{conf: u.String(), namespace: u.Host, name: path[0], key: caddy.DefaultConfigFile}
+	if len(path) == 2 {
+		c.key = path[1]
+	}
+	return c, nil
+}
+
+// Load implements Loader.
+func (c *configMapLoader) Load(serverType string) (caddy.Input, error) {
+	cm, err := c.client.CoreV1().ConfigMaps(c.namespace).Get(c.name, meta.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+	return c.input(cm, serverType)
+}
+
+// Watch implements Loader. The ConfigMap is watched through the API, so changes are seen right away
+// and not after the kubelet synced the volume it is mounted on.
+func (c *configMapLoader) Watch(serverType string, stop <-chan struct{}, changed func(caddy.Input)) {
+	opts := meta.ListOptions{FieldSelector: fields.OneTermEqualSelector("metadata.name", c.name).String()}
+	for {
+		w, err := c.client.CoreV1().ConfigMaps(c.namespace).Watch(opts)
+		if err != nil {
+			clog.Warningf("Failed to watch Corefile %s: %s", c.conf, err)
+		} else {
+			c.watch(w, serverType, stop, changed)
+		}
+
+		select {
+		case <-stop:
+			return
+		case <-time.After(loaderRetry):
+		}
+	}
+}
+
+// watch handles the events of w until it is closed or stop is closed.
+func (c *configMapLoader) watch(w watch.Interface, serverType string, stop <-chan struct{}, changed func(caddy.Input)) {
+	defer w.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case ev, ok := <-w.ResultChan():
+			if !ok {
+				return
+			}
+			if ev.Type != watch.Added && ev.Type != watch.Modified {
+				continue
+			}
+			cm, ok := ev.Object.(*api.ConfigMap)
+			if !ok {
+				continue
+			}
+			input, err := c.input(cm, serverType)
+			if err != nil {
+				clog.Warningf("Failed to load Corefile %s: %s", c.conf, err)
+				continue
+			}
+			changed(input)
+		}
+	}
+}
+
+func (c *configMapLoader) input(cm *api.ConfigMap, serverType string) (caddy.Input, error) {
+	corefile, ok := cm.Data[c.key]
+	if !ok {
+		return nil, fmt.Errorf("key %s not found in ConfigMap %s/%s", c.key, c.namespace, c.name)
+	}
+	return caddy.CaddyfileInput{Contents: []byte(corefile), Filepath: c.conf, ServerTypeName: serverType}, nil
+}
@@ -0,0 +1,147 @@
+package coremain
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	clog "github.com/coredns/coredns/plugin/pkg/log"
+	mwtls "github.com/coredns/coredns/plugin/pkg/tls"
+
+	etcdcv3 "github.com/coreos/etcd/clientv3"
+	"github.com/mholt/caddy"
+)
+
+func init() { RegisterLoader("etcd", newEtcdLoader) }
+
+// etcdLoader loads the Corefile from a key in etcd: etcd://HOST[:PORT][,HOST[:PORT]...]/KEY. The
+// query parameters cert, key and cacert configure TLS, as the tls option of the etcd plugin does.
+type etcdLoader struct {
+	conf   string
+	key    string
+	client *etcdcv3.Client
+}
+
+func newEtcdLoader(u *url.URL) (Loader, error) {
+	endpoints, key, cc, err := etcdLoaderArgs(u)
+	if err != nil {
+		return nil, err
+	}
+	client, err := etcdcv3.New(etcdcv3.Config{Endpoints: endpoints, TLS: cc, DialTimeout: loaderTimeout})
+	if err != nil {
+		return nil, err
+	}
+	return &etcdLoader{conf: u.String(), key: key, client: client}, nil
+}
+
+// etcdLoaderArgs returns the endpoints, the key and the TLS configuration of u.
+func etcdLoaderArgs(u *url.URL) ([]string, string, *tls.Config, error) {
+	key := u.Path
+	if u.Host == "" || key == "" || key == "/" {
+		return nil, "", nil, fmt.Errorf("etcd Corefile must be etcd://HOST[:PORT]/KEY: %s", u)
+	}
+
+	var cc *tls.Config
+	q := u.Query()
+	var args []string
+	for _, a := range []string{q.Get("cert"), q.Get("key"), q.Get("cacert")} {
+		if a != "" {
+			args = append(args, a)
+		}
+	}
+	if len(args) > 0 {
+		var err error
+		if cc, err = mwtls.NewTLSConfigFromArgs(args...); err != nil {
+			return nil, "", nil, err
+		}
+	}
+
+	scheme := "http://"
+	if cc != nil {
+		scheme = "https://"
+	}
+	var endpoints []string
+	for _, h := range strings.Split(u.Host, ",") {
+		if !strings.Contains(h, ":") {
+			h += ":2379"
+		}
+		endpoints = append(endpoints, scheme+h)
+	}
+	return endpoints, key, cc, nil
+}
+
+// Load implements Loader.
+func (e *etcdLoader) Load(serverType string) (caddy.Input, error) {
+	input, _, err := e.get(context.Background(), serverType)
+	return input, err
+}
+
+// get returns the Corefile and the revision of etcd it was read at.
+func (e *etcdLoader) get(ctx context.Context, serverType string) (caddy.Input, int64, error) {
+	ctx, cancel := context.WithTimeout(ctx, loaderTimeout)
+	defer cancel()
+	r, err := e.client.Get(ctx, e.key)
+	if err != nil {
+		return nil, 0, err
+	}
+	if len(r.Kvs) == 0 {
+		return nil, 0, fmt.Errorf("etcd key %s not found", e.key)
+	}
+	return e.input(r.Kvs[0].Value, serverType), r.Header.Revision, nil
+}
+
+// Watch implements Loader.
+func (e *etcdLoader) Watch(serverType string, stop <-chan struct{}, changed func(caddy.Input)) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		<-stop
+		cancel()
+	}()
+
+	for {
+		// Read the key before every watch and watch from the revision after it, so the changes
+		// made while there was no watch, or that were compacted away, aren't lost.
+		input, rev, err := e.get(ctx, serverType)
+		if err != nil {
+			clog.Warningf("Failed to load Corefile %s: %s", e.conf, err)
+		} else {
+			changed(input)
+			e.watch(ctx, rev+1, serverType, changed)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(loaderRetry):
+		}
+	}
+}
+
+// watch calls changed for every new value of the key from revision rev on, until the watch channel
+// is closed. This happens when ctx is canceled, when etcd is gone, or when rev is compacted.
+func (e *etcdLoader) watch(ctx context.Context, rev int64, serverType string, changed func(caddy.Input)) {
+	for wr := range e.client.Watch(ctx, e.key, etcdcv3.WithRev(rev)) {
+		if err := wr.Err(); err != nil {
+			clog.Warningf("Failed to watch Corefile %s: %s", e.conf, err)
+			continue
+		}
+		for _, ev := range wr.Events {
+			if ev.Type == etcdcv3.EventTypePut {
+				changed(e.input(ev.Kv.Value, serverType))
+			}
+		}
+	}
+}
+
+func (e *etcdLoader) input(contents []byte, serverType string) caddy.Input {
+	return caddy.CaddyfileInput{Contents: contents, Filepath: e.conf, ServerTypeName: serverType}
+}
+
+const (
+	loaderTimeout = 5 * time.Second
+	loaderRetry   = 5 * time.Second
+)
@@ -0,0 +1,121 @@
+package coremain
+
+import (
+	"net/url"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/mholt/caddy"
+	api "k8s.io/api/core/v1"
+	meta "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestEtcdLoaderArgs(t *testing.T) {
+	tests := []struct {
+		conf      string
+		shouldErr bool
+		endpoints []string
+		key       string
+	}{
+		{"etcd://localhost/coredns/Corefile", false, []string{"http://localhost:2379"}, "/coredns/Corefile"},
+		{"etcd://10.0.0.1:2380,10.0.0.2/Corefile", false, []string{"http://10.0.0.1:2380", "http://10.0.0.2:2379"}, "/Corefile"},
+		{"etcd://localhost/", true, nil, ""},
+		{"etcd:///Corefile", true, nil, ""},
+	}
+
+	for i, tc := range tests {
+		u, err := parseConfURL(tc.conf)
+		if err != nil {
+			t.Fatalf("Test %d: failed to parse %s: %s", i, tc.conf, err)
+		}
+		endpoints, key, _, err := etcdLoaderArgs(u)
+		if tc.shouldErr {
+			if err == nil {
+				t.Errorf("Test %d: expected error, got none", i)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Test %d: expected no error, got %s", i, err)
+			continue
+		}
+		if !reflect.DeepEqual(endpoints, tc.endpoints) || key != tc.key {
+			t.Errorf("Test %d: expected %v %s, got %v %s", i, tc.endpoints, tc.key, endpoints, key)
+		}
+	}
+}
+
+func TestConfigMapLoaderArgs(t *testing.T) {
+	tests := []struct {
+		conf      string
+		shouldErr bool
+		namespace string
+		name      string
+		key       string
+	}{
+		{"configmap://kube-system/coredns", false, "kube-system", "coredns", "Corefile"},
+		{"configmap://kube-system/coredns/Corefile.custom", false, "kube-system", "coredns", "Corefile.custom"},
+		{"configmap://kube-system/", true, "", "", ""},
+		{"configmap://kube-system/coredns/Corefile/more", true, "", "", ""},
+	}
+
+	for i, tc := range tests {
+		u, _ := url.Parse(tc.conf)
+		c, err := configMapLoaderArgs(u)
+		if tc.shouldErr {
+			if err == nil {
+				t.Errorf("Test %d: expected error, got none", i)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Test %d: expected no error, got %s", i, err)
+			continue
+		}
+		if c.namespace != tc.namespace || c.name != tc.name || c.key != tc.key {
+			t.Errorf("Test %d: expected %s/%s/%s, got %s/%s/%s", i, tc.namespace, tc.name, tc.key, c.namespace, c.name, c.key)
+		}
+	}
+}
+
+func TestConfigMapLoader(t *testing.T) {
+	cm := &api.ConfigMap{
+		ObjectMeta: meta.ObjectMeta{Name: "coredns", Namespace: "kube-system"},
+		Data:       map[string]string{"Corefile": ". {\n    whoami\n}\n"},
+	}
+	client := fake.NewSimpleClientset(cm)
+	c := &configMapLoader{conf: "configmap://kube-system/coredns", namespace: "kube-system", name: "coredns", key: "Corefile", client: client}
+
+	input, err := c.Load(serverType)
+	if err != nil {
+		t.Fatalf("Expected no error, got %s", err)
+	}
+	if string(input.Body()) != cm.Data["Corefile"] {
+		t.Errorf("Expected Corefile %q, got %q", cm.Data["Corefile"], input.Body())
+	}
+
+	stop := make(chan struct{})
+	defer close(stop)
+	changed := make(chan caddy.Input, 10)
+	go c.Watch(serverType, stop, func(i caddy.Input) { changed <- i })
+
+	// The watch is started asynchronously, update until the change is seen.
+	want := ". {\n    erratic\n}\n"
+	cm.Data["Corefile"] = want
+	for i := 0; i < 100; i++ {
+		if _, err := client.CoreV1().ConfigMaps("kube-system").Update(cm); err != nil {
+			t.Fatalf("Failed to update ConfigMap: %s", err)
+		}
+		select {
+		case input := <-changed:
+			if string(input.Body()) != want {
+				t.Errorf("Expected Corefile %q, got %q", want, input.Body())
+			}
+			return
+		case <-time.After(20 * time.Millisecond):
+		}
+	}
+	t.Fatalf("Expected the change to be seen")
+}
@@ -23,14 +23,18 @@ func init() {
 	caddy.Quiet = true // don't show init stuff from caddy
 	setVersion()
 
-	flag.StringVar(&conf, "conf", "", "Corefile to load (default \""+caddy.DefaultConfigFile+"\")")
+	flag.StringVar(&conf, "conf", "", "Corefile to load, a file, stdin, etcd://... or configmap://... (default \""+caddy.DefaultConfigFile+"\")")
 	flag.StringVar(&cpu, "cpu", "100%", "CPU cap")
 	flag.BoolVar(&plugins, "plugins", false, "List installed plugins")
 	flag.StringVar(&caddy.PidFile, "pidfile", "", "Path to write pid file")
 	flag.BoolVar(&version, "version", false, "Show version")
+	flag.BoolVar(&validate, "validate", false, "Validate the Corefile and exit, no servers are started")
 	flag.BoolVar(&dnsserver.Quiet, "quiet", false, "Quiet mode (no initialization output)")
+	flag.StringVar(&logFormat, "log-format", "text", "Log format, text or json")
+	flag.StringVar(&logLevel, "log-level", "", "Log levels, the default level optionally followed by PLUGIN=LEVEL pairs, e.g. warning,forward=debug")
 
 	caddy.RegisterCaddyfileLoader("flag", caddy.LoaderFunc(confLoader))
+	caddy.RegisterEventHook("corefile", trackInstance)
 	caddy.SetDefaultCaddyfileLoader("default", caddy.LoaderFunc(defaultLoader))
 
 	caddy.AppName = coreName
@@ -64,6 +68,10 @@ func Run() {
 	log.SetOutput(os.Stdout)
 	log.SetFlags(0) // Set to 0 because we're doing our own time, with timezone
 
+	if err := setLog(logFormat, logLevel); err != nil {
+		mustLogFatal(err)
+	}
+
 	if version {
 		showVersion()
 		os.Exit(0)
@@ -84,6 +92,14 @@ func Run() {
 		mustLogFatal(err)
 	}
 
+	if validate {
+		if err := dnsserver.Validate(corefile); err != nil {
+			mustLogFatal(err)
+		}
+		fmt.Printf("%s: valid\n", corefile.Path())
+		os.Exit(0)
+	}
+
 	// Start your engines
 	instance, err := caddy.Start(corefile)
 	if err != nil {
@@ -98,6 +114,11 @@ func Run() {
 	// Execute instantiation events
 	caddy.EmitEvent(caddy.InstanceStartupEvent, instance)
 
+	// Reload when a Corefile that isn't a file changes.
+	if l, _ := confURLLoader(conf); l != nil {
+		go watchCorefile(l, serverType, nil)
+	}
+
 	// Twiddle your thumbs
 	instance.Wait()
 }
@@ -125,6 +146,14 @@ func confLoader(serverType string) (caddy.Input, error) {
 		return caddy.CaddyfileFromPipe(os.Stdin, serverType)
 	}
 
+	l, err := confURLLoader(conf)
+	if err != nil {
+		return nil, err
+	}
+	if l != nil {
+		return l.Load(serverType)
+	}
+
 	contents, err := ioutil.ReadFile(conf)
 	if err != nil {
 		return nil, err
@@ -232,13 +261,46 @@ func setCPU(cpu string) error {
 	return nil
 }
 
+// setLog sets the log format, "text" or "json", and the log levels. The levels are a comma separated
+// list of the default level and PLUGIN=LEVEL pairs, e.g. "warning,forward=debug".
+func setLog(format, levels string) error {
+	switch format {
+	case "text":
+	case "json":
+		clog.SetFormat(clog.JSON)
+		// Also log the lines of caddy and libraries as JSON.
+		log.SetOutput(clog.JSONWriter(os.Stdout))
+	default:
+		return fmt.Errorf("invalid log format: %q", format)
+	}
+
+	if levels == "" {
+		return nil
+	}
+	for _, l := range strings.Split(levels, ",") {
+		plugin := ""
+		if i := strings.Index(l, "="); i >= 0 {
+			plugin, l = l[:i], l[i+1:]
+		}
+		level, err := clog.ParseLevel(l)
+		if err != nil {
+			return err
+		}
+		clog.SetLevel(plugin, level)
+	}
+	return nil
+}
+
 // Flags that control program flow or startup
 var (
-	conf    string
-	cpu     string
-	logfile bool
-	version bool
-	plugins bool
+	conf      string
+	cpu       string
+	logfile   bool
+	logFormat string
+	logLevel  string
+	version   bool
+	plugins   bool
+	validate  bool
 )
 
 // Build information obtained with the help of -ldflags
@@ -3,6 +3,8 @@ package coremain
 import (
 	"runtime"
 	"testing"
+
+	clog "github.com/coredns/coredns/plugin/pkg/log"
 )
 
 func TestSetCPU(t *testing.T) {
@@ -42,3 +44,34 @@ func TestSetCPU(t *testing.T) {
 		runtime.GOMAXPROCS(currentCPU)
 	}
 }
+
+func TestSetLog(t *testing.T) {
+	defer clog.SetFormat(clog.Text)
+	defer clog.SetLevel("", clog.LevelInfo)
+	defer clog.ResetLevel("forward")
+
+	for i, test := range []struct {
+		format    string
+		levels    string
+		shouldErr bool
+	}{
+		{"text", "", false},
+		{"text", "warning,forward=debug", false},
+		{"xml", "", true},
+		{"text", "loud", true},
+		{"text", "forward=", true},
+	} {
+		err := setLog(test.format, test.levels)
+		if test.shouldErr && err == nil {
+			t.Errorf("Test %d: Expected error, but there wasn't any", i)
+		}
+		if !test.shouldErr && err != nil {
+			t.Errorf("Test %d: Expected no error, but there was one: %v", i, err)
+		}
+	}
+
+	def, plugins := clog.Levels()
+	if def != clog.LevelWarning || plugins["forward"] != clog.LevelDebug {
+		t.Errorf("Expected default level warning and forward debug, got %s and %v", def, plugins)
+	}
+}
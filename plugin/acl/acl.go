@@ -0,0 +1,126 @@
+// Package acl implements a plugin that enforces access control on queries, based on the
+// client's source address and the query type.
+package acl
+
+import (
+	"context"
+	"net"
+
+	"github.com/coredns/coredns/plugin"
+	"github.com/coredns/coredns/plugin/metrics"
+	"github.com/coredns/coredns/request"
+
+	"github.com/miekg/dns"
+)
+
+// ACL enforces access control policies on DNS queries.
+type ACL struct {
+	Next  plugin.Handler
+	Rules []rule
+}
+
+// rule defines a list of zones and the policies that are enforced on them.
+type rule struct {
+	zones    []string
+	policies []policy
+}
+
+// policy performs its action on all queries that match both the source networks
+// and the query types.
+type policy struct {
+	action action
+	qtypes map[uint16]struct{}
+	nets   []*net.IPNet
+}
+
+// action defines what to do with a matching query.
+type action int
+
+const (
+	// actionNone does nothing on the queries.
+	actionNone action = iota
+	// actionAllow allows authorized queries to recurse.
+	actionAllow
+	// actionBlock blocks unauthorized queries, they are answered with REFUSED.
+	actionBlock
+	// actionFilter returns empty sets for queries towards protected DNS zones.
+	actionFilter
+	// actionDrop drops unauthorized queries, no reply is sent.
+	actionDrop
+)
+
+// ServeDNS implements the plugin.Handler interface.
+func (a ACL) ServeDNS(ctx context.Context, w dns.ResponseWriter, r *dns.Msg) (int, error) {
+	state := request.Request{W: w, Req: r}
+
+Rules:
+	for _, rule := range a.Rules {
+		zone := plugin.Zones(rule.zones).Matches(state.Name())
+		if zone == "" {
+			continue
+		}
+
+		switch matchWithPolicies(rule.policies, state) {
+		case actionAllow:
+			break Rules
+
+		case actionBlock:
+			m := new(dns.Msg)
+			m.SetRcode(r, dns.RcodeRefused)
+			w.WriteMsg(m)
+			RequestBlockCount.WithLabelValues(metrics.WithServer(ctx), zone).Inc()
+			return dns.RcodeSuccess, nil
+
+		case actionFilter:
+			m := new(dns.Msg)
+			m.SetRcode(r, dns.RcodeSuccess)
+			w.WriteMsg(m)
+			RequestFilterCount.WithLabelValues(metrics.WithServer(ctx), zone).Inc()
+			return dns.RcodeSuccess, nil
+
+		case actionDrop:
+			// Returning a "written" rcode makes sure the server doesn't send a reply either.
+			RequestDropCount.WithLabelValues(metrics.WithServer(ctx), zone).Inc()
+			return dns.RcodeSuccess, nil
+		}
+	}
+
+	RequestAllowCount.WithLabelValues(metrics.WithServer(ctx)).Inc()
+	return plugin.NextOrFailure(a.Name(), a.Next, ctx, w, r)
+}
+
+// matchWithPolicies matches the query against the policies and returns the action of the
+// first policy that matches. If no policy matches actionNone is returned.
+func matchWithPolicies(policies []policy, state request.Request) action {
+	ip := net.ParseIP(state.IP())
+	qtype := state.QType()
+
+	for _, p := range policies {
+		if _, ok := p.qtypes[qtype]; !ok {
+			if _, all := p.qtypes[dns.TypeNone]; !all {
+				continue
+			}
+		}
+		if !p.contains(ip) {
+			continue
+		}
+		return p.action
+	}
+	return actionNone
+}
+
+// contains returns true if ip is in one of the networks of the policy.
+func (p policy) contains(ip net.IP) bool {
+	if ip == nil {
+		return false
+	}
+	for _, n := range p.nets {
+		if n.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// Name implements the plugin.Handler interface.
+func (a ACL) Name() string { return "acl" }
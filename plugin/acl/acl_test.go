@@ -0,0 +1,115 @@
+package acl
+
+import (
+	"context"
+	"testing"
+
+	"github.com/coredns/coredns/plugin"
+	"github.com/coredns/coredns/plugin/pkg/dnstest"
+	"github.com/coredns/coredns/plugin/test"
+
+	"github.com/mholt/caddy"
+	"github.com/miekg/dns"
+)
+
+// next is a handler that answers every query with NOERROR.
+var next = plugin.HandlerFunc(func(ctx context.Context, w dns.ResponseWriter, r *dns.Msg) (int, error) {
+	m := new(dns.Msg)
+	m.SetReply(r)
+	m.Answer = []dns.RR{test.A(r.Question[0].Name + " 3600 IN A 127.0.0.53")}
+	w.WriteMsg(m)
+	return dns.RcodeSuccess, nil
+})
+
+func TestServeDNS(t *testing.T) {
+	tests := []struct {
+		config   string
+		qname    string
+		qtype    uint16
+		ipv6     bool
+		written  bool // false when the query is dropped
+		rcode    int
+		nAnswers int
+	}{
+		// test.ResponseWriter uses 10.240.0.1 as the client address.
+		{`acl example.org {
+			block net 10.240.0.0/16
+		}`, "www.example.org.", dns.TypeA, false, true, dns.RcodeRefused, 0},
+		{`acl example.org {
+			block net 10.240.0.0/16
+		}`, "www.example.com.", dns.TypeA, false, true, dns.RcodeSuccess, 1},
+		{`acl example.org {
+			block net 192.168.0.0/16
+		}`, "www.example.org.", dns.TypeA, false, true, dns.RcodeSuccess, 1},
+		{`acl example.org {
+			block type AAAA
+		}`, "www.example.org.", dns.TypeA, false, true, dns.RcodeSuccess, 1},
+		{`acl example.org {
+			allow net 10.240.0.1
+			block
+		}`, "www.example.org.", dns.TypeA, false, true, dns.RcodeSuccess, 1},
+		{`acl example.org {
+			allow net 10.240.0.2
+			block
+		}`, "www.example.org.", dns.TypeA, false, true, dns.RcodeRefused, 0},
+		{`acl example.org {
+			filter type A net *
+		}`, "www.example.org.", dns.TypeA, false, true, dns.RcodeSuccess, 0},
+		{`acl example.org {
+			drop net 10.0.0.0/8
+		}`, "www.example.org.", dns.TypeA, false, false, 0, 0},
+		{`acl {
+			drop net fe80::/64
+		}`, "www.example.org.", dns.TypeA, true, false, 0, 0},
+		{`acl {
+			drop net fe80::/64
+		}`, "www.example.org.", dns.TypeA, false, true, dns.RcodeSuccess, 1},
+		// First rule that matches wins.
+		{`acl example.org {
+			allow type MX
+		}
+		acl example.org {
+			block
+		}`, "www.example.org.", dns.TypeMX, false, true, dns.RcodeSuccess, 1},
+	}
+
+	for i, tc := range tests {
+		c := caddy.NewTestController("dns", tc.config)
+		c.ServerBlockKeys = []string{"example.org."}
+		a, err := parseACL(c)
+		if err != nil {
+			t.Fatalf("Test %d: failed to parse config: %s", i, err)
+		}
+		a.Next = next
+
+		m := new(dns.Msg)
+		m.SetQuestion(tc.qname, tc.qtype)
+
+		var w dns.ResponseWriter = &test.ResponseWriter{}
+		if tc.ipv6 {
+			w = &test.ResponseWriter6{}
+		}
+		rec := dnstest.NewRecorder(w)
+		if _, err := a.ServeDNS(context.TODO(), rec, m); err != nil {
+			t.Errorf("Test %d: expected no error, got %s", i, err)
+			continue
+		}
+
+		if !tc.written {
+			if rec.Msg != nil {
+				t.Errorf("Test %d: expected query to be dropped, got reply", i)
+			}
+			continue
+		}
+		if rec.Msg == nil {
+			t.Errorf("Test %d: expected a reply, got none", i)
+			continue
+		}
+		if rec.Msg.Rcode != tc.rcode {
+			t.Errorf("Test %d: expected rcode %d, got %d", i, tc.rcode, rec.Msg.Rcode)
+		}
+		if len(rec.Msg.Answer) != tc.nAnswers {
+			t.Errorf("Test %d: expected %d answers, got %d", i, tc.nAnswers, len(rec.Msg.Answer))
+		}
+	}
+}
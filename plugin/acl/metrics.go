@@ -0,0 +1,38 @@
+package acl
+
+import (
+	"github.com/coredns/coredns/plugin"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	// RequestBlockCount is the number of DNS requests being blocked.
+	RequestBlockCount = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: plugin.Namespace,
+		Subsystem: "acl",
+		Name:      "blocked_requests_total",
+		Help:      "Counter of DNS requests being blocked.",
+	}, []string{"server", "zone"})
+	// RequestFilterCount is the number of DNS requests being filtered.
+	RequestFilterCount = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: plugin.Namespace,
+		Subsystem: "acl",
+		Name:      "filtered_requests_total",
+		Help:      "Counter of DNS requests being filtered.",
+	}, []string{"server", "zone"})
+	// RequestDropCount is the number of DNS requests being dropped.
+	RequestDropCount = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: plugin.Namespace,
+		Subsystem: "acl",
+		Name:      "dropped_requests_total",
+		Help:      "Counter of DNS requests being dropped.",
+	}, []string{"server", "zone"})
+	// RequestAllowCount is the number of DNS requests being allowed.
+	RequestAllowCount = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: plugin.Namespace,
+		Subsystem: "acl",
+		Name:      "allowed_requests_total",
+		Help:      "Counter of DNS requests being allowed.",
+	}, []string{"server"})
+)
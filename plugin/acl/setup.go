@@ -0,0 +1,136 @@
+package acl
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/coredns/coredns/core/dnsserver"
+	"github.com/coredns/coredns/plugin"
+	"github.com/coredns/coredns/plugin/metrics"
+	"github.com/coredns/coredns/plugin/pkg/parse"
+
+	"github.com/mholt/caddy"
+	"github.com/miekg/dns"
+)
+
+func init() {
+	caddy.RegisterPlugin("acl", caddy.Plugin{
+		ServerType: "dns",
+		Action:     setup,
+	})
+}
+
+func setup(c *caddy.Controller) error {
+	a, err := parseACL(c)
+	if err != nil {
+		return plugin.Error("acl", err)
+	}
+
+	dnsserver.GetConfig(c).AddPlugin(func(next plugin.Handler) plugin.Handler {
+		a.Next = next
+		return a
+	})
+
+	c.OnStartup(func() error {
+		metrics.MustRegister(c, RequestBlockCount, RequestFilterCount, RequestDropCount, RequestAllowCount)
+		return nil
+	})
+	return nil
+}
+
+func parseACL(c *caddy.Controller) (ACL, error) {
+	a := ACL{}
+	for c.Next() {
+		r := rule{}
+		r.zones = c.RemainingArgs()
+		if len(r.zones) == 0 {
+			r.zones = make([]string, len(c.ServerBlockKeys))
+			copy(r.zones, c.ServerBlockKeys)
+		}
+		for i := range r.zones {
+			r.zones[i] = plugin.Host(r.zones[i]).Normalize()
+		}
+
+		for c.NextBlock() {
+			p := policy{}
+
+			switch strings.ToLower(c.Val()) {
+			case "allow":
+				p.action = actionAllow
+			case "block":
+				p.action = actionBlock
+			case "filter":
+				p.action = actionFilter
+			case "drop":
+				p.action = actionDrop
+			default:
+				return a, c.Errf("unexpected token %q; expect 'allow', 'block', 'filter' or 'drop'", c.Val())
+			}
+
+			p.qtypes = make(map[uint16]struct{})
+
+			hasTypeSection, hasNetSection := false, false
+			remainingTokens := c.RemainingArgs()
+			for len(remainingTokens) > 0 {
+				section := strings.ToLower(remainingTokens[0])
+				i := 1
+				var tokens []string
+				for ; i < len(remainingTokens); i++ {
+					if t := strings.ToLower(remainingTokens[i]); t == "type" || t == "net" {
+						break
+					}
+					tokens = append(tokens, remainingTokens[i])
+				}
+				remainingTokens = remainingTokens[i:]
+
+				switch section {
+				case "type":
+					hasTypeSection = true
+					if err := parseQtypes(p.qtypes, tokens); err != nil {
+						return a, c.Err(err.Error())
+					}
+				case "net":
+					hasNetSection = true
+					nets, err := parse.Nets(tokens...)
+					if err != nil {
+						return a, c.Err(err.Error())
+					}
+					p.nets = append(p.nets, nets...)
+				default:
+					return a, c.Errf("unexpected token %q; expect 'type' or 'net'", section)
+				}
+			}
+
+			// Omitting a section means it matches everything.
+			if !hasTypeSection {
+				p.qtypes[dns.TypeNone] = struct{}{}
+			}
+			if !hasNetSection {
+				p.nets, _ = parse.Nets("*")
+			}
+
+			r.policies = append(r.policies, p)
+		}
+		a.Rules = append(a.Rules, r)
+	}
+	return a, nil
+}
+
+// parseQtypes adds the query types in tokens to qtypes, "*" matches all types.
+func parseQtypes(qtypes map[uint16]struct{}, tokens []string) error {
+	if len(tokens) == 0 {
+		return fmt.Errorf("no query type given")
+	}
+	for _, t := range tokens {
+		if t == "*" {
+			qtypes[dns.TypeNone] = struct{}{}
+			continue
+		}
+		qtype, ok := dns.StringToType[strings.ToUpper(t)]
+		if !ok {
+			return fmt.Errorf("invalid query type %q", t)
+		}
+		qtypes[qtype] = struct{}{}
+	}
+	return nil
+}
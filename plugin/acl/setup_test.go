@@ -0,0 +1,60 @@
+package acl
+
+import (
+	"testing"
+
+	"github.com/mholt/caddy"
+)
+
+func TestSetup(t *testing.T) {
+	tests := []struct {
+		input     string
+		shouldErr bool
+	}{
+		{`acl {
+			block
+		}`, false},
+		{`acl example.org {
+			block type A net 192.168.0.0/16
+		}`, false},
+		{`acl example.org {
+			allow net 192.168.1.0/24
+			block net 192.168.0.0/16
+			filter type AAAA MX
+			drop net 10.0.0.1 2001:db8::/32
+		}`, false},
+		{`acl example.org {
+			block type * net *
+		}`, false},
+		{`acl example.org {
+			block net 192.168.0.0/16 type ANY
+		}`, false},
+		// errors
+		{`acl example.org {
+			deny type A
+		}`, true},
+		{`acl example.org {
+			block type ABC
+		}`, true},
+		{`acl example.org {
+			block net 192.168.0.0/36
+		}`, true},
+		{`acl example.org {
+			block net
+		}`, true},
+		{`acl example.org {
+			block source 10.0.0.1
+		}`, true},
+	}
+
+	for i, test := range tests {
+		c := caddy.NewTestController("dns", test.input)
+		err := setup(c)
+		if test.shouldErr && err == nil {
+			t.Errorf("Test %d: expected error but found none for input %s", i, test.input)
+		}
+		if !test.shouldErr && err != nil {
+			t.Errorf("Test %d: expected no error but found one for input %s, got: %v", i, test.input, err)
+		}
+	}
+}
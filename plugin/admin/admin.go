@@ -0,0 +1,102 @@
+// Package admin implements an authenticated HTTP API to inspect and manage the running servers.
+package admin
+
+import (
+	"crypto/subtle"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/coredns/coredns/core/dnsserver"
+	clog "github.com/coredns/coredns/plugin/pkg/log"
+	"github.com/coredns/coredns/plugin/pkg/uniq"
+)
+
+var (
+	log      = clog.NewWithPlugin("admin")
+	uniqAddr = uniq.New()
+)
+
+type admin struct {
+	Addr  string
+	token string
+
+	// The configs of all server blocks, set on startup.
+	configs []*dnsserver.Config
+
+	sync.Mutex
+	ln   net.Listener
+	done bool
+	mux  *http.ServeMux
+}
+
+// newAdmin returns a new initialized admin.
+func newAdmin(addr, token string) *admin {
+	return &admin{Addr: addr, token: token}
+}
+
+func (a *admin) onStartup() error {
+	ln, err := net.Listen("tcp", a.Addr)
+	if err != nil {
+		log.Errorf("Failed to start admin handler: %s", err)
+		return err
+	}
+
+	a.Lock()
+	a.ln = ln
+	a.mux = http.NewServeMux()
+	a.done = true
+	a.Unlock()
+
+	a.mux.HandleFunc("/servers", a.auth(a.servers, http.MethodGet))
+	a.mux.HandleFunc("/forward", a.auth(a.forward, http.MethodGet))
+	a.mux.HandleFunc("/cache/purge", a.auth(a.cachePurge, http.MethodPost))
+	a.mux.HandleFunc("/zone/reload", a.auth(a.zoneReload, http.MethodPost))
+	a.mux.HandleFunc("/zone/transfer", a.auth(a.zoneTransfer, http.MethodPost))
+	a.mux.HandleFunc("/zone/dump", a.auth(a.zoneDump, http.MethodGet))
+	a.mux.HandleFunc("/log/level", a.auth(a.logLevel, http.MethodGet, http.MethodPost, http.MethodDelete))
+
+	go func() { http.Serve(a.ln, a.mux) }()
+
+	return nil
+}
+
+func (a *admin) onRestart() error {
+	uniqAddr.Unset(a.Addr)
+	return a.onFinalShutdown()
+}
+
+func (a *admin) onFinalShutdown() error {
+	a.Lock()
+	defer a.Unlock()
+	if !a.done {
+		return nil
+	}
+
+	a.ln.Close()
+	a.done = false
+	return nil
+}
+
+// auth returns a handler that calls h when the request uses one of methods and carries the bearer token.
+func (a *admin) auth(h http.HandlerFunc, methods ...string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if subtle.ConstantTimeCompare([]byte(token), []byte(a.token)) != 1 {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="coredns"`)
+			http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+			return
+		}
+		for _, m := range methods {
+			if r.Method == m {
+				h(w, r)
+				return
+			}
+		}
+		w.Header().Set("Allow", strings.Join(methods, ", "))
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+	}
+}
+
+const defAddr = "localhost:8054"
@@ -0,0 +1,105 @@
+package admin
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/coredns/coredns/core/dnsserver"
+	"github.com/coredns/coredns/plugin"
+	"github.com/coredns/coredns/plugin/cache"
+	"github.com/coredns/coredns/plugin/file"
+)
+
+func TestAdmin(t *testing.T) {
+	z, err := file.Parse(strings.NewReader(dbExampleOrg), "example.org.", "stdin", 0)
+	if err != nil {
+		t.Fatalf("Failed to parse zone: %s", err)
+	}
+	c := &dnsserver.Config{Zone: "example.org.", Transport: "dns", ListenHosts: []string{"127.0.0.1"}, Port: "1053"}
+	c.AddPlugin(func(next plugin.Handler) plugin.Handler {
+		ca := cache.New()
+		ca.Next = next
+		return ca
+	})
+	c.AddPlugin(func(next plugin.Handler) plugin.Handler {
+		return file.File{Next: next, Zones: file.Zones{Z: map[string]*file.Zone{"example.org.": z}, Names: []string{"example.org."}}}
+	})
+	if _, err := dnsserver.NewServer("127.0.0.1:1053", []*dnsserver.Config{c}); err != nil {
+		t.Fatalf("Failed to create server: %s", err)
+	}
+
+	a := newAdmin("localhost:0", "secret")
+	a.configs = []*dnsserver.Config{c}
+	if err := a.onStartup(); err != nil {
+		t.Fatalf("Unable to startup the admin server: %v", err)
+	}
+	defer a.onFinalShutdown()
+	address := fmt.Sprintf("http://%s", a.ln.Addr().String())
+
+	tests := []struct {
+		method       string
+		path         string
+		token        string
+		expectedCode int
+		expectedBody string
+	}{
+		{http.MethodGet, "/servers", "", http.StatusUnauthorized, ""},
+		{http.MethodGet, "/servers", "wrong", http.StatusUnauthorized, ""},
+		{http.MethodPost, "/servers", "secret", http.StatusMethodNotAllowed, ""},
+		{http.MethodGet, "/servers", "secret", http.StatusOK, `[{"zone":"example.org.","listen":["dns://127.0.0.1:1053"],"plugins":["cache","file"]}]`},
+		{http.MethodGet, "/forward", "secret", http.StatusOK, `[]`},
+		{http.MethodPost, "/cache/purge", "secret", http.StatusBadRequest, ""},
+		{http.MethodPost, "/cache/purge?name=example.org&subtree=yes", "secret", http.StatusBadRequest, ""},
+		{http.MethodPost, "/cache/purge?name=example.org&subtree=true", "secret", http.StatusOK, `{"purged":0}`},
+		{http.MethodGet, "/zone/dump?zone=example.net", "secret", http.StatusNotFound, ""},
+		{http.MethodGet, "/zone/dump?zone=Example.org", "secret", http.StatusOK, dumpExampleOrg},
+		{http.MethodPost, "/zone/transfer?zone=example.org", "secret", http.StatusOK, `{"transferred":0}`},
+		{http.MethodGet, "/log/level", "secret", http.StatusOK, `{"default":"info","plugins":{}}`},
+		{http.MethodPost, "/log/level?plugin=forward&level=loud", "secret", http.StatusBadRequest, ""},
+		{http.MethodPost, "/log/level?plugin=forward&level=debug", "secret", http.StatusOK, `{"default":"info","plugins":{"forward":"debug"}}`},
+		{http.MethodDelete, "/log/level", "secret", http.StatusBadRequest, ""},
+		{http.MethodDelete, "/log/level?plugin=forward", "secret", http.StatusOK, `{"default":"info","plugins":{}}`},
+		{http.MethodPut, "/log/level", "secret", http.StatusMethodNotAllowed, ""},
+	}
+
+	for i, tc := range tests {
+		req, _ := http.NewRequest(tc.method, address+tc.path, nil)
+		if tc.token != "" {
+			req.Header.Set("Authorization", "Bearer "+tc.token)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("Test %d: Unable to query %s: %v", i, tc.path, err)
+		}
+		body, _ := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+
+		if resp.StatusCode != tc.expectedCode {
+			t.Errorf("Test %d: Expected status code %d, got %d", i, tc.expectedCode, resp.StatusCode)
+			continue
+		}
+		if tc.expectedBody != "" && strings.TrimSpace(string(body)) != tc.expectedBody {
+			t.Errorf("Test %d: Expected body %q, got %q", i, tc.expectedBody, body)
+		}
+		if tc.expectedCode == http.StatusOK && strings.HasPrefix(tc.expectedBody, "[") {
+			var v []interface{}
+			if err := json.Unmarshal(body, &v); err != nil {
+				t.Errorf("Test %d: Expected JSON, got %q", i, body)
+			}
+		}
+	}
+}
+
+const dbExampleOrg = `$ORIGIN example.org.
+@	3600 IN	SOA sns.dns.icann.org. noc.dns.icann.org. 2017042745 7200 3600 1209600 3600
+	3600 IN NS a.iana-servers.net.
+www	3600 IN A 127.0.0.1
+`
+
+const dumpExampleOrg = `example.org.	3600	IN	SOA	sns.dns.icann.org. noc.dns.icann.org. 2017042745 7200 3600 1209600 3600
+example.org.	3600	IN	NS	a.iana-servers.net.
+www.example.org.	3600	IN	A	127.0.0.1`
@@ -0,0 +1,201 @@
+package admin
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/coredns/coredns/plugin/cache"
+	"github.com/coredns/coredns/plugin/file"
+	"github.com/coredns/coredns/plugin/forward"
+	"github.com/coredns/coredns/plugin/secondary"
+
+	"github.com/miekg/dns"
+)
+
+type server struct {
+	Zone    string   `json:"zone"`
+	View    string   `json:"view,omitempty"`
+	Listen  []string `json:"listen"`
+	Plugins []string `json:"plugins"`
+}
+
+// servers lists the zones, their listen addresses and plugin chains.
+func (a *admin) servers(w http.ResponseWriter, r *http.Request) {
+	servers := []server{}
+	for _, c := range a.configs {
+		s := server{Zone: c.Zone, View: c.ViewName, Listen: []string{}, Plugins: []string{}}
+		for _, h := range c.ListenHosts {
+			s.Listen = append(s.Listen, c.Transport+"://"+net.JoinHostPort(h, c.Port))
+		}
+		for _, h := range c.Chain() {
+			s.Plugins = append(s.Plugins, h.Name())
+		}
+		servers = append(servers, s)
+	}
+	writeJSON(w, servers)
+}
+
+type upstream struct {
+	Addr  string `json:"addr"`
+	Fails uint32 `json:"fails"`
+	Down  bool   `json:"down"`
+}
+
+type forwarder struct {
+	Zone      string     `json:"zone"`
+	From      string     `json:"from"`
+	Upstreams []upstream `json:"upstreams"`
+}
+
+// forward lists the upstreams of the forward plugins and their health.
+func (a *admin) forward(w http.ResponseWriter, r *http.Request) {
+	forwarders := []forwarder{}
+	for _, c := range a.configs {
+		for _, h := range c.Chain() {
+			f, ok := h.(*forward.Forward)
+			if !ok {
+				continue
+			}
+			fw := forwarder{Zone: c.Zone, From: f.From(), Upstreams: []upstream{}}
+			for _, p := range f.Proxies() {
+				fw.Upstreams = append(fw.Upstreams, upstream{Addr: p.Addr(), Fails: p.Fails(), Down: p.Down(f.MaxFails())})
+			}
+			forwarders = append(forwarders, fw)
+		}
+	}
+	writeJSON(w, forwarders)
+}
+
+// cachePurge removes the replies for a name, and optionally the names below it, from all caches.
+func (a *admin) cachePurge(w http.ResponseWriter, r *http.Request) {
+	name := r.URL.Query().Get("name")
+	if name == "" {
+		http.Error(w, "missing name", http.StatusBadRequest)
+		return
+	}
+	subtree := false
+	if s := r.URL.Query().Get("subtree"); s != "" {
+		var err error
+		if subtree, err = strconv.ParseBool(s); err != nil {
+			http.Error(w, fmt.Sprintf("invalid subtree: %s", s), http.StatusBadRequest)
+			return
+		}
+	}
+
+	purged := 0
+	seen := map[*cache.Cache]bool{}
+	for _, c := range a.configs {
+		for _, h := range c.Chain() {
+			ca, ok := h.(*cache.Cache)
+			if !ok || seen[ca] {
+				continue
+			}
+			seen[ca] = true
+			purged += ca.Purge(name, subtree)
+		}
+	}
+	log.Infof("Purged %d cached replies for %q", purged, name)
+	writeJSON(w, map[string]int{"purged": purged})
+}
+
+// zoneReload reloads the zone from disk in the file plugins that serve it.
+func (a *admin) zoneReload(w http.ResponseWriter, r *http.Request) {
+	zones, ok := a.zones(w, r)
+	if !ok {
+		return
+	}
+	reloaded := 0
+	for _, z := range zones {
+		if len(z.TransferFrom) > 0 {
+			continue
+		}
+		if err := z.ReloadNow(); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		reloaded++
+	}
+	writeJSON(w, map[string]int{"reloaded": reloaded})
+}
+
+// zoneTransfer transfers the zone from its primaries in the secondary plugins that serve it.
+func (a *admin) zoneTransfer(w http.ResponseWriter, r *http.Request) {
+	zones, ok := a.zones(w, r)
+	if !ok {
+		return
+	}
+	transferred := 0
+	for _, z := range zones {
+		if len(z.TransferFrom) == 0 {
+			continue
+		}
+		if err := z.TransferIn(); err != nil {
+			http.Error(w, err.Error(), http.StatusBadGateway)
+			return
+		}
+		transferred++
+	}
+	writeJSON(w, map[string]int{"transferred": transferred})
+}
+
+// zoneDump writes the records of the zone in the zone file format.
+func (a *admin) zoneDump(w http.ResponseWriter, r *http.Request) {
+	zones, ok := a.zones(w, r)
+	if !ok {
+		return
+	}
+	z := zones[0]
+	if z.SOASerialIfDefined() < 0 {
+		http.Error(w, "zone not loaded", http.StatusServiceUnavailable)
+		return
+	}
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	for _, rr := range z.All() {
+		fmt.Fprintln(w, rr.String())
+	}
+}
+
+// zones returns the zones named by the zone parameter of r that are served by the file and secondary
+// plugins. If there are none an error is written to w and false is returned.
+func (a *admin) zones(w http.ResponseWriter, r *http.Request) ([]*file.Zone, bool) {
+	origin := r.URL.Query().Get("zone")
+	if origin == "" {
+		http.Error(w, "missing zone", http.StatusBadRequest)
+		return nil, false
+	}
+	origin = dns.Fqdn(strings.ToLower(origin))
+
+	zones := []*file.Zone{}
+	seen := map[*file.Zone]bool{}
+	for _, c := range a.configs {
+		for _, h := range c.Chain() {
+			var f file.File
+			switch x := h.(type) {
+			case file.File:
+				f = x
+			case secondary.Secondary:
+				f = x.File
+			default:
+				continue
+			}
+			if z, ok := f.Zones.Z[origin]; ok && !seen[z] {
+				seen[z] = true
+				zones = append(zones, z)
+			}
+		}
+	}
+	if len(zones) == 0 {
+		http.Error(w, fmt.Sprintf("zone %s not found", origin), http.StatusNotFound)
+		return nil, false
+	}
+	return zones, true
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,45 @@
+package admin
+
+import (
+	"net/http"
+
+	clog "github.com/coredns/coredns/plugin/pkg/log"
+)
+
+type levels struct {
+	Default string            `json:"default"`
+	Plugins map[string]string `json:"plugins"`
+}
+
+// logLevel lists the log levels on GET. On POST it sets the level of the plugin parameter, or the
+// default level without it, to the level parameter. On DELETE it removes the level of the plugin.
+func (a *admin) logLevel(w http.ResponseWriter, r *http.Request) {
+	plugin := r.URL.Query().Get("plugin")
+	switch r.Method {
+	case http.MethodPost:
+		l, err := clog.ParseLevel(r.URL.Query().Get("level"))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		clog.SetLevel(plugin, l)
+		if plugin == "" {
+			log.Infof("Default log level set to %s", l)
+			break
+		}
+		log.Infof("Log level of %s set to %s", plugin, l)
+	case http.MethodDelete:
+		if plugin == "" {
+			http.Error(w, "missing plugin", http.StatusBadRequest)
+			return
+		}
+		clog.ResetLevel(plugin)
+	}
+
+	def, plugins := clog.Levels()
+	lv := levels{Default: def.String(), Plugins: map[string]string{}}
+	for p, l := range plugins {
+		lv.Plugins[p] = l.String()
+	}
+	writeJSON(w, lv)
+}
@@ -0,0 +1,91 @@
+package admin
+
+import (
+	"errors"
+	"net"
+
+	"github.com/coredns/coredns/core/dnsserver"
+	"github.com/coredns/coredns/plugin"
+
+	"github.com/mholt/caddy"
+)
+
+func init() {
+	caddy.RegisterPlugin("admin", caddy.Plugin{
+		ServerType: "dns",
+		Action:     setup,
+	})
+}
+
+func setup(c *caddy.Controller) error {
+	addr, token, err := parse(c)
+	if err != nil {
+		return plugin.Error("admin", err)
+	}
+
+	a := newAdmin(addr, token)
+	// Only one admin per address, the first one serves the plugins of all server blocks.
+	obj := uniqAddr.Set(addr, a.onStartup, a)
+	if a != obj.(*admin) {
+		return nil
+	}
+
+	c.OnStartup(func() error {
+		a.configs = dnsserver.GetConfigs(c)
+		return nil
+	})
+
+	c.OncePerServerBlock(func() error {
+		c.OnStartup(func() error {
+			return uniqAddr.ForEach()
+		})
+		return nil
+	})
+
+	c.OnRestart(a.onRestart)
+	c.OnFinalShutdown(a.onFinalShutdown)
+
+	// Don't do AddPlugin, as admin is not *really* a plugin just a separate webserver running.
+	return nil
+}
+
+func parse(c *caddy.Controller) (string, string, error) {
+	addr := defAddr
+	token := ""
+	i := 0
+	for c.Next() {
+		if i > 0 {
+			return "", "", plugin.ErrOnce
+		}
+		i++
+		args := c.RemainingArgs()
+
+		switch len(args) {
+		case 0:
+		case 1:
+			addr = args[0]
+			if _, _, e := net.SplitHostPort(addr); e != nil {
+				return "", "", e
+			}
+		default:
+			return "", "", c.ArgErr()
+		}
+
+		for c.NextBlock() {
+			switch c.Val() {
+			case "token":
+				args := c.RemainingArgs()
+				if len(args) != 1 {
+					return "", "", c.ArgErr()
+				}
+				token = args[0]
+			default:
+				return "", "", c.Errf("unknown property '%s'", c.Val())
+			}
+		}
+	}
+	if token == "" {
+		return "", "", errors.New("a token is required")
+	}
+	return addr, token, nil
+}
@@ -0,0 +1,60 @@
+package admin
+
+import (
+	"testing"
+
+	"github.com/mholt/caddy"
+)
+
+func TestSetupAdmin(t *testing.T) {
+	tests := []struct {
+		input         string
+		shouldErr     bool
+		expectedAddr  string
+		expectedToken string
+	}{
+		{`admin {
+			token secret
+		}`, false, defAddr, "secret"},
+		{`admin localhost:1234 {
+			token secret
+		}`, false, "localhost:1234", "secret"},
+		{`admin`, true, "", ""},
+		{`admin localhost:1234`, true, "", ""},
+		{`admin bla {
+			token secret
+		}`, true, "", ""},
+		{`admin localhost:1234 b {
+			token secret
+		}`, true, "", ""},
+		{`admin {
+			token
+		}`, true, "", ""},
+		{`admin {
+			token secret
+			lameduck 4s
+		}`, true, "", ""},
+	}
+
+	for i, test := range tests {
+		addr, token, err := parse(caddy.NewTestController("dns", test.input))
+
+		if test.shouldErr && err == nil {
+			t.Errorf("Test %d: Expected error but found none for input %s", i, test.input)
+		}
+
+		if err != nil {
+			if !test.shouldErr {
+				t.Errorf("Test %d: Expected no error but found one for input %s. Error was: %v", i, test.input, err)
+			}
+			continue
+		}
+
+		if addr != test.expectedAddr {
+			t.Errorf("Test %d: Expected address %s, got %s", i, test.expectedAddr, addr)
+		}
+		if token != test.expectedToken {
+			t.Errorf("Test %d: Expected token %s, got %s", i, test.expectedToken, token)
+		}
+	}
+}
@@ -0,0 +1,23 @@
+package auto
+
+import (
+	"github.com/coredns/coredns/plugin"
+	"github.com/coredns/coredns/plugin/pkg/watch"
+)
+
+// SetWatchChan implements watch.Watchable.
+func (a Auto) SetWatchChan(c watch.Chan) { a.Zones.watched.SetWatchChan(c) }
+
+// Watch implements watch.Watchable. Names in the origins are watched, even when their zone has not
+// been loaded (yet).
+func (a Auto) Watch(qname string) error {
+	if plugin.Zones(a.Zones.Origins()).Matches(qname) == "" {
+		return nil
+	}
+	return a.Zones.watched.Watch(qname)
+}
+
+// StopWatching implements watch.Watchable.
+func (a Auto) StopWatching(qname string) { a.Zones.watched.StopWatching(qname) }
+
+var _ watch.Watchable = Auto{}
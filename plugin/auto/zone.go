@@ -5,6 +5,7 @@ import (
 	"sync"
 
 	"github.com/coredns/coredns/plugin/file"
+	"github.com/coredns/coredns/plugin/pkg/watch"
 )
 
 // Zones maps zone names to a *Zone. This keep track of what we zones we have loaded at
@@ -15,6 +16,8 @@ type Zones struct {
 
 	origins []string // Any origins from the server block.
 
+	watched watch.Names // Watched names, shared by all zones.
+
 	sync.RWMutex
 }
 
@@ -49,11 +52,14 @@ func (z *Zones) Add(zo *file.Zone, name string) {
 		z.Z = make(map[string]*file.Zone)
 	}
 
+	zo.Watched = &z.watched
 	z.Z[name] = zo
 	z.names = append(z.names, name)
 	zo.Reload()
 
 	z.Unlock()
+
+	z.watched.Changed(name)
 }
 
 // Remove removes the zone named name from z. It also stop the the zone's reload goroutine.
@@ -73,4 +79,6 @@ func (z *Zones) Remove(name string) {
 	}
 
 	z.Unlock()
+
+	z.watched.Changed(name)
 }
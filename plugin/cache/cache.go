@@ -2,6 +2,7 @@
 package cache
 
 import (
+	"context"
 	"hash/fnv"
 	"net"
 	"time"
@@ -9,6 +10,7 @@ import (
 	"github.com/coredns/coredns/plugin"
 	"github.com/coredns/coredns/plugin/pkg/cache"
 	"github.com/coredns/coredns/plugin/pkg/dnsutil"
+	"github.com/coredns/coredns/plugin/pkg/edns"
 	"github.com/coredns/coredns/plugin/pkg/response"
 	"github.com/coredns/coredns/request"
 
@@ -36,6 +38,9 @@ type Cache struct {
 	duration   time.Duration
 	percentage int
 
+	// Scope prefix lengths of the stored client subnet replies.
+	subnets subnets
+
 	// Testing.
 	now func() time.Time
 }
@@ -62,8 +67,8 @@ func New() *Cache {
 
 // key returns key under which we store the item, -1 will be returned if we don't store the message.
 // Currently we do not cache Truncated, errors zone transfers or dynamic update messages.
-// qname holds the already lowercased qname.
-func key(qname string, m *dns.Msg, t response.Type, do bool) (bool, uint64) {
+// qname holds the already lowercased qname, e the client subnet option of the reply, if any.
+func key(qname string, m *dns.Msg, t response.Type, do bool, e *dns.EDNS0_SUBNET) (bool, uint64) {
 	// We don't store truncated responses.
 	if m.Truncated {
 		return false, 0
@@ -73,6 +78,12 @@ func key(qname string, m *dns.Msg, t response.Type, do bool) (bool, uint64) {
 		return false, 0
 	}
 
+	if e != nil {
+		if scope := subnetScope(e); scope > 0 {
+			return true, subnetHash(qname, m.Question[0].Qtype, do, e.Family, scope, e.Address)
+		}
+	}
+
 	return true, hash(qname, m.Question[0].Qtype, do)
 }
 
@@ -110,7 +121,8 @@ type ResponseWriter struct {
 	dns.ResponseWriter
 	*Cache
 	state  request.Request
-	server string // Server handling the request.
+	server string          // Server handling the request.
+	ctx    context.Context // carries the client subnet option removed from the reply, see edns.NewSubnetContext
 
 	prefetch   bool // When true write nothing back to the client.
 	remoteAddr net.Addr
@@ -119,7 +131,7 @@ type ResponseWriter struct {
 // newPrefetchResponseWriter returns a Cache ResponseWriter to be used in
 // prefetch requests. It ensures RemoteAddr() can be called even after the
 // original connetion has already been closed.
-func newPrefetchResponseWriter(server string, state request.Request, c *Cache) *ResponseWriter {
+func newPrefetchResponseWriter(ctx context.Context, server string, state request.Request, c *Cache) *ResponseWriter {
 	// Resolve the address now, the connection might be already closed when the
 	// actual prefetch request is made.
 	addr := state.W.RemoteAddr()
@@ -135,6 +147,7 @@ func newPrefetchResponseWriter(server string, state request.Request, c *Cache) *
 		Cache:          c,
 		state:          state,
 		server:         server,
+		ctx:            ctx,
 		prefetch:       true,
 		remoteAddr:     addr,
 	}
@@ -156,8 +169,14 @@ func (w *ResponseWriter) WriteMsg(res *dns.Msg) error {
 		do = opt.Do()
 	}
 
+	// The client subnet option may have been removed from the reply by a plugin further down the chain.
+	e := edns.Subnet(res)
+	if e == nil && w.ctx != nil {
+		e = edns.ReplySubnet(w.ctx)
+	}
+
 	// key returns empty string for anything we don't want to cache.
-	hasKey, key := key(w.state.Name(), res, mt, do)
+	hasKey, key := key(w.state.Name(), res, mt, do, e)
 
 	msgTTL := dnsutil.MinimalTTL(res, mt)
 	var duration time.Duration
@@ -169,7 +188,12 @@ func (w *ResponseWriter) WriteMsg(res *dns.Msg) error {
 
 	if hasKey && duration > 0 {
 		if w.state.Match(res) {
-			w.set(res, key, mt, duration)
+			if e != nil {
+				if scope := subnetScope(e); scope > 0 {
+					w.subnets.add(e.Family, scope)
+				}
+			}
+			w.set(res, key, mt, duration, e)
 			cacheSize.WithLabelValues(w.server, Success).Set(float64(w.pcache.Len()))
 			cacheSize.WithLabelValues(w.server, Denial).Set(float64(w.ncache.Len()))
 		} else {
@@ -182,6 +206,11 @@ func (w *ResponseWriter) WriteMsg(res *dns.Msg) error {
 		return nil
 	}
 
+	// The client subnet option may have been added by a plugin further down the chain.
+	if edns.Subnet(w.state.Req) == nil {
+		edns.RemoveSubnet(res)
+	}
+
 	// Apply capped TTL to this reply to avoid jarring TTL experience 1799 -> 8 (e.g.)
 	ttl := uint32(duration.Seconds())
 	for i := range res.Answer {
@@ -198,16 +227,18 @@ func (w *ResponseWriter) WriteMsg(res *dns.Msg) error {
 	return w.ResponseWriter.WriteMsg(res)
 }
 
-func (w *ResponseWriter) set(m *dns.Msg, key uint64, mt response.Type, duration time.Duration) {
+func (w *ResponseWriter) set(m *dns.Msg, key uint64, mt response.Type, duration time.Duration, e *dns.EDNS0_SUBNET) {
 	// duration is expected > 0
 	// and key is valid
 	switch mt {
 	case response.NoError, response.Delegation:
 		i := newItem(m, w.now(), duration)
+		i.setScope(e)
 		w.pcache.Add(key, i)
 
 	case response.NameError, response.NoData:
 		i := newItem(m, w.now(), duration)
+		i.setScope(e)
 		w.ncache.Add(key, i)
 
 	case response.OtherError:
@@ -2,10 +2,14 @@ package cache
 
 import (
 	"context"
+	"encoding/binary"
+	"reflect"
 	"testing"
 	"time"
 
 	"github.com/coredns/coredns/plugin"
+	"github.com/coredns/coredns/plugin/pkg/dnstest"
+	"github.com/coredns/coredns/plugin/pkg/ede"
 	"github.com/coredns/coredns/plugin/pkg/response"
 	"github.com/coredns/coredns/plugin/test"
 	"github.com/coredns/coredns/request"
@@ -167,10 +171,10 @@ func TestCache(t *testing.T) {
 		state := request.Request{W: nil, Req: m}
 
 		mt, _ := response.Typify(m, utc)
-		valid, k := key(state.Name(), m, mt, state.Do())
+		valid, k := key(state.Name(), m, mt, state.Do(), nil)
 
 		if valid {
-			crr.set(m, k, mt, c.pttl)
+			crr.set(m, k, mt, c.pttl, nil)
 		}
 
 		i, _ := c.get(time.Now().UTC(), state, "dns://:53")
@@ -222,6 +226,65 @@ func TestCacheZeroTTL(t *testing.T) {
 	}
 }
 
+func TestCacheEDE(t *testing.T) {
+	c := New()
+	c.Next = plugin.HandlerFunc(func(ctx context.Context, w dns.ResponseWriter, r *dns.Msg) (int, error) {
+		m := new(dns.Msg)
+		m.SetRcode(r, dns.RcodeNameError)
+		m.Ns = []dns.RR{test.SOA("example.org. 300 IN SOA ns.example.org. admin.example.org. 1 3600 900 604800 300")}
+		ede.Set(ctx, ede.Blocked, "")
+		ede.Add(ctx, r, m)
+		w.WriteMsg(m)
+		return dns.RcodeNameError, nil
+	})
+
+	codes := func(m *dns.Msg) []uint16 {
+		var c []uint16
+		for _, o := range m.IsEdns0().Option {
+			if o.Option() == ede.OptionCode {
+				c = append(c, binary.BigEndian.Uint16(o.(*dns.EDNS0_LOCAL).Data))
+			}
+		}
+		return c
+	}
+
+	tests := []struct {
+		expected []uint16
+	}{
+		{[]uint16{ede.Blocked}}, // from the handler
+		{[]uint16{ede.Blocked}}, // from the cache, an NXDOMAIN is not a cached error
+	}
+	for i, tc := range tests {
+		req := new(dns.Msg)
+		req.SetQuestion("blocked.example.org.", dns.TypeA)
+		req.SetEdns0(4096, false)
+		rec := dnstest.NewRecorder(&test.ResponseWriter{})
+		c.ServeDNS(ede.NewContext(context.TODO()), rec, req)
+
+		if got := codes(rec.Msg); !reflect.DeepEqual(got, tc.expected) {
+			t.Errorf("Test %d: expected extended errors %v, got %v", i, tc.expected, got)
+		}
+	}
+}
+
+func TestCacheEDEServerFailure(t *testing.T) {
+	m := new(dns.Msg)
+	m.SetQuestion("example.org.", dns.TypeA)
+	m.Rcode = dns.RcodeServerFailure
+	i := newItem(m, time.Now(), 5*time.Second)
+
+	req := new(dns.Msg)
+	req.SetQuestion("example.org.", dns.TypeA)
+	req.SetEdns0(4096, false)
+	o := i.toMsg(req, time.Now()).IsEdns0()
+	if o == nil || len(o.Option) != 1 {
+		t.Fatalf("Expected one extended error, got %v", o)
+	}
+	if code := binary.BigEndian.Uint16(o.Option[0].(*dns.EDNS0_LOCAL).Data); code != ede.CachedError {
+		t.Errorf("Expected extended error %d, got %d", ede.CachedError, code)
+	}
+}
+
 func BenchmarkCacheResponse(b *testing.B) {
 	c := New()
 	c.prefetch = 1
@@ -0,0 +1,110 @@
+package cache
+
+import (
+	"hash/fnv"
+	"net"
+	"sync/atomic"
+
+	"github.com/coredns/coredns/plugin/pkg/edns"
+	"github.com/coredns/coredns/request"
+
+	"github.com/miekg/dns"
+)
+
+// Replies with an EDNS0 Client Subnet option (RFC 7871) that has a non-zero scope prefix length are
+// only valid for clients in that subnet. They are stored under a key that includes the subnet. As
+// the scope is only known once the reply is in, a lookup tries every scope prefix length that has
+// been stored so far, longest first, and then the key without a subnet.
+
+// subnets keeps track of the scope prefix lengths that are stored, per address family.
+type subnets struct {
+	any    uint32         // set when any subnet has been stored
+	scopes [2][129]uint32 // IPv4 and IPv6
+}
+
+func (s *subnets) add(family uint16, scope uint8) {
+	atomic.StoreUint32(&s.scopes[family-1][scope], 1)
+	atomic.StoreUint32(&s.any, 1)
+}
+
+func (s *subnets) has(family uint16, scope uint8) bool {
+	return atomic.LoadUint32(&s.scopes[family-1][scope]) == 1
+}
+
+// subnetScope returns the scope prefix length of e, capped to its source prefix length. It returns
+// 0 for options that can't be cached per subnet.
+func subnetScope(e *dns.EDNS0_SUBNET) uint8 {
+	if e.Family != 1 && e.Family != 2 {
+		return 0
+	}
+	scope := e.SourceScope
+	if scope > e.SourceNetmask {
+		scope = e.SourceNetmask
+	}
+	if e.Family == 1 && scope > 32 {
+		scope = 32
+	}
+	return scope
+}
+
+// subnetHash returns the key of a reply for qname and qtype that is valid for the subnet of ip
+// with a prefix length of scope.
+func subnetHash(qname string, qtype uint16, do bool, family uint16, scope uint8, ip net.IP) uint64 {
+	bits := 32
+	if family == 2 {
+		bits = 128
+	} else {
+		ip = ip.To4()
+	}
+	h := fnv.New64()
+
+	if do {
+		h.Write(one)
+	} else {
+		h.Write(zero)
+	}
+
+	h.Write([]byte{byte(qtype >> 8)})
+	h.Write([]byte{byte(qtype)})
+	h.Write([]byte{byte(family), scope})
+	h.Write(ip.Mask(net.CIDRMask(int(scope), bits)))
+	h.Write([]byte(qname))
+	return h.Sum64()
+}
+
+// keys returns the keys a reply for state may be stored under, most specific first.
+func (c *Cache) keys(state request.Request) []uint64 {
+	k := hash(state.Name(), state.QType(), state.Do())
+	if atomic.LoadUint32(&c.subnets.any) == 0 {
+		return []uint64{k}
+	}
+
+	var (
+		family uint16
+		ip     net.IP
+		max    uint8
+	)
+	if e := edns.Subnet(state.Req); e != nil {
+		family, ip, max = e.Family, e.Address, e.SourceNetmask
+	} else {
+		ip = net.ParseIP(state.IP())
+		family, max = 2, 128
+		if ip.To4() != nil {
+			family, max = 1, 32
+		}
+	}
+	if ip == nil || (family != 1 && family != 2) {
+		return []uint64{k}
+	}
+	if family == 1 && max > 32 {
+		max = 32
+	}
+
+	keys := []uint64{}
+	for scope := max; scope > 0; scope-- {
+		if c.subnets.has(family, scope) {
+			keys = append(keys, subnetHash(state.Name(), state.QType(), state.Do(), family, scope, ip))
+		}
+	}
+	return append(keys, k)
+}
@@ -0,0 +1,84 @@
+package cache
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	"github.com/coredns/coredns/plugin"
+	"github.com/coredns/coredns/plugin/pkg/dnstest"
+	"github.com/coredns/coredns/plugin/pkg/edns"
+	"github.com/coredns/coredns/plugin/test"
+
+	"github.com/miekg/dns"
+)
+
+// subnetHandler replies with the address of the client's /24 and a client subnet option with a scope of 24.
+// Like forward, it removes the option from the reply when the query didn't have one, and records it in ctx.
+func subnetHandler(queries *int) plugin.Handler {
+	return plugin.HandlerFunc(func(ctx context.Context, w dns.ResponseWriter, r *dns.Msg) (int, error) {
+		*queries++
+		e := edns.Subnet(r)
+		client := e != nil
+		if !client {
+			e = edns.NewSubnet(net.ParseIP("10.240.0.1"), 24, 56) // test.ResponseWriter's address
+		}
+		m := new(dns.Msg)
+		m.SetReply(r)
+		m.Answer = []dns.RR{test.A("example.org. 300 IN A " + e.Address.Mask(net.CIDRMask(24, 32)).String())}
+		edns.EchoSubnet(m, e, 24, 512)
+		if !client {
+			edns.SetReplySubnet(ctx, edns.Subnet(m))
+			edns.RemoveOPT(m)
+		}
+		w.WriteMsg(m)
+		return dns.RcodeSuccess, nil
+	})
+}
+
+func TestCacheSubnet(t *testing.T) {
+	queries := 0
+	c := New()
+	c.Next = subnetHandler(&queries)
+
+	tests := []struct {
+		client  string // address in the client subnet option of the query, empty for none
+		answer  string
+		queries int // queries that reached the handler
+	}{
+		{"", "10.240.0.0", 1},
+		{"", "10.240.0.0", 1},
+		{"10.240.0.77", "10.240.0.0", 1},
+		{"192.0.2.1", "192.0.2.0", 2},
+		{"192.0.2.200", "192.0.2.0", 2},
+		{"10.240.1.1", "10.240.1.0", 3},
+	}
+
+	for i, tc := range tests {
+		req := new(dns.Msg)
+		req.SetQuestion("example.org.", dns.TypeA)
+		if tc.client != "" {
+			edns.SetSubnet(req, edns.NewSubnet(net.ParseIP(tc.client), 32, 128), 512)
+		}
+		rec := dnstest.NewRecorder(&test.ResponseWriter{})
+		c.ServeDNS(context.TODO(), rec, req)
+
+		if queries != tc.queries {
+			t.Errorf("Test %d: expected %d queries to reach the handler, got %d", i, tc.queries, queries)
+		}
+		if a := rec.Msg.Answer[0].(*dns.A).A.String(); a != tc.answer {
+			t.Errorf("Test %d: expected answer %s, got %s", i, tc.answer, a)
+		}
+
+		e := edns.Subnet(rec.Msg)
+		if tc.client == "" {
+			if e != nil {
+				t.Errorf("Test %d: expected no client subnet option in the reply, got %s", i, e)
+			}
+			continue
+		}
+		if e == nil || e.SourceScope != 24 || !e.Address.Equal(net.ParseIP(tc.client)) {
+			t.Errorf("Test %d: expected client subnet option %s/32/24 in the reply, got %v", i, tc.client, e)
+		}
+	}
+}
@@ -7,6 +7,7 @@ import (
 
 	"github.com/coredns/coredns/plugin"
 	"github.com/coredns/coredns/plugin/metrics"
+	"github.com/coredns/coredns/plugin/pkg/edns"
 	"github.com/coredns/coredns/request"
 
 	"github.com/miekg/dns"
@@ -29,6 +30,12 @@ func (c *Cache) ServeDNS(ctx context.Context, w dns.ResponseWriter, r *dns.Msg)
 	i, found := c.get(now, state, server)
 	if i != nil && found {
 		resp := i.toMsg(r, now)
+		if e := edns.Subnet(r); e != nil {
+			edns.EchoSubnet(resp, e, i.scope, uint16(state.Size()))
+			if state.Do() {
+				resp.IsEdns0().SetDo()
+			}
+		}
 
 		w.WriteMsg(resp)
 
@@ -38,7 +45,8 @@ func (c *Cache) ServeDNS(ctx context.Context, w dns.ResponseWriter, r *dns.Msg)
 
 			threshold := int(math.Ceil(float64(c.percentage) / 100 * float64(i.origTTL)))
 			if i.Freq.Hits() >= c.prefetch && ttl <= threshold {
-				cw := newPrefetchResponseWriter(server, state, c)
+				ctx := edns.NewSubnetContext(ctx)
+				cw := newPrefetchResponseWriter(ctx, server, state, c)
 				go func(w dns.ResponseWriter) {
 					cachePrefetches.WithLabelValues(server).Inc()
 					plugin.NextOrFailure(c.Name(), c.Next, ctx, w, r)
@@ -55,7 +63,8 @@ func (c *Cache) ServeDNS(ctx context.Context, w dns.ResponseWriter, r *dns.Msg)
 		return dns.RcodeSuccess, nil
 	}
 
-	crr := &ResponseWriter{ResponseWriter: w, Cache: c, state: state, server: server}
+	ctx = edns.NewSubnetContext(ctx)
+	crr := &ResponseWriter{ResponseWriter: w, Cache: c, state: state, server: server, ctx: ctx}
 	return plugin.NextOrFailure(c.Name(), c.Next, ctx, crr, r)
 }
 
@@ -63,28 +72,29 @@ func (c *Cache) ServeDNS(ctx context.Context, w dns.ResponseWriter, r *dns.Msg)
 func (c *Cache) Name() string { return "cache" }
 
 func (c *Cache) get(now time.Time, state request.Request, server string) (*item, bool) {
-	k := hash(state.Name(), state.QType(), state.Do())
-
-	if i, ok := c.ncache.Get(k); ok && i.(*item).ttl(now) > 0 {
-		cacheHits.WithLabelValues(server, Denial).Inc()
-		return i.(*item), true
-	}
+	for _, k := range c.keys(state) {
+		if i, ok := c.ncache.Get(k); ok && i.(*item).ttl(now) > 0 {
+			cacheHits.WithLabelValues(server, Denial).Inc()
+			return i.(*item), true
+		}
 
-	if i, ok := c.pcache.Get(k); ok && i.(*item).ttl(now) > 0 {
-		cacheHits.WithLabelValues(server, Success).Inc()
-		return i.(*item), true
+		if i, ok := c.pcache.Get(k); ok && i.(*item).ttl(now) > 0 {
+			cacheHits.WithLabelValues(server, Success).Inc()
+			return i.(*item), true
+		}
 	}
 	cacheMisses.WithLabelValues(server).Inc()
 	return nil, false
 }
 
 func (c *Cache) exists(state request.Request) *item {
-	k := hash(state.Name(), state.QType(), state.Do())
-	if i, ok := c.ncache.Get(k); ok {
-		return i.(*item)
-	}
-	if i, ok := c.pcache.Get(k); ok {
-		return i.(*item)
+	for _, k := range c.keys(state) {
+		if i, ok := c.ncache.Get(k); ok {
+			return i.(*item)
+		}
+		if i, ok := c.pcache.Get(k); ok {
+			return i.(*item)
+		}
 	}
 	return nil
 }
@@ -1,13 +1,17 @@
 package cache
 
 import (
+	"strings"
 	"time"
 
 	"github.com/coredns/coredns/plugin/cache/freq"
+	"github.com/coredns/coredns/plugin/pkg/ede"
+
 	"github.com/miekg/dns"
 )
 
 type item struct {
+	Name               string // lowercased qname
 	Rcode              int
 	Authoritative      bool
 	AuthenticatedData  bool
@@ -15,15 +19,20 @@ type item struct {
 	Answer             []dns.RR
 	Ns                 []dns.RR
 	Extra              []dns.RR
+	EDE                []dns.EDNS0 // extended DNS errors of the reply
 
 	origTTL uint32
 	stored  time.Time
+	scope   uint8 // scope prefix length of the client subnet option, 0 when the reply is valid for everyone
 
 	*freq.Freq
 }
 
 func newItem(m *dns.Msg, now time.Time, d time.Duration) *item {
 	i := new(item)
+	if len(m.Question) > 0 {
+		i.Name = strings.ToLower(m.Question[0].Name)
+	}
 	i.Rcode = m.Rcode
 	i.Authoritative = m.Authoritative
 	i.AuthenticatedData = m.AuthenticatedData
@@ -41,6 +50,14 @@ func newItem(m *dns.Msg, now time.Time, d time.Duration) *item {
 		j++
 	}
 	i.Extra = i.Extra[:j]
+	// Extended errors are in the OPT record, but tell the client why it got this reply; keep them.
+	if o := m.IsEdns0(); o != nil {
+		for _, e := range o.Option {
+			if e.Option() == ede.OptionCode {
+				i.EDE = append(i.EDE, e)
+			}
+		}
+	}
 
 	i.origTTL = uint32(d.Seconds())
 	i.stored = now.UTC()
@@ -50,6 +67,13 @@ func newItem(m *dns.Msg, now time.Time, d time.Duration) *item {
 	return i
 }
 
+// setScope sets the scope prefix length of i from e, the client subnet option of the reply.
+func (i *item) setScope(e *dns.EDNS0_SUBNET) {
+	if e != nil {
+		i.scope = subnetScope(e)
+	}
+}
+
 // toMsg turns i into a message, it tailors the reply to m.
 // The Authoritative bit is always set to 0, because the answer is from the cache.
 func (i *item) toMsg(m *dns.Msg, now time.Time) *dns.Msg {
@@ -79,6 +103,17 @@ func (i *item) toMsg(m *dns.Msg, now time.Time) *dns.Msg {
 		m1.Extra[j] = dns.Copy(r)
 		m1.Extra[j].Header().Ttl = ttl
 	}
+	// Return the extended errors of the reply; a cached SERVFAIL is a cached error, say so.
+	servfail := i.Rcode == dns.RcodeServerFailure
+	if o := m.IsEdns0(); o != nil && (len(i.EDE) > 0 || servfail) {
+		m1.SetEdns0(o.UDPSize(), o.Do())
+		o1 := m1.IsEdns0()
+		o1.Option = append(o1.Option, i.EDE...)
+		if servfail {
+			cached := &ede.Error{Code: ede.CachedError}
+			o1.Option = append(o1.Option, cached.Option())
+		}
+	}
 	return m1
 }
 
@@ -0,0 +1,21 @@
+package cache
+
+import (
+	"strings"
+
+	"github.com/miekg/dns"
+)
+
+// Purge removes the cached replies for name from the cache, when subtree is true the replies for the
+// names below name are removed as well. It returns the number of removed replies.
+func (c *Cache) Purge(name string, subtree bool) int {
+	name = strings.ToLower(dns.Fqdn(name))
+	match := func(el interface{}) bool {
+		i := el.(*item)
+		if subtree {
+			return dns.IsSubDomain(name, i.Name)
+		}
+		return i.Name == name
+	}
+	return c.pcache.RemoveFunc(match) + c.ncache.RemoveFunc(match)
+}
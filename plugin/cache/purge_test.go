@@ -0,0 +1,31 @@
+package cache
+
+import (
+	"testing"
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+func TestPurge(t *testing.T) {
+	c := New()
+	for i, name := range []string{"example.org.", "www.example.org.", "example.net."} {
+		m := new(dns.Msg)
+		m.SetQuestion(name, dns.TypeA)
+		c.pcache.Add(uint64(i), newItem(m, time.Now(), time.Minute))
+	}
+	m := new(dns.Msg)
+	m.SetQuestion("a.example.org.", dns.TypeA)
+	m.Rcode = dns.RcodeNameError
+	c.ncache.Add(3, newItem(m, time.Now(), time.Minute))
+
+	if n := c.Purge("Example.org", false); n != 1 {
+		t.Errorf("Expected 1 reply purged, got %d", n)
+	}
+	if n := c.Purge("example.org.", true); n != 2 {
+		t.Errorf("Expected 2 replies purged, got %d", n)
+	}
+	if l := c.pcache.Len() + c.ncache.Len(); l != 1 {
+		t.Errorf("Expected 1 reply left in the cache, got %d", l)
+	}
+}
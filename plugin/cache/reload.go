@@ -0,0 +1,54 @@
+package cache
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// A reload restarts all plugins, but a cache whose configuration didn't change takes over the
+// storage of the running cache; the cached replies are kept. Caches are identified by the
+// fingerprint of their Config.
+
+var (
+	runningMu sync.Mutex
+	running   = make(map[string]*Cache)
+)
+
+// takeOver makes c use the storage of the running cache with fingerprint fp, if there is one.
+func (c *Cache) takeOver(fp string) {
+	runningMu.Lock()
+	defer runningMu.Unlock()
+	prev, ok := running[fp]
+	if !ok {
+		return
+	}
+	c.pcache, c.ncache = prev.pcache, prev.ncache
+	c.subnets.merge(&prev.subnets)
+}
+
+// run registers c as the running cache with fingerprint fp.
+func (c *Cache) run(fp string) {
+	runningMu.Lock()
+	running[fp] = c
+	runningMu.Unlock()
+}
+
+// stop unregisters c, unless a new cache with fingerprint fp has taken its place.
+func (c *Cache) stop(fp string) {
+	runningMu.Lock()
+	if running[fp] == c {
+		delete(running, fp)
+	}
+	runningMu.Unlock()
+}
+
+// merge adds the scope prefix lengths of s1 to s.
+func (s *subnets) merge(s1 *subnets) {
+	for f := range s1.scopes {
+		for scope := range s1.scopes[f] {
+			if atomic.LoadUint32(&s1.scopes[f][scope]) == 1 {
+				s.add(uint16(f+1), uint8(scope))
+			}
+		}
+	}
+}
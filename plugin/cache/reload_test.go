@@ -0,0 +1,34 @@
+package cache
+
+import "testing"
+
+func TestTakeOver(t *testing.T) {
+	prev := New()
+	prev.subnets.add(1, 24)
+	prev.run("fp")
+
+	ca := New()
+	ca.takeOver("other")
+	if ca.pcache == prev.pcache {
+		t.Fatalf("Expected storage not to be taken over for another fingerprint")
+	}
+
+	ca.takeOver("fp")
+	if ca.pcache != prev.pcache || ca.ncache != prev.ncache {
+		t.Fatalf("Expected storage of the running cache to be taken over")
+	}
+	if !ca.subnets.has(1, 24) {
+		t.Errorf("Expected the stored scopes to be taken over")
+	}
+
+	// The new cache starts before the old one is stopped.
+	ca.run("fp")
+	prev.stop("fp")
+	if running["fp"] != ca {
+		t.Errorf("Expected the new cache to be running")
+	}
+	ca.stop("fp")
+	if _, ok := running["fp"]; ok {
+		t.Errorf("Expected no running cache")
+	}
+}
@@ -28,7 +28,9 @@ func setup(c *caddy.Controller) error {
 	if err != nil {
 		return plugin.Error("cache", err)
 	}
-	dnsserver.GetConfig(c).AddPlugin(func(next plugin.Handler) plugin.Handler {
+	config := dnsserver.GetConfig(c)
+	ca.takeOver(config.Fingerprint)
+	config.AddPlugin(func(next plugin.Handler) plugin.Handler {
 		ca.Next = next
 		return ca
 	})
@@ -37,6 +39,12 @@ func setup(c *caddy.Controller) error {
 		metrics.MustRegister(c,
 			cacheSize, cacheHits, cacheMisses,
 			cachePrefetches, cacheDrops)
+		ca.run(config.Fingerprint)
+		return nil
+	})
+
+	c.OnShutdown(func() error {
+		ca.stop(config.Fingerprint)
 		return nil
 	})
 
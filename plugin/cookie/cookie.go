@@ -0,0 +1,114 @@
+// Package cookie implements DNS Cookies (RFC 7873). Server cookies use the interoperable format of
+// RFC 9018, so servers that share a secret accept each other's cookies.
+package cookie
+
+import (
+	"context"
+	"net"
+	"time"
+
+	"github.com/coredns/coredns/core/dnsserver"
+	"github.com/coredns/coredns/plugin"
+	"github.com/coredns/coredns/plugin/metrics"
+	"github.com/coredns/coredns/plugin/pkg/edns"
+	"github.com/coredns/coredns/request"
+
+	"github.com/miekg/dns"
+)
+
+// Cookie validates the cookies of queries and adds a fresh server cookie to the replies.
+type Cookie struct {
+	Next plugin.Handler
+
+	secrets *secrets
+	rotate  time.Duration // rotation interval of the secret, 0 when the secret is configured
+	require bool          // send UDP clients without a valid cookie to TCP
+
+	now func() time.Time
+}
+
+// New returns a new Cookie with a random secret.
+func New() *Cookie {
+	return &Cookie{secrets: newSecrets(), rotate: defaultRotate, now: time.Now}
+}
+
+// ServeDNS implements the plugin.Handler interface.
+func (c *Cookie) ServeDNS(ctx context.Context, w dns.ResponseWriter, r *dns.Msg) (int, error) {
+	state := request.Request{W: w, Req: r}
+	server := metrics.WithServer(ctx)
+	udp := state.Proto() == "udp"
+
+	client, sc, err := edns.Cookie(r)
+	if err != nil {
+		RequestCount.WithLabelValues(server, "malformed").Inc()
+		return dns.RcodeFormatError, nil
+	}
+
+	if client == nil {
+		RequestCount.WithLabelValues(server, "none").Inc()
+		if c.require && udp {
+			// A truncated reply makes the client retry over TCP, where its address can't be spoofed.
+			m := new(dns.Msg)
+			m.SetReply(r)
+			m.Truncated = true
+			w.WriteMsg(m)
+			return dns.RcodeSuccess, nil
+		}
+		return plugin.NextOrFailure(c.Name(), c.Next, ctx, w, r)
+	}
+
+	ip := net.ParseIP(state.IP())
+	now := c.now()
+	size := r.IsEdns0().UDPSize()
+	fresh := edns.ServerCookie(c.secrets.secret(), client, ip, now)
+
+	bad := false
+	switch {
+	case sc == nil:
+		RequestCount.WithLabelValues(server, "client").Inc()
+		bad = c.require && udp
+	case c.secrets.valid(client, sc, ip, now):
+		RequestCount.WithLabelValues(server, "valid").Inc()
+	default:
+		// RFC 7873, Section 5.2.3: over TCP the query is answered as if it only had a client cookie.
+		RequestCount.WithLabelValues(server, "invalid").Inc()
+		bad = udp
+	}
+
+	if bad {
+		// The client retries with the fresh server cookie.
+		m := new(dns.Msg)
+		m.SetRcode(r, dns.RcodeBadCookie)
+		edns.SetCookie(m, client, fresh, size)
+		edns.SetExtendedRcode(m)
+		w.WriteMsg(m)
+		return dns.RcodeBadCookie, nil
+	}
+
+	cw := &ResponseWriter{ResponseWriter: w, client: client, server: fresh, size: size}
+	rcode, err := plugin.NextOrFailure(c.Name(), c.Next, ctx, cw, r)
+	if plugin.ClientWrite(rcode) {
+		return rcode, err
+	}
+	// The server writes error replies with its own response writer, write it here so it carries the cookie.
+	dnsserver.DefaultErrorFunc(ctx, cw, r, rcode)
+	return dns.RcodeSuccess, err
+}
+
+// Name implements the plugin.Handler interface.
+func (c *Cookie) Name() string { return "cookie" }
+
+// ResponseWriter adds the cookie to the replies it writes.
+type ResponseWriter struct {
+	dns.ResponseWriter
+	client, server []byte
+	size           uint16
+}
+
+// WriteMsg implements the dns.ResponseWriter interface.
+func (w *ResponseWriter) WriteMsg(res *dns.Msg) error {
+	edns.SetCookie(res, w.client, w.server, w.size)
+	return w.ResponseWriter.WriteMsg(res)
+}
+
+const defaultRotate = 24 * time.Hour
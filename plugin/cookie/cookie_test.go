@@ -0,0 +1,116 @@
+package cookie
+
+import (
+	"bytes"
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/coredns/coredns/plugin/pkg/dnstest"
+	"github.com/coredns/coredns/plugin/pkg/edns"
+	"github.com/coredns/coredns/plugin/test"
+
+	"github.com/miekg/dns"
+)
+
+func TestCookie(t *testing.T) {
+	backend := test.HandlerFunc(func(ctx context.Context, w dns.ResponseWriter, r *dns.Msg) (int, error) {
+		m := new(dns.Msg)
+		m.SetReply(r)
+		m.Answer = []dns.RR{test.A("example.org. 3600 IN A 127.0.0.53")}
+		w.WriteMsg(m)
+		return dns.RcodeSuccess, nil
+	})
+
+	secrets := newSecrets()
+	client := []byte("01234567")
+	ip := net.ParseIP("10.240.0.1") // address of test.ResponseWriter
+	valid := edns.ServerCookie(secrets.secret(), client, ip, time.Now())
+	invalid := edns.ServerCookie(secrets.secret(), client, net.ParseIP("10.240.0.2"), time.Now())
+
+	tests := []struct {
+		require           bool
+		client            []byte
+		server            []byte
+		tcp               bool
+		expectedCode      int
+		expectedAnswer    bool
+		expectedTruncated bool
+	}{
+		{false, nil, nil, false, dns.RcodeSuccess, true, false},
+		{false, client, nil, false, dns.RcodeSuccess, true, false},
+		{false, client, valid, false, dns.RcodeSuccess, true, false},
+		{false, client, invalid, false, dns.RcodeBadCookie, false, false},
+		// Over TCP an invalid server cookie is ignored.
+		{false, client, invalid, true, dns.RcodeSuccess, true, false},
+		// Clients without a cookie are sent to TCP.
+		{true, nil, nil, false, dns.RcodeSuccess, false, true},
+		{true, nil, nil, true, dns.RcodeSuccess, true, false},
+		{true, client, nil, false, dns.RcodeBadCookie, false, false},
+		{true, client, nil, true, dns.RcodeSuccess, true, false},
+		// A client cookie must be 8 octets.
+		{false, []byte("short"), nil, false, dns.RcodeFormatError, false, false},
+	}
+
+	ctx := context.TODO()
+
+	for i, tc := range tests {
+		c := &Cookie{Next: backend, secrets: secrets, require: tc.require, now: time.Now}
+
+		req := new(dns.Msg)
+		req.SetQuestion("example.org.", dns.TypeA)
+		if tc.client != nil {
+			edns.SetCookie(req, tc.client, tc.server, 4096)
+		}
+
+		rec := dnstest.NewRecorder(&test.ResponseWriter{TCP: tc.tcp})
+		code, _ := c.ServeDNS(ctx, rec, req)
+		if code != tc.expectedCode {
+			t.Errorf("Test %d: Expected status code %d, but got %d", i, tc.expectedCode, code)
+		}
+		if rec.Msg == nil {
+			if tc.expectedCode != dns.RcodeFormatError {
+				t.Errorf("Test %d: Expected a reply, but got none", i)
+			}
+			continue
+		}
+		if answer := len(rec.Msg.Answer) > 0; answer != tc.expectedAnswer {
+			t.Errorf("Test %d: Expected answer %t, but got %t", i, tc.expectedAnswer, answer)
+		}
+		if rec.Msg.Truncated != tc.expectedTruncated {
+			t.Errorf("Test %d: Expected truncated %t, but got %t", i, tc.expectedTruncated, rec.Msg.Truncated)
+		}
+		if tc.client == nil {
+			continue
+		}
+		cc, sc, err := edns.Cookie(rec.Msg)
+		if err != nil || !bytes.Equal(cc, client) {
+			t.Errorf("Test %d: Expected client cookie to be echoed, but got %x (%v)", i, cc, err)
+		}
+		if !secrets.valid(client, sc, ip, time.Now()) {
+			t.Errorf("Test %d: Expected a valid server cookie, but got %x", i, sc)
+		}
+	}
+}
+
+func TestCookieRotate(t *testing.T) {
+	c := New()
+	c.Next = test.ErrorHandler()
+
+	client := []byte("01234567")
+	server := edns.ServerCookie(c.secrets.secret(), client, net.ParseIP("10.240.0.1"), time.Now())
+
+	for i, expected := range []int{dns.RcodeSuccess, dns.RcodeBadCookie} {
+		// Cookies of the previous secret are accepted, older ones are not.
+		c.secrets.rotate()
+
+		req := new(dns.Msg)
+		req.SetQuestion("example.org.", dns.TypeA)
+		edns.SetCookie(req, client, server, 4096)
+		code, _ := c.ServeDNS(context.TODO(), dnstest.NewRecorder(&test.ResponseWriter{}), req)
+		if code != expected {
+			t.Errorf("Test %d: Expected status code %d, but got %d", i, expected, code)
+		}
+	}
+}
@@ -0,0 +1,15 @@
+package cookie
+
+import (
+	"github.com/coredns/coredns/plugin"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// RequestCount is the number of queries by the kind of cookie they carry.
+var RequestCount = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Namespace: plugin.Namespace,
+	Subsystem: "cookie",
+	Name:      "requests_total",
+	Help:      "Counter of queries per kind of cookie.",
+}, []string{"server", "type"})
@@ -0,0 +1,68 @@
+package cookie
+
+import (
+	"crypto/rand"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/coredns/coredns/plugin/pkg/edns"
+)
+
+// secrets holds the secret server cookies are created with, and the previous secret. Cookies created
+// with the previous secret are still accepted, so clients don't get BADCOOKIE after a rotation.
+type secrets struct {
+	sync.RWMutex
+	current  [16]byte
+	previous *[16]byte
+}
+
+// newSecrets returns secrets with a random current secret.
+func newSecrets() *secrets {
+	s := &secrets{}
+	rand.Read(s.current[:])
+	return s
+}
+
+// secret returns the current secret.
+func (s *secrets) secret() [16]byte {
+	s.RLock()
+	defer s.RUnlock()
+	return s.current
+}
+
+// rotate makes the current secret the previous one and replaces it with a random secret.
+func (s *secrets) rotate() {
+	var next [16]byte
+	rand.Read(next[:])
+
+	s.Lock()
+	defer s.Unlock()
+	prev := s.current
+	s.previous = &prev
+	s.current = next
+}
+
+// rotateEvery rotates the secrets every interval until stop is closed.
+func (s *secrets) rotateEvery(interval time.Duration, stop <-chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			s.rotate()
+		}
+	}
+}
+
+// valid returns true when server is a valid server cookie for client and ip under either secret.
+func (s *secrets) valid(client, server []byte, ip net.IP, now time.Time) bool {
+	s.RLock()
+	defer s.RUnlock()
+	if edns.ValidServerCookie(s.current, client, server, ip, now) {
+		return true
+	}
+	return s.previous != nil && edns.ValidServerCookie(*s.previous, client, server, ip, now)
+}
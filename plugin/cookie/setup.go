@@ -0,0 +1,136 @@
+package cookie
+
+import (
+	"encoding/hex"
+	"time"
+
+	"github.com/coredns/coredns/core/dnsserver"
+	"github.com/coredns/coredns/plugin"
+	"github.com/coredns/coredns/plugin/metrics"
+
+	"github.com/mholt/caddy"
+)
+
+func init() {
+	caddy.RegisterPlugin("cookie", caddy.Plugin{
+		ServerType: "dns",
+		Action:     setup,
+	})
+}
+
+func setup(c *caddy.Controller) error {
+	ck, err := parse(c)
+	if err != nil {
+		return plugin.Error("cookie", err)
+	}
+
+	c.OnStartup(func() error {
+		metrics.MustRegister(c, RequestCount)
+		return nil
+	})
+
+	// The keys of a server block share the secrets, so a cookie for one zone is valid for the
+	// others; the first key rotates them.
+	if s, ok := c.ServerBlockStorage.(*secrets); ok {
+		ck.secrets = s
+	} else {
+		c.ServerBlockStorage = ck.secrets
+		if ck.rotate > 0 {
+			stop := make(chan struct{})
+			c.OnStartup(func() error {
+				go ck.secrets.rotateEvery(ck.rotate, stop)
+				return nil
+			})
+			c.OnShutdown(func() error {
+				close(stop)
+				return nil
+			})
+		}
+	}
+
+	dnsserver.GetConfig(c).AddPlugin(func(next plugin.Handler) plugin.Handler {
+		ck.Next = next
+		return ck
+	})
+
+	return nil
+}
+
+func parse(c *caddy.Controller) (*Cookie, error) {
+	ck := New()
+	i := 0
+	for c.Next() {
+		if i > 0 {
+			return nil, plugin.ErrOnce
+		}
+		i++
+		if len(c.RemainingArgs()) != 0 {
+			return nil, c.ArgErr()
+		}
+
+		rotate := false
+		configured := false
+		for c.NextBlock() {
+			switch c.Val() {
+			case "secret":
+				args := c.RemainingArgs()
+				if len(args) == 0 || len(args) > 2 {
+					return nil, c.ArgErr()
+				}
+				current, err := parseSecret(c, args[0])
+				if err != nil {
+					return nil, err
+				}
+				ck.secrets.current = current
+				if len(args) == 2 {
+					previous, err := parseSecret(c, args[1])
+					if err != nil {
+						return nil, err
+					}
+					ck.secrets.previous = &previous
+				}
+				configured = true
+			case "rotate":
+				args := c.RemainingArgs()
+				if len(args) != 1 {
+					return nil, c.ArgErr()
+				}
+				d, err := time.ParseDuration(args[0])
+				if err != nil {
+					return nil, c.Errf("invalid rotate value '%s': %s", args[0], err)
+				}
+				if d <= 0 {
+					return nil, c.Errf("rotate must be positive: %s", args[0])
+				}
+				ck.rotate = d
+				rotate = true
+			case "require":
+				if c.NextArg() {
+					return nil, c.ArgErr()
+				}
+				ck.require = true
+			default:
+				return nil, c.Errf("unknown property '%s'", c.Val())
+			}
+		}
+
+		if configured {
+			if rotate {
+				return nil, c.Err("rotate can not be used with a configured secret")
+			}
+			ck.rotate = 0
+		}
+	}
+	return ck, nil
+}
+
+// parseSecret parses a secret of 16 octets, written as 32 hexadecimal characters.
+func parseSecret(c *caddy.Controller, s string) ([16]byte, error) {
+	var secret [16]byte
+	b, err := hex.DecodeString(s)
+	if err != nil || len(b) != len(secret) {
+		return secret, c.Errf("secret must be 32 hexadecimal characters: '%s'", s)
+	}
+	copy(secret[:], b)
+	return secret, nil
+}
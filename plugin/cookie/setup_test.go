@@ -0,0 +1,94 @@
+package cookie
+
+import (
+	"testing"
+	"time"
+
+	"github.com/coredns/coredns/core/dnsserver"
+
+	"github.com/mholt/caddy"
+)
+
+func TestSetup(t *testing.T) {
+	tests := []struct {
+		input     string
+		shouldErr bool
+		rotate    time.Duration
+		require   bool
+		previous  bool
+	}{
+		{`cookie`, false, defaultRotate, false, false},
+		{`cookie {
+			rotate 1h
+			require
+		}`, false, time.Hour, true, false},
+		{`cookie {
+			secret e5e973e5a6b2a43f48e7dc849e37bfcf
+		}`, false, 0, false, false},
+		{`cookie {
+			secret e5e973e5a6b2a43f48e7dc849e37bfcf 000102030405060708090a0b0c0d0e0f
+		}`, false, 0, false, true},
+		// errors
+		{`cookie example.org`, true, 0, false, false},
+		{`cookie {
+			secret e5e973e5
+		}`, true, 0, false, false},
+		{`cookie {
+			secret e5e973e5a6b2a43f48e7dc849e37bfcf
+			rotate 1h
+		}`, true, 0, false, false},
+		{`cookie {
+			rotate 0s
+		}`, true, 0, false, false},
+		{`cookie {
+			require yes
+		}`, true, 0, false, false},
+		{`cookie {
+			blah
+		}`, true, 0, false, false},
+		{"cookie\ncookie", true, 0, false, false},
+	}
+
+	for i, test := range tests {
+		c := caddy.NewTestController("dns", test.input)
+		ck, err := parse(c)
+		if test.shouldErr {
+			if err == nil {
+				t.Errorf("Test %d: expected error, got none", i)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Test %d: expected no error, got %s", i, err)
+			continue
+		}
+		if ck.rotate != test.rotate {
+			t.Errorf("Test %d: expected rotate %s, got %s", i, test.rotate, ck.rotate)
+		}
+		if ck.require != test.require {
+			t.Errorf("Test %d: expected require %t, got %t", i, test.require, ck.require)
+		}
+		if (ck.secrets.previous != nil) != test.previous {
+			t.Errorf("Test %d: expected previous secret %t", i, test.previous)
+		}
+	}
+}
+
+func TestSetupServerBlock(t *testing.T) {
+	// Two keys of a server block, such as "example.org example.net { cookie }".
+	c := caddy.NewTestController("dns", `cookie`)
+	if err := setup(c); err != nil {
+		t.Fatalf("Expected no error, got %s", err)
+	}
+	c1 := caddy.NewTestController("dns", `cookie`)
+	c1.ServerBlockStorage = c.ServerBlockStorage
+	if err := setup(c1); err != nil {
+		t.Fatalf("Expected no error, got %s", err)
+	}
+
+	ck := dnsserver.GetConfig(c).Plugin[0](nil).(*Cookie)
+	ck1 := dnsserver.GetConfig(c1).Plugin[0](nil).(*Cookie)
+	if ck.secrets != ck1.secrets {
+		t.Errorf("Expected the keys of a server block to share the secrets")
+	}
+}
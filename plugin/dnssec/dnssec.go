@@ -7,6 +7,7 @@ import (
 
 	"github.com/coredns/coredns/plugin"
 	"github.com/coredns/coredns/plugin/pkg/cache"
+	"github.com/coredns/coredns/plugin/pkg/ede"
 	"github.com/coredns/coredns/plugin/pkg/response"
 	"github.com/coredns/coredns/plugin/pkg/singleflight"
 	"github.com/coredns/coredns/request"
@@ -46,6 +47,14 @@ func (d Dnssec) Sign(state request.Request, now time.Time, server string) *dns.M
 
 	incep, expir := incepExpir(now)
 
+	// If any signing fails, the client gets told why its RRSIGs are missing.
+	failed := false
+	defer func() {
+		if failed {
+			ede.Set(state.Context, ede.RRSIGsMissing, "failed to sign the reply")
+		}
+	}()
+
 	mt, _ := response.Typify(req, time.Now().UTC()) // TODO(miek): need opt record here?
 	if mt == response.Delegation {
 		return req
@@ -60,9 +69,13 @@ func (d Dnssec) Sign(state request.Request, now time.Time, server string) *dns.M
 
 		if sigs, err := d.sign(req.Ns, state.Zone, ttl, incep, expir, server); err == nil {
 			req.Ns = append(req.Ns, sigs...)
+		} else {
+			failed = true
 		}
 		if sigs, err := d.nsec(state, mt, ttl, incep, expir, server); err == nil {
 			req.Ns = append(req.Ns, sigs...)
+		} else {
+			failed = true
 		}
 		if len(req.Ns) > 1 { // actually added nsec and sigs, reset the rcode
 			req.Rcode = dns.RcodeSuccess
@@ -74,18 +87,24 @@ func (d Dnssec) Sign(state request.Request, now time.Time, server string) *dns.M
 		ttl := r[0].Header().Ttl
 		if sigs, err := d.sign(r, state.Zone, ttl, incep, expir, server); err == nil {
 			req.Answer = append(req.Answer, sigs...)
+		} else {
+			failed = true
 		}
 	}
 	for _, r := range rrSets(req.Ns) {
 		ttl := r[0].Header().Ttl
 		if sigs, err := d.sign(r, state.Zone, ttl, incep, expir, server); err == nil {
 			req.Ns = append(req.Ns, sigs...)
+		} else {
+			failed = true
 		}
 	}
 	for _, r := range rrSets(req.Extra) {
 		ttl := r[0].Header().Ttl
 		if sigs, err := d.sign(r, state.Zone, ttl, incep, expir, server); err == nil {
 			req.Extra = append(req.Extra, sigs...)
+		} else {
+			failed = true
 		}
 	}
 	return req
@@ -1,6 +1,7 @@
 package dnssec
 
 import (
+	"context"
 	"time"
 
 	"github.com/coredns/coredns/plugin"
@@ -13,14 +14,15 @@ import (
 type ResponseWriter struct {
 	dns.ResponseWriter
 	d      Dnssec
-	server string // server label for metrics.
+	server string          // server label for metrics.
+	ctx    context.Context // context of the request, to set an extended error when signing fails.
 }
 
 // WriteMsg implements the dns.ResponseWriter interface.
 func (d *ResponseWriter) WriteMsg(res *dns.Msg) error {
 	// By definition we should sign anything that comes back, we should still figure out for
 	// which zone it should be.
-	state := request.Request{W: d.ResponseWriter, Req: res}
+	state := request.Request{W: d.ResponseWriter, Req: res, Context: d.ctx}
 
 	zone := plugin.Zones(d.d.zones).Matches(state.Name())
 	if zone == "" {
@@ -9,6 +9,7 @@ import (
 	"unsafe"
 
 	"github.com/coredns/coredns/plugin"
+	"github.com/coredns/coredns/plugin/metrics"
 	clog "github.com/coredns/coredns/plugin/pkg/log"
 	"github.com/coredns/coredns/request"
 
@@ -35,7 +36,7 @@ func (p *pattern) setTimer(t *time.Timer) {
 // errorHandler handles DNS errors (and errors from other plugin).
 type errorHandler struct {
 	patterns []*pattern
-	eLogger  func(int, string, string, string)
+	eLogger  func(string, int, string, string, string)
 	cLogger  func(uint32, string, time.Duration)
 	stopFlag uint32
 	Next     plugin.Handler
@@ -45,8 +46,8 @@ func newErrorHandler() *errorHandler {
 	return &errorHandler{eLogger: errorLogger, cLogger: consLogger}
 }
 
-func errorLogger(code int, qName, qType, err string) {
-	log.Errorf("%d %s %s: %s", code, qName, qType, err)
+func errorLogger(server string, code int, qName, qType, err string) {
+	log.With("server", server).Errorf("%d %s %s: %s", code, qName, qType, err)
 }
 
 func consLogger(cnt uint32, pattern string, p time.Duration) {
@@ -102,7 +103,7 @@ func (h *errorHandler) ServeDNS(ctx context.Context, w dns.ResponseWriter, r *dn
 			}
 		}
 		state := request.Request{W: w, Req: r}
-		h.eLogger(rcode, state.Name(), state.Type(), strErr)
+		h.eLogger(metrics.WithServer(ctx), rcode, state.Name(), state.Type(), strErr)
 	}
 
 	return rcode, err
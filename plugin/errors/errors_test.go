@@ -42,12 +42,12 @@ func TestErrors(t *testing.T) {
 		{
 			next:         genErrorHandler(dns.RcodeNotAuth, testErr),
 			expectedCode: dns.RcodeNotAuth,
-			expectedLog:  fmt.Sprintf("%d %s: %v\n", dns.RcodeNotAuth, "example.org. A", testErr),
+			expectedLog:  fmt.Sprintf("%d %s: %v server=dns://:53\n", dns.RcodeNotAuth, "example.org. A", testErr),
 			expectedErr:  testErr,
 		},
 	}
 
-	ctx := context.TODO()
+	ctx := context.WithValue(context.TODO(), plugin.ServerCtx{}, "dns://:53")
 	req := new(dns.Msg)
 	req.SetQuestion("example.org.", dns.TypeA)
 
@@ -261,7 +261,7 @@ func TestServeDNSConcurrent(t *testing.T) {
 				rep.incConsolidated(Err1, cnt)
 			}
 		},
-		eLogger: func(code int, n, t, e string) {
+		eLogger: func(server string, code int, n, t, e string) {
 			switch e {
 			case ErrStr0:
 				rep.incPassed(Err0)
@@ -7,11 +7,13 @@ import (
 	"errors"
 	"fmt"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/coredns/coredns/plugin"
 	"github.com/coredns/coredns/plugin/etcd/msg"
 	"github.com/coredns/coredns/plugin/pkg/fall"
+	"github.com/coredns/coredns/plugin/pkg/watch"
 	"github.com/coredns/coredns/plugin/proxy"
 	"github.com/coredns/coredns/request"
 
@@ -41,6 +43,10 @@ type Etcd struct {
 	Stubmap    *map[string]proxy.Proxy // list of proxies for stub resolving.
 
 	endpoints []string // Stored here as well, to aid in testing.
+
+	watched     watch.Names
+	watchOnce   sync.Once          // starts watching the keys in etcd on the first watch
+	watchCancel context.CancelFunc // stops watching the keys in etcd
 }
 
 // Services implements the ServiceBackend interface.
@@ -75,7 +81,7 @@ func (e *Etcd) Records(state request.Request, exact bool) ([]msg.Service, error)
 	name := state.Name()
 
 	path, star := msg.PathWithWildcard(name, e.PathPrefix)
-	r, err := e.get(path, !exact)
+	r, err := e.get(state.Context, path, !exact)
 	if err != nil {
 		return nil, err
 	}
@@ -83,8 +89,13 @@ func (e *Etcd) Records(state request.Request, exact bool) ([]msg.Service, error)
 	return e.loopNodes(r.Kvs, segments, star)
 }
 
-func (e *Etcd) get(path string, recursive bool) (*etcdcv3.GetResponse, error) {
-	ctx, cancel := context.WithTimeout(e.Ctx, etcdTimeout)
+// get gets the keys for path from etcd. The request is canceled when ctx is, a nil ctx uses the
+// context of e.
+func (e *Etcd) get(ctx context.Context, path string, recursive bool) (*etcdcv3.GetResponse, error) {
+	if ctx == nil {
+		ctx = e.Ctx
+	}
+	ctx, cancel := context.WithTimeout(ctx, etcdTimeout)
 	defer cancel()
 	if recursive == true {
 		if !strings.HasSuffix(path, "/") {
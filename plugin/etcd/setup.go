@@ -37,6 +37,11 @@ func setup(c *caddy.Controller) error {
 		})
 	}
 
+	c.OnShutdown(func() error {
+		e.stopWatching()
+		return nil
+	})
+
 	dnsserver.GetConfig(c).AddPlugin(func(next plugin.Handler) plugin.Handler {
 		e.Next = next
 		return e
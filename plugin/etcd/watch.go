@@ -0,0 +1,64 @@
+package etcd
+
+import (
+	"context"
+
+	"github.com/coredns/coredns/plugin"
+	"github.com/coredns/coredns/plugin/etcd/msg"
+	"github.com/coredns/coredns/plugin/pkg/watch"
+
+	etcdcv3 "github.com/coreos/etcd/clientv3"
+	"github.com/miekg/dns"
+)
+
+// SetWatchChan implements watch.Watchable.
+func (e *Etcd) SetWatchChan(c watch.Chan) { e.watched.SetWatchChan(c) }
+
+// Watch implements watch.Watchable. The first watch starts watching the keys in etcd.
+func (e *Etcd) Watch(qname string) error {
+	if plugin.Zones(e.Zones).Matches(qname) == "" {
+		return nil
+	}
+	if err := e.watched.Watch(qname); err != nil {
+		return err
+	}
+	e.watchOnce.Do(func() {
+		ctx, cancel := context.WithCancel(e.Ctx)
+		e.watchCancel = cancel
+		go e.watchKeys(ctx)
+	})
+	return nil
+}
+
+// StopWatching implements watch.Watchable.
+func (e *Etcd) StopWatching(qname string) { e.watched.StopWatching(qname) }
+
+var _ watch.Watchable = &Etcd{}
+
+// watchKeys watches the keys under the path prefix. A change to a key changes the records of
+// its name and of the names above it, as records are looked up recursively.
+func (e *Etcd) watchKeys(ctx context.Context) {
+	for wr := range e.Client.Watch(ctx, "/"+e.PathPrefix+"/", etcdcv3.WithPrefix()) {
+		if err := wr.Err(); err != nil {
+			log.Warningf("Failed to watch keys: %s", err)
+			continue
+		}
+		for _, ev := range wr.Events {
+			name := msg.Domain(string(ev.Kv.Key))
+			for _, w := range e.watched.Names() {
+				if dns.IsSubDomain(w, name) {
+					e.watched.Changed(w)
+				}
+			}
+		}
+	}
+}
+
+// stopWatching stops watching the keys in etcd, if started.
+func (e *Etcd) stopWatching() {
+	// After this no watching of the keys will be started.
+	e.watchOnce.Do(func() {})
+	if e.watchCancel != nil {
+		e.watchCancel()
+	}
+}
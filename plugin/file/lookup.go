@@ -30,14 +30,9 @@ func (z *Zone) Lookup(state request.Request, qname string) ([]dns.RR, []dns.RR,
 	qtype := state.QType()
 	do := state.Do()
 
-	if 0 < z.ReloadInterval {
-		z.reloadMu.RLock()
-	}
-	defer func() {
-		if 0 < z.ReloadInterval {
-			z.reloadMu.RUnlock()
-		}
-	}()
+	// The zone can also be swapped by a transfer or a reload through the admin API, so always lock.
+	z.reloadMu.RLock()
+	defer z.reloadMu.RUnlock()
 
 	// If z is a secondary zone we might not have transferred it, meaning we have
 	// all zone context setup, except the actual record. This means (for one thing) the apex
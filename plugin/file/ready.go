@@ -0,0 +1,15 @@
+package file
+
+// Ready implements the ready.Readiness interface. A zone is ready once it has a SOA record, for
+// secondary zones this is only the case after the zone has been transferred.
+func (f File) Ready() bool {
+	for _, z := range f.Zones.Z {
+		z.reloadMu.RLock()
+		soa := z.Apex.SOA
+		z.reloadMu.RUnlock()
+		if soa == nil {
+			return false
+		}
+	}
+	return true
+}
@@ -28,31 +28,12 @@ func (z *Zone) Reload() error {
 				//saving timestamp of last attempted reload
 				z.LastReloaded = time.Now()
 
-				zFile := z.File()
-				reader, err := os.Open(zFile)
-				if err != nil {
-					log.Errorf("Failed to open zone %q in %q: %v", z.origin, zFile, err)
-					continue
-				}
-
-				serial := z.SOASerialIfDefined()
-				zone, err := Parse(reader, z.origin, zFile, serial)
-				if err != nil {
+				if err := z.reload(z.SOASerialIfDefined()); err != nil {
 					if _, ok := err.(*serialErr); !ok {
-						log.Errorf("Parsing zone %q: %v", z.origin, err)
+						log.Errorf("Failed to reload zone %q in %q: %v", z.origin, z.File(), err)
 					}
-					continue
 				}
 
-				// copy elements we need
-				z.reloadMu.Lock()
-				z.Apex = zone.Apex
-				z.Tree = zone.Tree
-				z.reloadMu.Unlock()
-
-				log.Infof("Successfully reloaded zone %q in %q with serial %d", z.origin, zFile, z.Apex.SOA.Serial)
-				z.Notify()
-
 			case <-z.reloadShutdown:
 				tick.Stop()
 				return
@@ -62,6 +43,39 @@ func (z *Zone) Reload() error {
 	return nil
 }
 
+// ReloadNow reloads the zone from disk right away, also when its SOA serial didn't change.
+func (z *Zone) ReloadNow() error { return z.reload(-1) }
+
+// reload parses the zone's file and sets it live. If serial >= 0 and the SOA serial in the file
+// is the same, nothing is reloaded and a *serialErr is returned.
+func (z *Zone) reload(serial int64) error {
+	zFile := z.File()
+	reader, err := os.Open(zFile)
+	if err != nil {
+		return err
+	}
+	defer reader.Close()
+
+	zone, err := Parse(reader, z.origin, zFile, serial)
+	if err != nil {
+		return err
+	}
+
+	// copy elements we need
+	z.reloadMu.Lock()
+	changed := z.watchedChanges(zone)
+	z.Apex = zone.Apex
+	z.Tree = zone.Tree
+	z.reloadMu.Unlock()
+
+	log.Infof("Successfully reloaded zone %q in %q with serial %d", z.origin, zFile, zone.Apex.SOA.Serial)
+	z.Notify()
+	for _, name := range changed {
+		z.Watched.Changed(name)
+	}
+	return nil
+}
+
 // SOASerialIfDefined returns the SOA's serial if the zone has a SOA record in the Apex, or
 // -1 otherwise.
 func (z *Zone) SOASerialIfDefined() int64 {
@@ -61,6 +61,47 @@ func TestZoneReload(t *testing.T) {
 	}
 }
 
+func TestZoneReloadNow(t *testing.T) {
+	fileName, rm, err := test.TempFile(".", reloadZoneTest)
+	if err != nil {
+		t.Fatalf("Failed to create zone: %s", err)
+	}
+	defer rm()
+	reader, err := os.Open(fileName)
+	if err != nil {
+		t.Fatalf("Failed to open zone: %s", err)
+	}
+	z, err := Parse(reader, "miek.nl", fileName, 0)
+	reader.Close()
+	if err != nil {
+		t.Fatalf("Failed to parse zone: %s", err)
+	}
+
+	// Same serial, fewer records.
+	same := strings.Join(strings.SplitAfter(reloadZoneTest, "\n")[:3], "")
+	if err := ioutil.WriteFile(fileName, []byte(same), 0644); err != nil {
+		t.Fatalf("Failed to write new zone data: %s", err)
+	}
+	// Reloading isn't enabled, but lookups still race with ReloadNow.
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		r := new(dns.Msg)
+		r.SetQuestion("miek.nl", dns.TypeSOA)
+		state := request.Request{W: &test.ResponseWriter{}, Req: r}
+		for i := 0; i < 100; i++ {
+			z.Lookup(state, "miek.nl.")
+		}
+	}()
+	if err := z.ReloadNow(); err != nil {
+		t.Fatalf("Failed to reload zone: %s", err)
+	}
+	<-done
+	if len(z.All()) != 3 {
+		t.Fatalf("Expected 3 RRs, got %d", len(z.All()))
+	}
+}
+
 func TestZoneReloadSOAChange(t *testing.T) {
 	_, err := Parse(strings.NewReader(reloadZoneTest), "miek.nl.", "stdin", 1460175181)
 	if err == nil {
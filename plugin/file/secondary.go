@@ -51,10 +51,16 @@ Transfer:
 		return Err
 	}
 
+	changed := z.watchedChanges(z1)
+	z.reloadMu.Lock()
 	z.Tree = z1.Tree
 	z.Apex = z1.Apex
+	z.reloadMu.Unlock()
 	*z.Expired = false
 	log.Infof("Transferred: %s from %s", z.origin, tr)
+	for _, name := range changed {
+		z.Watched.Changed(name)
+	}
 	return nil
 }
 
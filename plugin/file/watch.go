@@ -0,0 +1,105 @@
+package file
+
+import (
+	"github.com/coredns/coredns/plugin"
+	"github.com/coredns/coredns/plugin/pkg/watch"
+
+	"github.com/miekg/dns"
+)
+
+// SetWatchChan implements watch.Watchable.
+func (f File) SetWatchChan(c watch.Chan) {
+	for _, z := range f.Zones.Z {
+		z.Watched.SetWatchChan(c)
+	}
+}
+
+// Watch implements watch.Watchable.
+func (f File) Watch(qname string) error {
+	zone := plugin.Zones(f.Zones.Names).Matches(qname)
+	if zone == "" {
+		return nil
+	}
+	return f.Zones.Z[zone].Watched.Watch(qname)
+}
+
+// StopWatching implements watch.Watchable.
+func (f File) StopWatching(qname string) {
+	zone := plugin.Zones(f.Zones.Names).Matches(qname)
+	if zone == "" {
+		return
+	}
+	f.Zones.Z[zone].Watched.StopWatching(qname)
+}
+
+var _ watch.Watchable = File{}
+
+// watchedChanges returns the watched names in zone z that have different records in z1. A name
+// has changed when its own records changed or those of a wildcard that may match it.
+func (z *Zone) watchedChanges(z1 *Zone) []string {
+	if z.Watched == nil {
+		return nil
+	}
+	var changed []string
+	for _, name := range z.Watched.Names() {
+		if !dns.IsSubDomain(z.origin, name) {
+			continue
+		}
+		for _, n := range wildcards(name, z.origin) {
+			if !equalRRs(z.records(n), z1.records(n)) {
+				changed = append(changed, name)
+				break
+			}
+		}
+	}
+	return changed
+}
+
+// records returns the records of name in z, including the apex records.
+func (z *Zone) records(name string) []dns.RR {
+	var rrs []dns.RR
+	if z.Tree != nil {
+		if e, ok := z.Tree.Search(name); ok {
+			rrs = e.All()
+		}
+	}
+	if name == z.origin {
+		if z.Apex.SOA != nil {
+			rrs = append(rrs, z.Apex.SOA)
+		}
+		rrs = append(rrs, z.Apex.NS...)
+	}
+	return rrs
+}
+
+// wildcards returns name and the wildcards that may match name in the zone origin.
+func wildcards(name, origin string) []string {
+	names := []string{name}
+	for off, end := dns.NextLabel(name, 0); !end; off, end = dns.NextLabel(name, off) {
+		parent := name[off:]
+		if !dns.IsSubDomain(origin, parent) {
+			break
+		}
+		names = append(names, "*."+parent)
+	}
+	return names
+}
+
+// equalRRs returns true when a and b hold the same records, regardless of their order.
+func equalRRs(a, b []dns.RR) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	seen := make(map[string]int, len(a))
+	for _, rr := range a {
+		seen[rr.String()]++
+	}
+	for _, rr := range b {
+		s := rr.String()
+		if seen[s] == 0 {
+			return false
+		}
+		seen[s]--
+	}
+	return true
+}
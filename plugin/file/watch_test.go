@@ -0,0 +1,48 @@
+package file
+
+import (
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/coredns/coredns/plugin/pkg/watch"
+)
+
+const dbWatch = `$ORIGIN example.org.
+@	3600 IN	SOA sns.dns.icann.org. noc.dns.icann.org. 2017042745 7200 3600 1209600 3600
+	3600 IN NS a.iana-servers.net.
+a	IN	A	127.0.0.1
+b	IN	A	127.0.0.2
+*.w	IN	A	127.0.0.3
+`
+
+func TestWatchedChanges(t *testing.T) {
+	z, err := Parse(strings.NewReader(dbWatch), "example.org.", "stdin", 0)
+	if err != nil {
+		t.Fatalf("Expected no error when reading zone, got %q", err)
+	}
+	z.Watched.SetWatchChan(make(watch.Chan))
+	for _, name := range []string{"example.org.", "a.example.org.", "b.example.org.", "x.w.example.org.", "example.com."} {
+		z.Watched.Watch(name)
+	}
+
+	// a is changed, *.w is changed, the SOA serial is updated and b stays the same.
+	db1 := strings.Replace(dbWatch, "2017042745", "2017042746", 1)
+	db1 = strings.Replace(db1, "127.0.0.1", "127.0.0.10", 1)
+	db1 = strings.Replace(db1, "127.0.0.3", "127.0.0.30", 1)
+	z1, err := Parse(strings.NewReader(db1), "example.org.", "stdin", 0)
+	if err != nil {
+		t.Fatalf("Expected no error when reading zone, got %q", err)
+	}
+
+	changed := z.watchedChanges(z1)
+	sort.Strings(changed)
+	expected := []string{"a.example.org.", "example.org.", "x.w.example.org."}
+	if strings.Join(changed, " ") != strings.Join(expected, " ") {
+		t.Errorf("Expected changes for %v, got %v", expected, changed)
+	}
+
+	if changed := z.watchedChanges(z); len(changed) != 0 {
+		t.Errorf("Expected no changes for the same zone, got %v", changed)
+	}
+}
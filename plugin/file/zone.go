@@ -10,6 +10,7 @@ import (
 
 	"github.com/coredns/coredns/plugin/file/tree"
 	"github.com/coredns/coredns/plugin/pkg/upstream"
+	"github.com/coredns/coredns/plugin/pkg/watch"
 	"github.com/coredns/coredns/request"
 
 	"github.com/miekg/dns"
@@ -33,6 +34,8 @@ type Zone struct {
 	reloadMu       sync.RWMutex
 	reloadShutdown chan bool
 	Upstream       upstream.Upstream // Upstream for looking up names during the resolution process
+
+	Watched *watch.Names // Watched names, zones of a plugin may share these
 }
 
 // Apex contains the apex records of a zone: SOA, NS and their potential signatures.
@@ -53,6 +56,7 @@ func NewZone(name, file string) *Zone {
 		Expired:        new(bool),
 		reloadShutdown: make(chan bool),
 		LastReloaded:   time.Now(),
+		Watched:        new(watch.Names),
 	}
 	*z.Expired = false
 
@@ -65,6 +69,7 @@ func (z *Zone) Copy() *Zone {
 	z1.TransferTo = z.TransferTo
 	z1.TransferFrom = z.TransferFrom
 	z1.Expired = z.Expired
+	z1.Watched = z.Watched
 
 	z1.Apex = z.Apex
 	return z1
@@ -76,6 +81,7 @@ func (z *Zone) CopyWithoutApex() *Zone {
 	z1.TransferTo = z.TransferTo
 	z1.TransferFrom = z.TransferFrom
 	z1.Expired = z.Expired
+	z1.Watched = z.Watched
 
 	return z1
 }
@@ -164,10 +170,8 @@ func (z *Zone) TransferAllowed(state request.Request) bool {
 // All returns all records from the zone, the first record will be the SOA record,
 // otionally followed by all RRSIG(SOA)s.
 func (z *Zone) All() []dns.RR {
-	if z.ReloadInterval > 0 {
-		z.reloadMu.RLock()
-		defer z.reloadMu.RUnlock()
-	}
+	z.reloadMu.RLock()
+	defer z.reloadMu.RUnlock()
 
 	records := []dns.RR{}
 	allNodes := z.Tree.All()
@@ -7,10 +7,13 @@ package forward
 import (
 	"context"
 	"io"
+	"net"
 	"strconv"
+	"sync"
 	"sync/atomic"
 	"time"
 
+	"github.com/coredns/coredns/plugin/pkg/edns"
 	"github.com/coredns/coredns/request"
 
 	"github.com/miekg/dns"
@@ -71,8 +74,37 @@ func (t *Transport) Dial(proto string) (*dns.Conn, bool, error) {
 
 // Connect selects an upstream, sends the request and waits for a response.
 func (p *Proxy) Connect(ctx context.Context, state request.Request, opts options) (*dns.Msg, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if !p.acquire() {
+		// Queries piling up means the upstream is in trouble, count this as a failure; but once
+		// until it has room again, not for every query that is rejected.
+		if atomic.CompareAndSwapUint32(&p.limited, 0, 1) {
+			atomic.AddUint32(&p.fails, 1)
+		}
+		return nil, ErrLimitExceeded
+	}
+	defer p.release()
 	start := time.Now()
 
+	if p.doh != nil {
+		ret, err := p.connectHTTPS(ctx, state)
+		if err != nil {
+			return nil, err
+		}
+		p.observe(ret, start)
+		return ret, nil
+	}
+	if p.grpc != nil {
+		ret, err := p.connectGRPC(ctx, state)
+		if err != nil {
+			return nil, err
+		}
+		p.observe(ret, start)
+		return ret, nil
+	}
+
 	proto := ""
 	switch {
 	case opts.forceTCP: // TCP flag has precedence over UDP flag
@@ -88,33 +120,66 @@ func (p *Proxy) Connect(ctx context.Context, state request.Request, opts options
 		return nil, err
 	}
 
+	// Stop waiting for the upstream when the query is canceled.
+	stop := cancelOnDone(ctx, conn.Conn)
+	defer stop()
+
 	// Set buffer size correctly for this client.
 	conn.UDPSize = uint16(state.Size())
 	if conn.UDPSize < 512 {
 		conn.UDPSize = 512
 	}
 
-	conn.SetWriteDeadline(time.Now().Add(maxTimeout))
-	if err := conn.WriteMsg(state.Req); err != nil {
+	req := state.Req
+	// Use our cookies for this upstream, and pad queries sent over TLS, RFC 8467; but only when
+	// the client used EDNS.
+	if req.IsEdns0() != nil {
+		req = req.Copy()
+		p.cookies.set(req)
+		if p.transport.tlsConfig != nil {
+			edns.Pad(req, edns.QueryPaddingBlock, dns.MaxMsgSize)
+		}
+	}
+
+	conn.SetWriteDeadline(limitDeadline(ctx, maxTimeout))
+	if err := conn.WriteMsg(req); err != nil {
 		conn.Close() // not giving it back
 		if err == io.EOF && cached {
 			return nil, ErrCachedClosed
 		}
+		if err := contextErr(ctx); err != nil {
+			return nil, err
+		}
 		return nil, err
 	}
 
-	conn.SetReadDeadline(time.Now().Add(readTimeout))
+	conn.SetReadDeadline(limitDeadline(ctx, readTimeout))
 	ret, err := conn.ReadMsg()
 	if err != nil {
 		conn.Close() // not giving it back
 		if err == io.EOF && cached {
 			return nil, ErrCachedClosed
 		}
+		if err := contextErr(ctx); err != nil {
+			// The query was canceled, this says nothing about the upstream.
+			return nil, err
+		}
 		return ret, err
 	}
 
+	stop()
 	p.transport.Yield(conn)
 
+	if err := p.cookies.update(ret); err != nil {
+		return nil, err
+	}
+
+	p.observe(ret, start)
+	return ret, nil
+}
+
+// observe updates the metrics of p for the reply ret to a query sent at start.
+func (p *Proxy) observe(ret *dns.Msg, start time.Time) {
 	rc, ok := dns.RcodeToString[ret.Rcode]
 	if !ok {
 		rc = strconv.Itoa(ret.Rcode)
@@ -123,8 +188,50 @@ func (p *Proxy) Connect(ctx context.Context, state request.Request, opts options
 	RequestCount.WithLabelValues(p.addr).Add(1)
 	RcodeCount.WithLabelValues(rc, p.addr).Add(1)
 	RequestDuration.WithLabelValues(p.addr).Observe(time.Since(start).Seconds())
-
-	return ret, nil
 }
 
 const cumulativeAvgWeight = 4
+
+// cancelOnDone unblocks the reads and writes on conn once ctx is done. The returned function stops
+// this, it must be called before conn is reused and may be called more than once.
+func cancelOnDone(ctx context.Context, conn net.Conn) (stop func()) {
+	done := ctx.Done()
+	if done == nil {
+		return func() {}
+	}
+	stopc := make(chan struct{})
+	exited := make(chan struct{})
+	go func() {
+		defer close(exited)
+		select {
+		case <-done:
+			conn.SetDeadline(time.Now())
+		case <-stopc:
+		}
+	}()
+	var once sync.Once
+	return func() {
+		once.Do(func() { close(stopc) })
+		<-exited
+	}
+}
+
+// limitDeadline returns the time timeout from now, or the deadline of ctx when that is earlier.
+func limitDeadline(ctx context.Context, timeout time.Duration) time.Time {
+	d := time.Now().Add(timeout)
+	if cd, ok := ctx.Deadline(); ok && cd.Before(d) {
+		return cd
+	}
+	return d
+}
+
+// contextErr returns the error of ctx, also when its deadline has passed but it isn't canceled yet.
+func contextErr(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if d, ok := ctx.Deadline(); ok && !time.Now().Before(d) {
+		return context.DeadlineExceeded
+	}
+	return nil
+}
@@ -0,0 +1,54 @@
+package forward
+
+import (
+	"bytes"
+	"crypto/rand"
+	"errors"
+	"sync"
+
+	"github.com/coredns/coredns/plugin/pkg/edns"
+
+	"github.com/miekg/dns"
+)
+
+// cookies holds the DNS Cookies (RFC 7873) of an upstream: the client cookie we use for it and the
+// server cookie it returned. Cookies are hop-by-hop, the cookie of the client's query is replaced.
+type cookies struct {
+	client []byte
+
+	sync.RWMutex
+	server []byte
+}
+
+func newCookies() *cookies {
+	c := &cookies{client: make([]byte, 8)}
+	rand.Read(c.client)
+	return c
+}
+
+// set sets the cookie option of the query m to our cookies, m must have an OPT RR.
+func (c *cookies) set(m *dns.Msg) {
+	c.RLock()
+	defer c.RUnlock()
+	edns.SetCookie(m, c.client, c.server, m.IsEdns0().UDPSize())
+}
+
+// update remembers the server cookie of the reply ret, and removes the cookie option from it. It
+// returns errBadCookie when the upstream rejected our cookie; the query should then be sent again
+// with the server cookie the upstream returned.
+func (c *cookies) update(ret *dns.Msg) error {
+	client, server, err := edns.Cookie(ret)
+	edns.RemoveCookie(ret)
+	if err == nil && server != nil && bytes.Equal(client, c.client) {
+		c.Lock()
+		c.server = server
+		c.Unlock()
+	}
+	if edns.Rcode(ret) == dns.RcodeBadCookie {
+		return errBadCookie
+	}
+	return nil
+}
+
+// errBadCookie is returned when an upstream replied with BADCOOKIE.
+var errBadCookie = errors.New("bad cookie")
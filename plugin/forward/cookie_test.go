@@ -0,0 +1,67 @@
+package forward
+
+import (
+	"bytes"
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/coredns/coredns/plugin/pkg/dnstest"
+	"github.com/coredns/coredns/plugin/pkg/edns"
+	"github.com/coredns/coredns/plugin/pkg/transport"
+	"github.com/coredns/coredns/plugin/test"
+
+	"github.com/miekg/dns"
+)
+
+func TestForwardCookie(t *testing.T) {
+	// The upstream requires a valid server cookie, and replies BADCOOKIE otherwise.
+	var secret [16]byte
+	sent := make(chan []byte, 2)
+	s := dnstest.NewServer(func(w dns.ResponseWriter, r *dns.Msg) {
+		client, server, _ := edns.Cookie(r)
+		sent <- client
+
+		ip := w.RemoteAddr().(*net.UDPAddr).IP
+		ret := new(dns.Msg)
+		ret.SetReply(r)
+		if edns.ValidServerCookie(secret, client, server, ip, time.Now()) {
+			ret.Answer = append(ret.Answer, test.A("example.org. IN A 127.0.0.1"))
+		} else {
+			ret.Rcode = dns.RcodeBadCookie
+		}
+		edns.SetCookie(ret, client, edns.ServerCookie(secret, client, ip, time.Now()), 512)
+		edns.SetExtendedRcode(ret)
+		w.WriteMsg(ret)
+	})
+	defer s.Close()
+
+	p := NewProxy(s.Addr, transport.DNS)
+	f := New()
+	f.SetProxy(p)
+	defer f.Close()
+
+	mine := []byte("01234567")
+	req := new(dns.Msg)
+	req.SetQuestion("example.org.", dns.TypeA)
+	edns.SetCookie(req, mine, nil, 512)
+
+	rec := dnstest.NewRecorder(&test.ResponseWriter{})
+	if _, err := f.ServeDNS(context.TODO(), rec, req); err != nil {
+		t.Fatalf("Expected no error, got %s", err)
+	}
+
+	for i := 0; i < 2; i++ {
+		client := <-sent
+		if client == nil || bytes.Equal(client, mine) {
+			t.Errorf("Expected the upstream to get our own client cookie, got %x", client)
+		}
+	}
+	if rec.Msg.Rcode != dns.RcodeSuccess || len(rec.Msg.Answer) != 1 {
+		t.Errorf("Expected an answer after retrying with the server cookie, got rcode %d", rec.Msg.Rcode)
+	}
+	if c, _, _ := edns.Cookie(rec.Msg); c != nil {
+		t.Errorf("Expected the upstream's cookie to be removed from the reply")
+	}
+}
@@ -0,0 +1,154 @@
+package forward
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"github.com/coredns/coredns/plugin/pkg/doh"
+	"github.com/coredns/coredns/plugin/pkg/edns"
+	"github.com/coredns/coredns/request"
+
+	"github.com/miekg/dns"
+	"golang.org/x/net/http2"
+)
+
+// dohTransport sends queries to a DNS-over-HTTPS upstream as POST requests, see RFC 8484. The HTTP/2
+// connections to the upstream are pooled by the HTTP client.
+type dohTransport struct {
+	url    string
+	expire time.Duration
+	tr     *http.Transport
+	client *http.Client
+}
+
+func newDoHTransport(url string) *dohTransport {
+	t := &dohTransport{url: url, expire: defaultExpire}
+	t.SetTLSConfig(nil)
+	return t
+}
+
+// SetTLSConfig sets the TLS config of the HTTP client. It must be called before the first query.
+func (t *dohTransport) SetTLSConfig(cfg *tls.Config) {
+	if cfg != nil {
+		cfg = cfg.Clone()
+	}
+	t.tr = &http.Transport{
+		Proxy:               http.ProxyFromEnvironment,
+		TLSClientConfig:     cfg,
+		TLSHandshakeTimeout: maxDialTimeout,
+		MaxIdleConnsPerHost: dohMaxIdleConns,
+		IdleConnTimeout:     t.expire,
+	}
+	// A custom TLS config turns off HTTP/2 in net/http, enable it again. This only fails when the
+	// transport already speaks HTTP/2, which a new one doesn't.
+	http2.ConfigureTransport(t.tr)
+	t.client = &http.Client{Transport: t.tr}
+}
+
+// SetExpire sets the time an idle connection is kept open.
+func (t *dohTransport) SetExpire(expire time.Duration) {
+	t.expire = expire
+	t.tr.IdleConnTimeout = expire
+}
+
+// exchange sends m to the upstream and returns the reply. It gives up once ctx is done.
+func (t *dohTransport) exchange(ctx context.Context, m *dns.Msg) (*dns.Msg, error) {
+	buf, err := m.Pack()
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequest(http.MethodPost, t.url, bytes.NewReader(buf))
+	if err != nil {
+		return nil, err
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Content-Type", doh.MimeType)
+	req.Header.Set("Accept", doh.MimeType)
+
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		io.Copy(ioutil.Discard, io.LimitReader(resp.Body, dns.MaxMsgSize))
+		resp.Body.Close()
+		return nil, fmt.Errorf("unexpected HTTP status from %s: %s", t.url, resp.Status)
+	}
+	return doh.ResponseToMsg(resp)
+}
+
+// close closes the idle connections.
+func (t *dohTransport) close() { t.tr.CloseIdleConnections() }
+
+// connectHTTPS sends the query in state to the DNS-over-HTTPS upstream of p. The message ID is set
+// to 0 upstream, as recommended by RFC 8484, so HTTP caches can cache the reply.
+func (p *Proxy) connectHTTPS(ctx context.Context, state request.Request) (*dns.Msg, error) {
+	req := state.Req.Copy()
+	req.Id = 0
+	if req.IsEdns0() != nil {
+		p.cookies.set(req)
+		edns.Pad(req, edns.QueryPaddingBlock, dns.MaxMsgSize)
+	}
+
+	dctx, cancel := context.WithDeadline(ctx, limitDeadline(ctx, readTimeout))
+	defer cancel()
+	ret, err := p.doh.exchange(dctx, req)
+	if err != nil {
+		if err := contextErr(ctx); err != nil {
+			// The query was canceled, this says nothing about the upstream.
+			return nil, err
+		}
+		return nil, err
+	}
+	ret.Id = state.Req.Id
+
+	if err := p.cookies.update(ret); err != nil {
+		return nil, err
+	}
+	return ret, nil
+}
+
+// dohHc is a health checker for a DNS-over-HTTPS endpoint, it uses the transport of the proxy.
+type dohHc struct{}
+
+// SetTLSConfig does nothing, the TLS config of the proxy's transport is used.
+func (h *dohHc) SetTLSConfig(cfg *tls.Config) {}
+
+// Check is used as the up.Func in the up.Probe.
+func (h *dohHc) Check(p *Proxy) error {
+	err := h.send(p)
+	if err != nil {
+		HealthcheckFailureCount.WithLabelValues(p.addr).Add(1)
+		atomic.AddUint32(&p.fails, 1)
+		return err
+	}
+
+	atomic.StoreUint32(&p.fails, 0)
+	return nil
+}
+
+func (h *dohHc) send(p *Proxy) error {
+	if p.doh == nil {
+		return fmt.Errorf("no DNS-over-HTTPS transport for %s", p.addr)
+	}
+	ping := new(dns.Msg)
+	ping.SetQuestion(".", dns.TypeNS)
+	ping.Id = 0
+
+	ctx, cancel := context.WithTimeout(context.Background(), hcTimeout)
+	defer cancel()
+	_, err := p.doh.exchange(ctx, ping)
+	return err
+}
+
+const (
+	dohMaxIdleConns = 2
+	hcTimeout       = 1 * time.Second
+)
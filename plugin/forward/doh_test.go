@@ -0,0 +1,94 @@
+package forward
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/coredns/coredns/plugin/pkg/doh"
+	"github.com/coredns/coredns/plugin/pkg/transport"
+	"github.com/coredns/coredns/plugin/test"
+	"github.com/coredns/coredns/request"
+
+	"github.com/miekg/dns"
+)
+
+// newDoHServer returns a DNS-over-HTTPS server that answers every query with an A record.
+func newDoHServer(t *testing.T) *httptest.Server {
+	s := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != doh.Path || r.ProtoMajor != 2 {
+			t.Errorf("Expected an HTTP/2 POST to %s, got %s %s %s", doh.Path, r.Proto, r.Method, r.URL.Path)
+		}
+		buf, _ := ioutil.ReadAll(r.Body)
+		m := new(dns.Msg)
+		if err := m.Unpack(buf); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if m.Id != 0 {
+			t.Errorf("Expected message ID 0, got %d", m.Id)
+		}
+		ret := new(dns.Msg)
+		ret.SetReply(m)
+		ret.Answer = append(ret.Answer, test.A("example.org. IN A 127.0.0.1"))
+		buf, _ = ret.Pack()
+		w.Header().Set("Content-Type", doh.MimeType)
+		w.Write(buf)
+	}))
+	s.EnableHTTP2 = true
+	s.StartTLS()
+	return s
+}
+
+func TestDoH(t *testing.T) {
+	s := newDoHServer(t)
+	defer s.Close()
+
+	p := NewProxy(strings.TrimPrefix(s.URL, "https://")+doh.Path, transport.HTTPS)
+	p.SetTLSConfig(s.Client().Transport.(*http.Transport).TLSClientConfig)
+	f := New()
+	f.SetProxy(p)
+	defer f.Close()
+
+	state := request.Request{W: &test.ResponseWriter{}, Req: new(dns.Msg)}
+	state.Req.SetQuestion("example.org.", dns.TypeA)
+	resp, err := f.Forward(state)
+	if err != nil {
+		t.Fatalf("Expected to receive reply, got %s", err)
+	}
+	if resp.Id != state.Req.Id {
+		t.Errorf("Expected message ID %d, got %d", state.Req.Id, resp.Id)
+	}
+	if len(resp.Answer) == 0 || resp.Answer[0].(*dns.A).A.String() != "127.0.0.1" {
+		t.Errorf("Expected A record for 127.0.0.1, got: %s", resp)
+	}
+
+	if err := p.health.Check(p); err != nil {
+		t.Errorf("Expected healthy upstream, got %s", err)
+	}
+}
+
+func TestDoHDown(t *testing.T) {
+	s := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "unavailable", http.StatusServiceUnavailable)
+	}))
+	defer s.Close()
+
+	p := NewProxy(strings.TrimPrefix(s.URL, "https://")+doh.Path, transport.HTTPS)
+	p.SetTLSConfig(s.Client().Transport.(*http.Transport).TLSClientConfig)
+
+	m := new(dns.Msg)
+	m.SetQuestion("example.org.", dns.TypeA)
+	if _, err := p.Connect(context.Background(), request.Request{W: &test.ResponseWriter{}, Req: m}, options{}); err == nil {
+		t.Errorf("Expected error for a 503 reply, got none")
+	}
+	if err := p.health.Check(p); err == nil {
+		t.Errorf("Expected failed health check, got none")
+	}
+	if fails := p.Fails(); fails != 1 {
+		t.Errorf("Expected 1 failure, got %d", fails)
+	}
+}
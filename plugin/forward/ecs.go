@@ -0,0 +1,89 @@
+package forward
+
+import (
+	"context"
+	"net"
+
+	"github.com/coredns/coredns/plugin/pkg/edns"
+	"github.com/coredns/coredns/request"
+
+	"github.com/miekg/dns"
+)
+
+// ecsMode tells forward what to do with the EDNS0 Client Subnet option (RFC 7871).
+type ecsMode int
+
+const (
+	// ecsNone leaves the query as is.
+	ecsNone ecsMode = iota
+	// ecsAdd sets the option to the client's address, replacing the option of the client.
+	ecsAdd
+	// ecsPassthrough forwards the option of the client, capped to the configured prefix lengths.
+	// When the client didn't send one, it is set to the client's address.
+	ecsPassthrough
+)
+
+// subnet returns the request to send upstream, which has its client subnet option set as configured,
+// and the client subnet option of the client's query, if any.
+func (f *Forward) subnet(state request.Request) (request.Request, *dns.EDNS0_SUBNET) {
+	client := edns.Subnet(state.Req)
+
+	var e *dns.EDNS0_SUBNET
+	if client != nil && f.ecs == ecsPassthrough {
+		e = capSubnet(client, f.ecsV4, f.ecsV6)
+	} else {
+		ip := net.ParseIP(state.IP())
+		if ip == nil {
+			return state, client
+		}
+		e = edns.NewSubnet(ip, f.ecsV4, f.ecsV6)
+	}
+
+	r := state.Req.Copy()
+	edns.SetSubnet(r, e, uint16(state.Size()))
+	return request.Request{W: state.W, Req: r}, client
+}
+
+// replySubnet makes the client subnet option of the reply from upstream fit the client's query. When
+// the client didn't send one, the option is removed, and so is the OPT RR when the query didn't have
+// one either. The removed option is recorded in ctx, so cache can still use its scope.
+func replySubnet(ctx context.Context, state request.Request, ret *dns.Msg, client *dns.EDNS0_SUBNET) {
+	e := edns.Subnet(ret)
+	if client == nil {
+		if e != nil {
+			edns.SetReplySubnet(ctx, e)
+			edns.RemoveSubnet(ret)
+		}
+		if state.Req.IsEdns0() == nil {
+			edns.RemoveOPT(ret)
+		}
+		return
+	}
+	scope := uint8(0)
+	if e != nil {
+		scope = e.SourceScope
+	}
+	edns.EchoSubnet(ret, client, scope, uint16(state.Size()))
+}
+
+// capSubnet returns e with its source prefix length capped to v4 or v6.
+func capSubnet(e *dns.EDNS0_SUBNET, v4, v6 uint8) *dns.EDNS0_SUBNET {
+	max, bits := v4, 32
+	if e.Family == 2 {
+		max, bits = v6, 128
+	}
+	if e.SourceNetmask <= max {
+		return e
+	}
+	return &dns.EDNS0_SUBNET{
+		Code:          dns.EDNS0SUBNET,
+		Family:        e.Family,
+		SourceNetmask: max,
+		Address:       e.Address.Mask(net.CIDRMask(int(max), bits)),
+	}
+}
+
+const (
+	defaultECSv4 = 24
+	defaultECSv6 = 56
+)
@@ -0,0 +1,98 @@
+package forward
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	"github.com/coredns/coredns/plugin/pkg/dnstest"
+	"github.com/coredns/coredns/plugin/pkg/edns"
+	"github.com/coredns/coredns/plugin/pkg/transport"
+	"github.com/coredns/coredns/plugin/test"
+
+	"github.com/miekg/dns"
+)
+
+func TestForwardECS(t *testing.T) {
+	// The upstream echoes the client subnet option of the query, with a scope of 16.
+	sent := make(chan *dns.EDNS0_SUBNET, 1)
+	s := dnstest.NewServer(func(w dns.ResponseWriter, r *dns.Msg) {
+		ret := new(dns.Msg)
+		ret.SetReply(r)
+		ret.Answer = append(ret.Answer, test.A("example.org. IN A 127.0.0.1"))
+		e := edns.Subnet(r)
+		if e != nil {
+			edns.EchoSubnet(ret, e, 16, 512)
+		}
+		sent <- e
+		w.WriteMsg(ret)
+	})
+	defer s.Close()
+
+	tests := []struct {
+		mode    ecsMode
+		client  *dns.EDNS0_SUBNET // client subnet option of the query
+		address string            // address sent upstream
+		netmask uint8
+	}{
+		{ecsAdd, nil, "10.240.0.0", 24},
+		{ecsAdd, edns.NewSubnet(net.ParseIP("192.0.2.1"), 32, 128), "10.240.0.0", 24},
+		{ecsPassthrough, nil, "10.240.0.0", 24},
+		{ecsPassthrough, edns.NewSubnet(net.ParseIP("192.0.2.1"), 32, 128), "192.0.2.0", 24},
+		{ecsPassthrough, edns.NewSubnet(net.ParseIP("192.0.2.1"), 20, 128), "192.0.0.0", 20},
+	}
+
+	for i, tc := range tests {
+		p := NewProxy(s.Addr, transport.DNS)
+		f := New()
+		f.SetProxy(p)
+		f.ecs, f.ecsV4, f.ecsV6 = tc.mode, 24, 56
+
+		req := new(dns.Msg)
+		req.SetQuestion("example.org.", dns.TypeA)
+		if tc.client != nil {
+			edns.SetSubnet(req, tc.client, 512)
+		}
+
+		ctx := edns.NewSubnetContext(context.TODO())
+		rec := dnstest.NewRecorder(&test.ResponseWriter{})
+		if _, err := f.ServeDNS(ctx, rec, req); err != nil {
+			t.Fatalf("Test %d: expected no error, got %s", i, err)
+		}
+		f.Close()
+
+		upstream := <-sent
+		if upstream == nil {
+			t.Fatalf("Test %d: expected client subnet option upstream, got none", i)
+		}
+		if !upstream.Address.Equal(net.ParseIP(tc.address)) || upstream.SourceNetmask != tc.netmask {
+			t.Errorf("Test %d: expected %s/%d upstream, got %s/%d", i, tc.address, tc.netmask, upstream.Address, upstream.SourceNetmask)
+		}
+		if e := edns.Subnet(req); tc.client == nil && e != nil {
+			t.Errorf("Test %d: expected the query of the client to be left alone", i)
+		}
+
+		// Without an option in the query, the reply doesn't get one, nor an OPT RR, but cache can
+		// find the scope in the context.
+		if tc.client == nil {
+			if rec.Msg.IsEdns0() != nil {
+				t.Errorf("Test %d: expected no OPT RR in the reply, got %s", i, rec.Msg.IsEdns0())
+			}
+			if e := edns.ReplySubnet(ctx); e == nil || e.SourceScope != 16 {
+				t.Errorf("Test %d: expected client subnet option with scope 16 in the context, got %v", i, e)
+			}
+			continue
+		}
+
+		e := edns.Subnet(rec.Msg)
+		if e == nil {
+			t.Fatalf("Test %d: expected client subnet option in the reply, got none", i)
+		}
+		if e.SourceScope != 16 {
+			t.Errorf("Test %d: expected scope 16, got %d", i, e.SourceScope)
+		}
+		if !e.Address.Equal(tc.client.Address) {
+			t.Errorf("Test %d: expected the client's address %s in the reply, got %s", i, tc.client.Address, e.Address)
+		}
+	}
+}
@@ -8,10 +8,12 @@ import (
 	"context"
 	"crypto/tls"
 	"errors"
+	"net"
 	"time"
 
 	"github.com/coredns/coredns/plugin"
 	"github.com/coredns/coredns/plugin/debug"
+	"github.com/coredns/coredns/plugin/pkg/ede"
 	clog "github.com/coredns/coredns/plugin/pkg/log"
 	"github.com/coredns/coredns/request"
 
@@ -33,9 +35,19 @@ type Forward struct {
 
 	tlsConfig     *tls.Config
 	tlsServerName string
+	tlsSet        bool // tls or tls_servername is configured, gRPC upstreams only use TLS then
 	maxfails      uint32
 	expire        time.Duration
 
+	maxConcurrent      int64 // maximum number of queries in flight per upstream, 0 is no limit
+	maxConcurrentRcode int   // the reply to a query when all upstreams are at maxConcurrent
+
+	hedgeDelay  time.Duration // send the query to a second upstream when the first hasn't replied by then, 0 is off
+	hedgeBudget *hedgeBudget
+
+	ecs          ecsMode // what to do with the EDNS0 Client Subnet option
+	ecsV4, ecsV6 uint8   // source prefix lengths of the client subnet option
+
 	opts options // also here for testing
 
 	Next plugin.Handler
@@ -44,6 +56,7 @@ type Forward struct {
 // New returns a new Forward.
 func New() *Forward {
 	f := &Forward{maxfails: 2, tlsConfig: new(tls.Config), expire: defaultExpire, p: new(random), from: ".", hcInterval: hcInterval}
+	f.maxConcurrentRcode = dns.RcodeServerFailure
 	return f
 }
 
@@ -56,6 +69,15 @@ func (f *Forward) SetProxy(p *Proxy) {
 // Len returns the number of configured proxies.
 func (f *Forward) Len() int { return len(f.proxies) }
 
+// Proxies returns the configured proxies, in the order they were configured.
+func (f *Forward) Proxies() []*Proxy { return f.proxies }
+
+// MaxFails returns the number of failed health checks after which a proxy is considered down.
+func (f *Forward) MaxFails() uint32 { return f.maxfails }
+
+// From returns the base domain that is forwarded.
+func (f *Forward) From() string { return f.from }
+
 // Name implements plugin.Handler.
 func (f *Forward) Name() string { return "forward" }
 
@@ -67,13 +89,21 @@ func (f *Forward) ServeDNS(ctx context.Context, w dns.ResponseWriter, r *dns.Msg
 		return plugin.NextOrFailure(f.Name(), f.Next, ctx, w, r)
 	}
 
+	upstream := state
+	var client *dns.EDNS0_SUBNET
+	if f.ecs != ecsNone {
+		upstream, client = f.subnet(state)
+	}
+
 	fails := 0
-	var span, child ot.Span
+	limited := 0 // upstreams that had too many queries in flight
 	var upstreamErr error
-	span = ot.SpanFromContext(ctx)
 	i := 0
 	list := f.List()
-	deadline := time.Now().Add(defaultTimeout)
+	deadline := limitDeadline(ctx, defaultTimeout)
+	if f.hedgeDelay > 0 {
+		f.hedgeBudget.add()
+	}
 
 	for time.Now().Before(deadline) {
 		if i >= len(list) {
@@ -97,45 +127,34 @@ func (f *Forward) ServeDNS(ctx context.Context, w dns.ResponseWriter, r *dns.Msg
 			HealthcheckBrokenCount.Add(1)
 		}
 
-		if span != nil {
-			child = span.Tracer().StartSpan("connect", ot.ChildOf(span.Context()))
-			ctx = ot.ContextWithSpan(ctx, child)
-		}
-
 		var (
 			ret *dns.Msg
 			err error
 		)
-		opts := f.opts
-		for {
-			ret, err = proxy.Connect(ctx, state, opts)
-			if err == nil {
-				break
-			}
-			if err == ErrCachedClosed { // Remote side closed conn, can only happen with TCP.
-				continue
-			}
-			// Retry with TCP if truncated and prefer_udp configured.
-			if err == dns.ErrTruncated && !opts.forceTCP && f.opts.preferUDP {
-				opts.forceTCP = true
-				continue
-			}
-			break
+		if next := f.hedgeTo(list, i, proxy); next != nil {
+			ret, err = f.hedge(ctx, state, upstream, proxy, next)
+		} else {
+			ret, err = f.exchange(ctx, state, upstream, proxy)
 		}
+		upstreamErr = err
 
-		if child != nil {
-			child.Finish()
+		if err := contextErr(ctx); err != nil {
+			// The query was canceled, don't try other upstreams nor hold this one responsible.
+			return dns.RcodeServerFailure, err
 		}
 
-		ret, err = truncated(state, ret, err)
-		upstreamErr = err
-
-		if err != nil {
-			// Kick off health check to see if *our* upstream is broken.
-			if f.maxfails != 0 {
-				proxy.Healthcheck()
+		if err == ErrLimitExceeded {
+			limited++
+			if limited < len(f.proxies) {
+				continue
 			}
+			// Shed the query, instead of waiting for an upstream to have room.
+			MaxConcurrentRejectCount.Add(1)
+			ede.Set(ctx, ede.Other, "too many queries in flight")
+			return f.maxConcurrentRcode, err
+		}
 
+		if err != nil {
 			if fails < len(f.proxies) {
 				continue
 			}
@@ -151,17 +170,88 @@ func (f *Forward) ServeDNS(ctx context.Context, w dns.ResponseWriter, r *dns.Msg
 			return 0, nil
 		}
 
+		if f.ecs != ecsNone {
+			replySubnet(ctx, state, ret, client)
+		}
+
 		w.WriteMsg(ret)
 		return 0, nil
 	}
 
 	if upstreamErr != nil {
+		ede.Set(ctx, ede.NetworkError, networkError(upstreamErr))
 		return dns.RcodeServerFailure, upstreamErr
 	}
 
+	ede.Set(ctx, ede.NoReachableAuthority, "no healthy upstreams")
 	return dns.RcodeServerFailure, ErrNoHealthy
 }
 
+// exchange sends upstream, the query of state as it is sent upstream, to proxy and returns the reply.
+// It retries when a cached connection was closed, on a BADCOOKIE reply and, with prefer_udp, when
+// the reply was truncated. Unless the query was canceled, it updates the statistics of proxy and
+// kicks off a health check when the exchange failed.
+func (f *Forward) exchange(ctx context.Context, state, upstream request.Request, proxy *Proxy) (*dns.Msg, error) {
+	if span := ot.SpanFromContext(ctx); span != nil {
+		child := span.Tracer().StartSpan("connect", ot.ChildOf(span.Context()))
+		defer child.Finish()
+		ctx = ot.ContextWithSpan(ctx, child)
+	}
+
+	var (
+		ret *dns.Msg
+		err error
+	)
+	opts := f.opts
+	badCookie := false
+	start := time.Now()
+	for {
+		ret, err = proxy.Connect(ctx, upstream, opts)
+		if err == nil {
+			break
+		}
+		if err == ErrCachedClosed { // Remote side closed conn, can only happen with TCP.
+			continue
+		}
+		// Retry once with the server cookie the upstream just gave us.
+		if err == errBadCookie && !badCookie {
+			badCookie = true
+			continue
+		}
+		// Retry with TCP if truncated and prefer_udp configured.
+		if err == dns.ErrTruncated && !opts.forceTCP && f.opts.preferUDP {
+			opts.forceTCP = true
+			continue
+		}
+		break
+	}
+
+	ret, err = truncated(state, ret, err)
+	if contextErr(ctx) != nil {
+		return ret, err
+	}
+	// A full upstream never saw the query: this says nothing about its latency, and Connect has
+	// already counted it as a failure, don't health check it for every rejected query.
+	if err == ErrLimitExceeded {
+		return ret, err
+	}
+	proxy.observeExchange(time.Since(start), err)
+	// Kick off health check to see if *our* upstream is broken.
+	if err != nil && f.maxfails != 0 {
+		proxy.Healthcheck()
+	}
+	return ret, err
+}
+
+// networkError returns the extra text of the extended error for err. The upstream's address is
+// left out, as it is sent to the client.
+func networkError(err error) string {
+	if ne, ok := err.(net.Error); ok && ne.Timeout() {
+		return "upstream timed out"
+	}
+	return "upstream failed"
+}
+
 func (f *Forward) match(state request.Request) bool {
 	if !plugin.Name(f.from).Matches(state.Name()) || !f.isAllowedDomain(state.Name()) {
 		return false
@@ -199,6 +289,8 @@ var (
 	ErrNoForward = errors.New("no forwarder defined")
 	// ErrCachedClosed means cached connection was closed by peer.
 	ErrCachedClosed = errors.New("cached connection was closed by peer")
+	// ErrLimitExceeded means the upstream has the maximum number of queries in flight.
+	ErrLimitExceeded = errors.New("max concurrent queries to upstream exceeded")
 )
 
 // policy tells forward what policy for selecting upstream it uses.
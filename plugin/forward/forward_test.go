@@ -1,9 +1,13 @@
 package forward
 
 import (
+	"context"
+	"net"
 	"testing"
+	"time"
 
 	"github.com/coredns/coredns/plugin/pkg/dnstest"
+	"github.com/coredns/coredns/plugin/pkg/ede"
 	"github.com/coredns/coredns/plugin/pkg/transport"
 	"github.com/coredns/coredns/plugin/test"
 	"github.com/coredns/coredns/request"
@@ -67,3 +71,148 @@ func TestForwardRefused(t *testing.T) {
 		t.Errorf("Expected rcode to be %d, got %d", dns.RcodeRefused, resp.Rcode)
 	}
 }
+
+func TestForwardDeadline(t *testing.T) {
+	s := dnstest.NewServer(func(w dns.ResponseWriter, r *dns.Msg) {
+		// Never reply.
+	})
+	defer s.Close()
+
+	p := NewProxy(s.Addr, transport.DNS)
+	f := New()
+	f.SetProxy(p)
+	defer f.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	m := new(dns.Msg)
+	m.SetQuestion("example.org.", dns.TypeA)
+	start := time.Now()
+	rcode, err := f.ServeDNS(ctx, &test.ResponseWriter{}, m)
+	if err != context.DeadlineExceeded {
+		t.Errorf("Expected %s, got %v", context.DeadlineExceeded, err)
+	}
+	if rcode != dns.RcodeServerFailure {
+		t.Errorf("Expected rcode %d, got %d", dns.RcodeServerFailure, rcode)
+	}
+	if d := time.Since(start); d > time.Second {
+		t.Errorf("Expected to give up at the deadline, took %s", d)
+	}
+	if fails := p.Fails(); fails != 0 {
+		t.Errorf("Expected no failures for the upstream, got %d", fails)
+	}
+}
+
+func TestForwardMaxConcurrent(t *testing.T) {
+	s := newAServer(t, "127.0.0.1", 300*time.Millisecond)
+	defer s.Shutdown()
+
+	p := NewProxy(s.PacketConn.LocalAddr().String(), transport.DNS)
+	p.SetMaxConcurrent(1)
+	f := New()
+	f.maxConcurrentRcode = dns.RcodeRefused
+	f.SetProxy(p)
+	defer f.Close()
+
+	m := new(dns.Msg)
+	m.SetQuestion("example.org.", dns.TypeA)
+	done := make(chan struct{})
+	go func() {
+		f.ServeDNS(context.Background(), &test.ResponseWriter{}, m)
+		close(done)
+	}()
+	for !p.full() {
+		time.Sleep(time.Millisecond)
+	}
+
+	for i := 0; i < 3; i++ {
+		start := time.Now()
+		rcode, err := f.ServeDNS(context.Background(), &test.ResponseWriter{}, m.Copy())
+		if err != ErrLimitExceeded {
+			t.Errorf("Expected %s, got %v", ErrLimitExceeded, err)
+		}
+		if rcode != dns.RcodeRefused {
+			t.Errorf("Expected rcode %d, got %d", dns.RcodeRefused, rcode)
+		}
+		if d := time.Since(start); d > 100*time.Millisecond {
+			t.Errorf("Expected the query to be rejected at once, took %s", d)
+		}
+	}
+	// The upstream being full counts as one failure, not one per rejected query.
+	if fails := p.Fails(); fails != 1 {
+		t.Errorf("Expected 1 failure for the upstream, got %d", fails)
+	}
+	// The rejected queries never reached the upstream, they don't count as its errors.
+	if r := p.errRate(); r != 0 {
+		t.Errorf("Expected no error rate for the upstream, got %f", r)
+	}
+	<-done
+
+	// Once the upstream had room again, it being full counts as a failure again.
+	done = make(chan struct{})
+	go func() {
+		f.ServeDNS(context.Background(), &test.ResponseWriter{}, m)
+		close(done)
+	}()
+	for !p.full() {
+		time.Sleep(time.Millisecond)
+	}
+	f.ServeDNS(context.Background(), &test.ResponseWriter{}, m.Copy())
+	if fails := p.Fails(); fails != 2 {
+		t.Errorf("Expected 2 failures for the upstream, got %d", fails)
+	}
+	<-done
+}
+
+func TestForwardEDE(t *testing.T) {
+	s := newAServer(t, "127.0.0.1", 0)
+	defer s.Shutdown()
+
+	// The first upstream replies with garbage, it fails. A closed port won't do: the retries may get
+	// it as their local port and read back their own query.
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %s", err)
+	}
+	defer pc.Close()
+	go func() {
+		buf := make([]byte, dns.MaxMsgSize)
+		for {
+			_, addr, err := pc.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+			pc.WriteTo([]byte{0}, addr)
+		}
+	}()
+	down := pc.LocalAddr().String()
+
+	f := New()
+	f.p = &sequential{}
+	f.SetProxy(NewProxy(down, transport.DNS))
+	f.SetProxy(NewProxy(s.PacketConn.LocalAddr().String(), transport.DNS))
+	defer f.Close()
+
+	ctx := ede.NewContext(context.Background())
+	m := new(dns.Msg)
+	m.SetQuestion("example.org.", dns.TypeA)
+	if _, err := f.ServeDNS(ctx, &test.ResponseWriter{}, m); err != nil {
+		t.Fatalf("Expected reply from the second upstream, got %s", err)
+	}
+	if e := ede.Get(ctx); e != nil {
+		t.Errorf("Expected no extended error after a retry succeeded, got %d %q", e.Code, e.Text)
+	}
+
+	f1 := New()
+	f1.SetProxy(NewProxy(down, transport.DNS))
+	defer f1.Close()
+
+	ctx = ede.NewContext(context.Background())
+	if _, err := f1.ServeDNS(ctx, &test.ResponseWriter{}, m.Copy()); err == nil {
+		t.Fatalf("Expected error from the failing upstream")
+	}
+	if e := ede.Get(ctx); e == nil || e.Code != ede.NetworkError {
+		t.Errorf("Expected extended error %d, got %v", ede.NetworkError, e)
+	}
+}
@@ -0,0 +1,155 @@
+package forward
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"sync"
+	"sync/atomic"
+
+	"github.com/coredns/coredns/pb"
+	"github.com/coredns/coredns/plugin/pkg/edns"
+	"github.com/coredns/coredns/request"
+
+	"github.com/miekg/dns"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+)
+
+// grpcTransport sends queries to a gRPC upstream with the DnsService protocol. The gRPC channel
+// to the upstream is dialed on first use and kept open until the proxy is closed.
+type grpcTransport struct {
+	addr  string
+	creds credentials.TransportCredentials // nil when the upstream is plain text
+
+	mu     sync.Mutex
+	conn   *grpc.ClientConn
+	client pb.DnsServiceClient
+}
+
+func newGRPCTransport(addr string) *grpcTransport { return &grpcTransport{addr: addr} }
+
+// SetTLSConfig makes the transport use TLS. It must be called before the first query.
+func (t *grpcTransport) SetTLSConfig(cfg *tls.Config) {
+	if cfg == nil {
+		t.creds = nil
+		return
+	}
+	t.creds = credentials.NewTLS(cfg)
+}
+
+// dial returns the client of the gRPC channel, it dials the upstream when there is no channel yet.
+// The channel connects in the background and reconnects when the connection is lost.
+func (t *grpcTransport) dial() (pb.DnsServiceClient, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.client != nil {
+		return t.client, nil
+	}
+
+	opt := grpc.WithInsecure()
+	if t.creds != nil {
+		opt = grpc.WithTransportCredentials(t.creds)
+	}
+	conn, err := grpc.Dial(t.addr, opt)
+	if err != nil {
+		return nil, err
+	}
+	t.conn = conn
+	t.client = pb.NewDnsServiceClient(conn)
+	return t.client, nil
+}
+
+// exchange sends m to the upstream and returns the reply. It gives up once ctx is done.
+func (t *grpcTransport) exchange(ctx context.Context, m *dns.Msg) (*dns.Msg, error) {
+	buf, err := m.Pack()
+	if err != nil {
+		return nil, err
+	}
+	client, err := t.dial()
+	if err != nil {
+		return nil, err
+	}
+	reply, err := client.Query(ctx, &pb.DnsPacket{Msg: buf})
+	if err != nil {
+		return nil, err
+	}
+	ret := new(dns.Msg)
+	if err := ret.Unpack(reply.Msg); err != nil {
+		return nil, err
+	}
+	return ret, nil
+}
+
+// close closes the gRPC channel.
+func (t *grpcTransport) close() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.conn == nil {
+		return
+	}
+	if err := t.conn.Close(); err != nil {
+		log.Warningf("Failed to close gRPC connection to %s: %s", t.addr, err)
+	}
+	t.conn, t.client = nil, nil
+}
+
+// connectGRPC sends the query in state to the gRPC upstream of p.
+func (p *Proxy) connectGRPC(ctx context.Context, state request.Request) (*dns.Msg, error) {
+	req := state.Req
+	if req.IsEdns0() != nil {
+		req = req.Copy()
+		p.cookies.set(req)
+		if p.grpc.creds != nil {
+			edns.Pad(req, edns.QueryPaddingBlock, dns.MaxMsgSize)
+		}
+	}
+
+	dctx, cancel := context.WithDeadline(ctx, limitDeadline(ctx, readTimeout))
+	defer cancel()
+	ret, err := p.grpc.exchange(dctx, req)
+	if err != nil {
+		if err := contextErr(ctx); err != nil {
+			// The query was canceled, this says nothing about the upstream.
+			return nil, err
+		}
+		return nil, err
+	}
+
+	if err := p.cookies.update(ret); err != nil {
+		return nil, err
+	}
+	return ret, nil
+}
+
+// grpcHc is a health checker for a gRPC endpoint, it uses the transport of the proxy.
+type grpcHc struct{}
+
+// SetTLSConfig does nothing, the TLS config of the proxy's transport is used.
+func (h *grpcHc) SetTLSConfig(cfg *tls.Config) {}
+
+// Check is used as the up.Func in the up.Probe.
+func (h *grpcHc) Check(p *Proxy) error {
+	err := h.send(p)
+	if err != nil {
+		HealthcheckFailureCount.WithLabelValues(p.addr).Add(1)
+		atomic.AddUint32(&p.fails, 1)
+		return err
+	}
+
+	atomic.StoreUint32(&p.fails, 0)
+	return nil
+}
+
+func (h *grpcHc) send(p *Proxy) error {
+	if p.grpc == nil {
+		return fmt.Errorf("no gRPC transport for %s", p.addr)
+	}
+	ping := new(dns.Msg)
+	ping.SetQuestion(".", dns.TypeNS)
+
+	ctx, cancel := context.WithTimeout(context.Background(), hcTimeout)
+	defer cancel()
+	_, err := p.grpc.exchange(ctx, ping)
+	return err
+}
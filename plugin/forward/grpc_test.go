@@ -0,0 +1,98 @@
+package forward
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+
+	"github.com/coredns/coredns/pb"
+	"github.com/coredns/coredns/plugin/pkg/transport"
+	"github.com/coredns/coredns/plugin/test"
+	"github.com/coredns/coredns/request"
+
+	"github.com/miekg/dns"
+	"google.golang.org/grpc"
+)
+
+// dnsService answers every query with an A record, or fails every query when fail is set.
+type dnsService struct{ fail bool }
+
+func (d dnsService) Query(ctx context.Context, in *pb.DnsPacket) (*pb.DnsPacket, error) {
+	if d.fail {
+		return nil, errors.New("unavailable")
+	}
+	m := new(dns.Msg)
+	if err := m.Unpack(in.Msg); err != nil {
+		return nil, err
+	}
+	ret := new(dns.Msg)
+	ret.SetReply(m)
+	ret.Answer = append(ret.Answer, test.A("example.org. IN A 127.0.0.1"))
+	buf, err := ret.Pack()
+	if err != nil {
+		return nil, err
+	}
+	return &pb.DnsPacket{Msg: buf}, nil
+}
+
+func (d dnsService) Watch(stream pb.DnsService_WatchServer) error { return nil }
+
+// newGRPCServer returns a plain text gRPC server running svc on a local address.
+func newGRPCServer(t *testing.T, svc dnsService) (*grpc.Server, string) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %s", err)
+	}
+	s := grpc.NewServer()
+	pb.RegisterDnsServiceServer(s, svc)
+	go s.Serve(l)
+	return s, l.Addr().String()
+}
+
+func TestGRPC(t *testing.T) {
+	s, addr := newGRPCServer(t, dnsService{})
+	defer s.Stop()
+
+	p := NewProxy(addr, transport.GRPC)
+	f := New()
+	f.SetProxy(p)
+	defer f.Close()
+
+	state := request.Request{W: &test.ResponseWriter{}, Req: new(dns.Msg)}
+	state.Req.SetQuestion("example.org.", dns.TypeA)
+	resp, err := f.Forward(state)
+	if err != nil {
+		t.Fatalf("Expected to receive reply, got %s", err)
+	}
+	if resp.Id != state.Req.Id {
+		t.Errorf("Expected message ID %d, got %d", state.Req.Id, resp.Id)
+	}
+	if len(resp.Answer) == 0 || resp.Answer[0].(*dns.A).A.String() != "127.0.0.1" {
+		t.Errorf("Expected A record for 127.0.0.1, got: %s", resp)
+	}
+
+	if err := p.health.Check(p); err != nil {
+		t.Errorf("Expected healthy upstream, got %s", err)
+	}
+}
+
+func TestGRPCDown(t *testing.T) {
+	s, addr := newGRPCServer(t, dnsService{fail: true})
+	defer s.Stop()
+
+	p := NewProxy(addr, transport.GRPC)
+	defer p.close()
+
+	m := new(dns.Msg)
+	m.SetQuestion("example.org.", dns.TypeA)
+	if _, err := p.Connect(context.Background(), request.Request{W: &test.ResponseWriter{}, Req: m}, options{}); err == nil {
+		t.Errorf("Expected error for a failed query, got none")
+	}
+	if err := p.health.Check(p); err == nil {
+		t.Errorf("Expected failed health check, got none")
+	}
+	if fails := p.Fails(); fails != 1 {
+		t.Errorf("Expected 1 failure, got %d", fails)
+	}
+}
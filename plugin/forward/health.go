@@ -29,6 +29,10 @@ func NewHealthChecker(trans string) HealthChecker {
 		c.WriteTimeout = 1 * time.Second
 
 		return &dnsHc{c: c}
+	case transport.HTTPS:
+		return &dohHc{}
+	case transport.GRPC:
+		return &grpcHc{}
 	}
 
 	log.Warningf("No healthchecker for transport %q", trans)
@@ -0,0 +1,120 @@
+package forward
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+
+	"github.com/coredns/coredns/request"
+
+	"github.com/miekg/dns"
+)
+
+// hedgeTo returns the upstream to send a hedged query to when proxy hasn't replied in time: the
+// first upstream in list, from i on, that is neither down nor full. It returns nil when hedging is
+// off or there is no such upstream.
+func (f *Forward) hedgeTo(list []*Proxy, i int, proxy *Proxy) *Proxy {
+	if f.hedgeDelay == 0 {
+		return nil
+	}
+	for _, p := range list[i:] {
+		if p != proxy && !p.Down(f.maxfails) && !p.full() {
+			return p
+		}
+	}
+	return nil
+}
+
+// result is the outcome of an exchange with an upstream.
+type result struct {
+	proxy *Proxy
+	ret   *dns.Msg
+	err   error
+}
+
+// hedge sends the query to proxy and, if that hasn't replied within the hedge delay and the
+// budget allows it, to next as well. The first reply wins and the other exchange is canceled. An
+// error is only returned when all exchanges failed.
+func (f *Forward) hedge(ctx context.Context, state, upstream request.Request, proxy, next *Proxy) (*dns.Msg, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel() // stops the exchange that lost
+
+	results := make(chan result, 2)
+	send := func(p *Proxy) {
+		ret, err := f.exchange(ctx, state, upstream, p)
+		results <- result{proxy: p, ret: ret, err: err}
+	}
+	go send(proxy)
+
+	timer := time.NewTimer(f.hedgeDelay)
+	defer timer.Stop()
+
+	pending := 1
+	hedged := false
+	var failed *result
+	for {
+		select {
+		case r := <-results:
+			pending--
+			if r.err == nil {
+				if r.proxy == next {
+					HedgeWinCount.WithLabelValues(next.addr).Add(1)
+				}
+				return r.ret, nil
+			}
+			if failed == nil {
+				failed = &r
+			}
+			// Without a hedged query in flight, the caller moves on to the next upstream.
+			if pending == 0 {
+				return failed.ret, failed.err
+			}
+		case <-timer.C:
+			if hedged || pending == 0 || !f.hedgeBudget.take() {
+				continue
+			}
+			hedged = true
+			pending++
+			HedgeCount.WithLabelValues(next.addr).Add(1)
+			go send(next)
+		}
+	}
+}
+
+// hedgeBudget limits the hedged queries to a percentage of all queries. Each query adds percent
+// credits, up to hedgeBurst hedged queries worth; a hedged query takes 100 credits.
+type hedgeBudget struct {
+	credits int64
+	percent int64
+}
+
+// add adds the credits of a query.
+func (b *hedgeBudget) add() {
+	for {
+		c := atomic.LoadInt64(&b.credits)
+		if c >= hedgeBurst*100 {
+			return
+		}
+		if atomic.CompareAndSwapInt64(&b.credits, c, c+b.percent) {
+			return
+		}
+	}
+}
+
+// take takes the credits of a hedged query, it returns false when there are not enough credits.
+func (b *hedgeBudget) take() bool {
+	for {
+		c := atomic.LoadInt64(&b.credits)
+		if c < 100 {
+			return false
+		}
+		if atomic.CompareAndSwapInt64(&b.credits, c, c-100) {
+			return true
+		}
+	}
+}
+
+const (
+	hedgeBurst   = 10 // maximum number of hedged queries the budget saves up
+	hedgePercent = 10 // default percentage of queries that may be hedged
+)
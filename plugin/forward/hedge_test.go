@@ -0,0 +1,100 @@
+package forward
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/coredns/coredns/plugin/pkg/dnstest"
+	"github.com/coredns/coredns/plugin/pkg/transport"
+	"github.com/coredns/coredns/plugin/test"
+
+	"github.com/miekg/dns"
+)
+
+// newAServer returns a UDP server that answers every query with an A record for ip after delay. Unlike
+// dnstest.NewServer it has its own handler, so servers with different replies can run at the same time.
+func newAServer(t *testing.T, ip string, delay time.Duration) *dns.Server {
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %s", err)
+	}
+	started := make(chan struct{})
+	s := &dns.Server{PacketConn: pc, NotifyStartedFunc: func() { close(started) }}
+	s.Handler = dns.HandlerFunc(func(w dns.ResponseWriter, r *dns.Msg) {
+		time.Sleep(delay)
+		ret := new(dns.Msg)
+		ret.SetReply(r)
+		ret.Answer = append(ret.Answer, test.A("example.org. IN A "+ip))
+		w.WriteMsg(ret)
+	})
+	go s.ActivateAndServe()
+	<-started
+	return s
+}
+
+func TestHedge(t *testing.T) {
+	slow := newAServer(t, "127.0.0.1", 500*time.Millisecond)
+	defer slow.Shutdown()
+	fast := newAServer(t, "127.0.0.2", 0)
+	defer fast.Shutdown()
+
+	f := New()
+	f.p = &sequential{}
+	f.hedgeDelay = 20 * time.Millisecond
+	f.hedgeBudget = &hedgeBudget{percent: 50, credits: 50}
+	f.SetProxy(NewProxy(slow.PacketConn.LocalAddr().String(), transport.DNS))
+	f.SetProxy(NewProxy(fast.PacketConn.LocalAddr().String(), transport.DNS))
+	defer f.Close()
+
+	m := new(dns.Msg)
+	m.SetQuestion("example.org.", dns.TypeA)
+	rec := dnstest.NewRecorder(&test.ResponseWriter{})
+	start := time.Now()
+	if _, err := f.ServeDNS(context.Background(), rec, m); err != nil {
+		t.Fatalf("Expected to receive reply, got %s", err)
+	}
+	if d := time.Since(start); d > 250*time.Millisecond {
+		t.Errorf("Expected the hedged reply, took %s", d)
+	}
+	if len(rec.Msg.Answer) == 0 || rec.Msg.Answer[0].(*dns.A).A.String() != "127.0.0.2" {
+		t.Errorf("Expected A record for 127.0.0.2, got: %s", rec.Msg)
+	}
+
+	// The budget is spent, the next query waits for the slow upstream.
+	rec = dnstest.NewRecorder(&test.ResponseWriter{})
+	if _, err := f.ServeDNS(context.Background(), rec, m); err != nil {
+		t.Fatalf("Expected to receive reply, got %s", err)
+	}
+	if len(rec.Msg.Answer) == 0 || rec.Msg.Answer[0].(*dns.A).A.String() != "127.0.0.1" {
+		t.Errorf("Expected A record for 127.0.0.1, got: %s", rec.Msg)
+	}
+}
+
+func TestHedgeBudget(t *testing.T) {
+	b := &hedgeBudget{percent: 25}
+	for i := 0; i < 4; i++ {
+		if b.take() {
+			t.Fatalf("Expected no hedge after %d queries", i)
+		}
+		b.add()
+	}
+	if !b.take() {
+		t.Errorf("Expected a hedge after 4 queries")
+	}
+	if b.take() {
+		t.Errorf("Expected no second hedge")
+	}
+
+	for i := 0; i < 100; i++ {
+		b.add()
+	}
+	n := 0
+	for b.take() {
+		n++
+	}
+	if n != hedgeBurst {
+		t.Errorf("Expected %d hedges, got %d", hedgeBurst, n)
+	}
+}
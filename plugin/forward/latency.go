@@ -0,0 +1,89 @@
+package forward
+
+import (
+	"math/rand"
+	"sort"
+	"sync/atomic"
+	"time"
+)
+
+// latency is a policy that selects the upstreams with the lowest score first, see Proxy.score.
+// Upstreams that haven't replied yet score 0 and are tried first. To notice when the score of
+// an upstream improves, one in latencyExplore lists starts with a random other upstream.
+type latency struct{}
+
+func (l *latency) String() string { return "latency" }
+
+func (l *latency) List(p []*Proxy) []*Proxy {
+	if len(p) == 1 {
+		return p
+	}
+
+	s := &byScore{p: make([]*Proxy, len(p)), score: make([]time.Duration, len(p))}
+	copy(s.p, p)
+	for i := range s.p {
+		s.score[i] = s.p[i].score()
+	}
+	sort.Stable(s)
+
+	if rand.Intn(latencyExplore) == 0 {
+		i := 1 + rand.Intn(len(s.p)-1)
+		s.p[0], s.p[i] = s.p[i], s.p[0]
+	}
+	return s.p
+}
+
+// byScore sorts proxies by the scores they had when the list was made.
+type byScore struct {
+	p     []*Proxy
+	score []time.Duration
+}
+
+func (s *byScore) Len() int           { return len(s.p) }
+func (s *byScore) Less(i, j int) bool { return s.score[i] < s.score[j] }
+func (s *byScore) Swap(i, j int) {
+	s.p[i], s.p[j] = s.p[j], s.p[i]
+	s.score[i], s.score[j] = s.score[j], s.score[i]
+}
+
+// observeExchange updates the averages of the round trip time and the error rate of p with the
+// outcome of an exchange that took rtt. The round trip time is only updated when there was a reply.
+func (p *Proxy) observeExchange(rtt time.Duration, err error) {
+	e := int64(0)
+	if err != nil {
+		e = errScale
+	} else {
+		ewma(&p.avgRtt, int64(rtt))
+	}
+	atomic.AddInt64(&p.avgErr, (e-atomic.LoadInt64(&p.avgErr))/latencyWeight)
+
+	UpstreamRtt.WithLabelValues(p.addr).Set(time.Duration(atomic.LoadInt64(&p.avgRtt)).Seconds())
+	UpstreamErrors.WithLabelValues(p.addr).Set(p.errRate())
+	UpstreamScore.WithLabelValues(p.addr).Set(p.score().Seconds())
+}
+
+// errRate returns the average error rate of p, between 0 and 1.
+func (p *Proxy) errRate() float64 { return float64(atomic.LoadInt64(&p.avgErr)) / errScale }
+
+// score returns the average round trip time of p, plus a penalty of errPenalty times its error rate.
+func (p *Proxy) score() time.Duration {
+	rtt := time.Duration(atomic.LoadInt64(&p.avgRtt))
+	return rtt + time.Duration(p.errRate()*float64(errPenalty))
+}
+
+// ewma moves the average in avg a 1/latencyWeight step towards v. The first value is taken as is, so
+// an upstream doesn't start out as the fastest.
+func ewma(avg *int64, v int64) {
+	if atomic.CompareAndSwapInt64(avg, 0, v) {
+		return
+	}
+	a := atomic.LoadInt64(avg)
+	atomic.AddInt64(avg, (v-a)/latencyWeight)
+}
+
+const (
+	latencyWeight  = 8
+	latencyExplore = 50              // one in latencyExplore lists starts with a random upstream
+	errScale       = 1000000         // avgErr holds the error rate times errScale
+	errPenalty     = 2 * time.Second // added to the score of an upstream that always fails
+)
@@ -0,0 +1,70 @@
+package forward
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/coredns/coredns/plugin/pkg/transport"
+)
+
+func TestLatencyList(t *testing.T) {
+	slow := NewProxy("10.0.0.1:53", transport.DNS)
+	fast := NewProxy("10.0.0.2:53", transport.DNS)
+	failing := NewProxy("10.0.0.3:53", transport.DNS)
+	slow.observeExchange(80*time.Millisecond, nil)
+	fast.observeExchange(5*time.Millisecond, nil)
+	failing.observeExchange(time.Millisecond, nil)
+	for i := 0; i < 10; i++ {
+		failing.observeExchange(0, errors.New("timeout"))
+	}
+
+	l := &latency{}
+	first := map[*Proxy]int{}
+	for i := 0; i < 1000; i++ {
+		list := l.List([]*Proxy{slow, failing, fast})
+		first[list[0]]++
+	}
+	if first[fast] < 900 {
+		t.Errorf("Expected the fast upstream first in most lists, got %d of 1000", first[fast])
+	}
+	if first[slow] == 0 || first[failing] == 0 {
+		t.Errorf("Expected the other upstreams first in some lists, got %d and %d", first[slow], first[failing])
+	}
+
+	// An upstream that hasn't replied yet is tried first.
+	fresh := NewProxy("10.0.0.4:53", transport.DNS)
+	n := 0
+	for i := 0; i < 100; i++ {
+		if l.List([]*Proxy{fast, fresh})[0] == fresh {
+			n++
+		}
+	}
+	if n < 90 {
+		t.Errorf("Expected the new upstream first in most lists, got %d of 100", n)
+	}
+	if s := fresh.score(); s != 0 {
+		t.Errorf("Expected score 0 for a new upstream, got %s", s)
+	}
+}
+
+func TestObserveExchange(t *testing.T) {
+	p := NewProxy("10.0.0.1:53", transport.DNS)
+
+	p.observeExchange(40*time.Millisecond, nil)
+	if s := p.score(); s != 40*time.Millisecond {
+		t.Errorf("Expected the first round trip time as score, got %s", s)
+	}
+	p.observeExchange(120*time.Millisecond, nil)
+	if s := p.score(); s != 50*time.Millisecond {
+		t.Errorf("Expected score %s, got %s", 50*time.Millisecond, s)
+	}
+
+	p.observeExchange(0, errors.New("timeout"))
+	if r := p.errRate(); r != 0.125 {
+		t.Errorf("Expected error rate %f, got %f", 0.125, r)
+	}
+	if s := p.score(); s != 50*time.Millisecond+errPenalty/8 {
+		t.Errorf("Expected score %s, got %s", 50*time.Millisecond+errPenalty/8, s)
+	}
+}
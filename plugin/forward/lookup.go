@@ -6,6 +6,7 @@ package forward
 
 import (
 	"context"
+	"time"
 
 	"github.com/coredns/coredns/plugin/pkg/transport"
 	"github.com/coredns/coredns/request"
@@ -14,12 +15,18 @@ import (
 )
 
 // Forward forward the request in state as-is. Unlike Lookup that adds EDNS0 suffix to the message.
-// Forward may be called with a nil f, an error is returned in that case.
+// Forward may be called with a nil f, an error is returned in that case. It gives up once the
+// context of state, if any, is canceled.
 func (f *Forward) Forward(state request.Request) (*dns.Msg, error) {
 	if f == nil {
 		return nil, ErrNoForward
 	}
 
+	ctx := state.Context
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
 	fails := 0
 	var upstreamErr error
 	for _, proxy := range f.List() {
@@ -33,11 +40,17 @@ func (f *Forward) Forward(state request.Request) (*dns.Msg, error) {
 			proxy = f.List()[0]
 		}
 
-		ret, err := proxy.Connect(context.Background(), state, f.opts)
+		start := time.Now()
+		ret, err := proxy.Connect(ctx, state, f.opts)
 
 		ret, err = truncated(state, ret, err)
 		upstreamErr = err
 
+		if err := contextErr(ctx); err != nil {
+			return nil, err
+		}
+		proxy.observeExchange(time.Since(start), err)
+
 		if err != nil {
 			if fails < len(f.proxies) {
 				continue
@@ -72,7 +85,7 @@ func (f *Forward) Lookup(state request.Request, name string, typ uint16) (*dns.M
 	req.SetQuestion(name, typ)
 	state.SizeAndDo(req)
 
-	state2 := request.Request{W: state.W, Req: req}
+	state2 := request.Request{W: state.W, Req: req, Context: state.Context}
 
 	return f.Forward(state2)
 }
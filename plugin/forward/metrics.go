@@ -39,10 +39,46 @@ var (
 		Name:      "healthcheck_broken_count_total",
 		Help:      "Counter of the number of complete failures of the healtchecks.",
 	})
+	MaxConcurrentRejectCount = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: plugin.Namespace,
+		Subsystem: "forward",
+		Name:      "max_concurrent_reject_count_total",
+		Help:      "Counter of the number of queries rejected because all upstreams had too many queries in flight.",
+	})
 	SocketGauge = prometheus.NewGaugeVec(prometheus.GaugeOpts{
 		Namespace: plugin.Namespace,
 		Subsystem: "forward",
 		Name:      "sockets_open",
 		Help:      "Gauge of open sockets per upstream.",
 	}, []string{"to"})
+	HedgeCount = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: plugin.Namespace,
+		Subsystem: "forward",
+		Name:      "hedge_count_total",
+		Help:      "Counter of hedged requests made per upstream.",
+	}, []string{"to"})
+	HedgeWinCount = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: plugin.Namespace,
+		Subsystem: "forward",
+		Name:      "hedge_win_count_total",
+		Help:      "Counter of hedged requests that were answered first per upstream.",
+	}, []string{"to"})
+	UpstreamRtt = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: plugin.Namespace,
+		Subsystem: "forward",
+		Name:      "upstream_rtt_seconds",
+		Help:      "Gauge of the moving average of the round trip time per upstream.",
+	}, []string{"to"})
+	UpstreamErrors = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: plugin.Namespace,
+		Subsystem: "forward",
+		Name:      "upstream_error_ratio",
+		Help:      "Gauge of the moving average of the error rate per upstream.",
+	}, []string{"to"})
+	UpstreamScore = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: plugin.Namespace,
+		Subsystem: "forward",
+		Name:      "upstream_score_seconds",
+		Help:      "Gauge of the score the latency policy uses to order the upstreams.",
+	}, []string{"to"})
 )
@@ -6,11 +6,19 @@ import (
 	"sync/atomic"
 	"time"
 
+	"github.com/coredns/coredns/plugin/pkg/transport"
 	"github.com/coredns/coredns/plugin/pkg/up"
 )
 
 // Proxy defines an upstream host.
 type Proxy struct {
+	avgRtt int64 // average round trip time in nanoseconds, 0 until the first reply
+	avgErr int64 // average error rate, times errScale
+
+	inflight      int64  // queries in flight
+	maxConcurrent int64  // maximum number of queries in flight, 0 is no limit
+	limited       uint32 // 1 when a query was rejected since the upstream last had room
+
 	fails uint32
 
 	addr string
@@ -19,18 +27,35 @@ type Proxy struct {
 	expire    time.Duration
 	transport *Transport
 
+	// doh replaces transport for DNS-over-HTTPS upstreams, nil otherwise.
+	doh *dohTransport
+	// grpc replaces transport for gRPC upstreams, nil otherwise.
+	grpc *grpcTransport
+
+	cookies *cookies
+
 	// health checking
 	probe  *up.Probe
 	health HealthChecker
 }
 
-// NewProxy returns a new proxy.
+// NewProxy returns a new proxy. For DNS-over-HTTPS addr is the URL of the upstream without the
+// https:// prefix. The address of DNS-over-HTTPS and gRPC proxies includes the scheme.
 func NewProxy(addr, trans string) *Proxy {
 	p := &Proxy{
 		addr:      addr,
 		fails:     0,
 		probe:     up.New(),
 		transport: newTransport(addr),
+		cookies:   newCookies(),
+	}
+	if trans == transport.HTTPS {
+		p.addr = transport.HTTPS + "://" + addr
+		p.doh = newDoHTransport(p.addr)
+	}
+	if trans == transport.GRPC {
+		p.addr = transport.GRPC + "://" + addr
+		p.grpc = newGRPCTransport(addr)
 	}
 	p.health = NewHealthChecker(trans)
 	runtime.SetFinalizer(p, (*Proxy).finalizer)
@@ -39,12 +64,54 @@ func NewProxy(addr, trans string) *Proxy {
 
 // SetTLSConfig sets the TLS config in the lower p.transport and in the healthchecking client.
 func (p *Proxy) SetTLSConfig(cfg *tls.Config) {
+	if p.doh != nil {
+		p.doh.SetTLSConfig(cfg)
+	}
+	if p.grpc != nil {
+		p.grpc.SetTLSConfig(cfg)
+	}
 	p.transport.SetTLSConfig(cfg)
 	p.health.SetTLSConfig(cfg)
 }
 
 // SetExpire sets the expire duration in the lower p.transport.
-func (p *Proxy) SetExpire(expire time.Duration) { p.transport.SetExpire(expire) }
+func (p *Proxy) SetExpire(expire time.Duration) {
+	if p.doh != nil {
+		p.doh.SetExpire(expire)
+	}
+	p.transport.SetExpire(expire)
+}
+
+// SetMaxConcurrent sets the maximum number of queries in flight to the upstream, 0 is no limit.
+func (p *Proxy) SetMaxConcurrent(n int64) { p.maxConcurrent = n }
+
+// acquire reserves a slot for a query to the upstream. It returns false when the maximum number of
+// queries are in flight already.
+func (p *Proxy) acquire() bool {
+	if p.maxConcurrent == 0 {
+		return true
+	}
+	if atomic.AddInt64(&p.inflight, 1) > p.maxConcurrent {
+		atomic.AddInt64(&p.inflight, -1)
+		return false
+	}
+	if atomic.LoadUint32(&p.limited) == 1 {
+		atomic.StoreUint32(&p.limited, 0)
+	}
+	return true
+}
+
+// release frees the slot reserved by acquire.
+func (p *Proxy) release() {
+	if p.maxConcurrent > 0 {
+		atomic.AddInt64(&p.inflight, -1)
+	}
+}
+
+// full returns true if the maximum number of queries are in flight to the upstream.
+func (p *Proxy) full() bool {
+	return p.maxConcurrent > 0 && atomic.LoadInt64(&p.inflight) >= p.maxConcurrent
+}
 
 // Healthcheck kicks of a round of health checks for this proxy.
 func (p *Proxy) Healthcheck() {
@@ -68,8 +135,23 @@ func (p *Proxy) Down(maxfails uint32) bool {
 	return fails > maxfails
 }
 
-// close stops the health checking goroutine.
-func (p *Proxy) close()     { p.probe.Stop() }
+// Addr returns the address of the upstream.
+func (p *Proxy) Addr() string { return p.addr }
+
+// Fails returns the number of subsequent failed health checks.
+func (p *Proxy) Fails() uint32 { return atomic.LoadUint32(&p.fails) }
+
+// close stops the health checking goroutine, and closes the connections of DoH and gRPC upstreams.
+func (p *Proxy) close() {
+	p.probe.Stop()
+	if p.doh != nil {
+		p.doh.close()
+	}
+	if p.grpc != nil {
+		p.grpc.close()
+	}
+}
+
 func (p *Proxy) finalizer() { p.transport.Stop() }
 
 // start starts the proxy's healthchecking.
@@ -2,18 +2,22 @@ package forward
 
 import (
 	"fmt"
+	"net/url"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/coredns/coredns/core/dnsserver"
 	"github.com/coredns/coredns/plugin"
 	"github.com/coredns/coredns/plugin/metrics"
+	"github.com/coredns/coredns/plugin/pkg/doh"
 	"github.com/coredns/coredns/plugin/pkg/parse"
 	pkgtls "github.com/coredns/coredns/plugin/pkg/tls"
 	"github.com/coredns/coredns/plugin/pkg/transport"
 
 	"github.com/mholt/caddy"
 	"github.com/mholt/caddy/caddyfile"
+	"github.com/miekg/dns"
 )
 
 func init() {
@@ -38,7 +42,9 @@ func setup(c *caddy.Controller) error {
 	})
 
 	c.OnStartup(func() error {
-		metrics.MustRegister(c, RequestCount, RcodeCount, RequestDuration, HealthcheckFailureCount, SocketGauge)
+		metrics.MustRegister(c, RequestCount, RcodeCount, RequestDuration, HealthcheckFailureCount, SocketGauge,
+			UpstreamRtt, UpstreamErrors, UpstreamScore, HedgeCount, HedgeWinCount,
+			MaxConcurrentRejectCount)
 		return f.OnStartup()
 	})
 
@@ -101,9 +107,21 @@ func ParseForwardStanza(c *caddyfile.Dispenser) (*Forward, error) {
 		return f, c.ArgErr()
 	}
 
-	toHosts, err := parse.HostPortOrFile(to...)
-	if err != nil {
-		return f, err
+	var toHosts []string
+	for _, h := range to {
+		if trans, _ := parse.Transport(h); trans == transport.HTTPS {
+			u, err := dohURL(h)
+			if err != nil {
+				return f, err
+			}
+			toHosts = append(toHosts, u)
+			continue
+		}
+		hosts, err := parse.HostPortOrFile(h)
+		if err != nil {
+			return f, err
+		}
+		toHosts = append(toHosts, hosts...)
 	}
 
 	transports := make([]string, len(toHosts))
@@ -125,10 +143,16 @@ func ParseForwardStanza(c *caddyfile.Dispenser) (*Forward, error) {
 	}
 	for i := range f.proxies {
 		// Only set this for proxies that need it.
-		if transports[i] == transport.TLS {
+		switch transports[i] {
+		case transport.TLS, transport.HTTPS:
 			f.proxies[i].SetTLSConfig(f.tlsConfig)
+		case transport.GRPC:
+			if f.tlsSet {
+				f.proxies[i].SetTLSConfig(f.tlsConfig)
+			}
 		}
 		f.proxies[i].SetExpire(f.expire)
+		f.proxies[i].SetMaxConcurrent(f.maxConcurrent)
 	}
 	return f, nil
 }
@@ -189,11 +213,13 @@ func parseBlock(c *caddyfile.Dispenser, f *Forward) error {
 			return err
 		}
 		f.tlsConfig = tlsConfig
+		f.tlsSet = true
 	case "tls_servername":
 		if !c.NextArg() {
 			return c.ArgErr()
 		}
 		f.tlsServerName = c.Val()
+		f.tlsSet = true
 	case "expire":
 		if !c.NextArg() {
 			return c.ArgErr()
@@ -206,6 +232,78 @@ func parseBlock(c *caddyfile.Dispenser, f *Forward) error {
 			return fmt.Errorf("expire can't be negative: %s", dur)
 		}
 		f.expire = dur
+	case "max_concurrent":
+		args := c.RemainingArgs()
+		if len(args) == 0 || len(args) > 2 {
+			return c.ArgErr()
+		}
+		n, err := strconv.Atoi(args[0])
+		if err != nil {
+			return err
+		}
+		if n <= 0 {
+			return fmt.Errorf("max_concurrent must be positive: %d", n)
+		}
+		f.maxConcurrent = int64(n)
+		if len(args) > 1 {
+			switch args[1] {
+			case "servfail":
+				f.maxConcurrentRcode = dns.RcodeServerFailure
+			case "refused":
+				f.maxConcurrentRcode = dns.RcodeRefused
+			default:
+				return c.Errf("unknown max_concurrent reply '%s'", args[1])
+			}
+		}
+	case "hedge":
+		args := c.RemainingArgs()
+		if len(args) == 0 || len(args) > 2 {
+			return c.ArgErr()
+		}
+		dur, err := time.ParseDuration(args[0])
+		if err != nil {
+			return err
+		}
+		if dur <= 0 {
+			return fmt.Errorf("hedge delay must be positive: %s", dur)
+		}
+		percent := hedgePercent
+		if len(args) > 1 {
+			percent, err = strconv.Atoi(strings.TrimSuffix(args[1], "%"))
+			if err != nil || percent < 1 || percent > 100 {
+				return c.Errf("invalid hedge percentage '%s'", args[1])
+			}
+		}
+		f.hedgeDelay = dur
+		f.hedgeBudget = &hedgeBudget{percent: int64(percent)}
+	case "ecs":
+		args := c.RemainingArgs()
+		if len(args) == 0 || len(args) > 3 {
+			return c.ArgErr()
+		}
+		switch args[0] {
+		case "add":
+			f.ecs = ecsAdd
+		case "passthrough":
+			f.ecs = ecsPassthrough
+		default:
+			return c.Errf("unknown ecs mode '%s'", args[0])
+		}
+		f.ecsV4, f.ecsV6 = defaultECSv4, defaultECSv6
+		if len(args) > 1 {
+			n, err := strconv.Atoi(args[1])
+			if err != nil || n < 0 || n > 32 {
+				return c.Errf("invalid IPv4 prefix length '%s'", args[1])
+			}
+			f.ecsV4 = uint8(n)
+		}
+		if len(args) > 2 {
+			n, err := strconv.Atoi(args[2])
+			if err != nil || n < 0 || n > 128 {
+				return c.Errf("invalid IPv6 prefix length '%s'", args[2])
+			}
+			f.ecsV6 = uint8(n)
+		}
 	case "policy":
 		if !c.NextArg() {
 			return c.ArgErr()
@@ -217,6 +315,8 @@ func parseBlock(c *caddyfile.Dispenser, f *Forward) error {
 			f.p = &roundRobin{}
 		case "sequential":
 			f.p = &sequential{}
+		case "latency":
+			f.p = &latency{}
 		default:
 			return c.Errf("unknown policy '%s'", x)
 		}
@@ -228,4 +328,19 @@ func parseBlock(c *caddyfile.Dispenser, f *Forward) error {
 	return nil
 }
 
+// dohURL checks the DNS-over-HTTPS URL s, and adds the default path when it has none.
+func dohURL(s string) (string, error) {
+	u, err := url.Parse(s)
+	if err != nil {
+		return "", err
+	}
+	if u.Host == "" || u.User != nil || u.Fragment != "" {
+		return "", fmt.Errorf("invalid DNS-over-HTTPS URL: %q", s)
+	}
+	if u.Path == "" {
+		u.Path = doh.Path
+	}
+	return u.String(), nil
+}
+
 const max = 15 // Maximum number of upstreams.
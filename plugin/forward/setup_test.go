@@ -6,8 +6,10 @@ import (
 	"reflect"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/mholt/caddy"
+	"github.com/miekg/dns"
 )
 
 func TestSetup(t *testing.T) {
@@ -73,6 +75,128 @@ func TestSetup(t *testing.T) {
 	}
 }
 
+func TestSetupECS(t *testing.T) {
+	tests := []struct {
+		input     string
+		shouldErr bool
+		mode      ecsMode
+		v4, v6    uint8
+	}{
+		{"forward . 127.0.0.1", false, ecsNone, 0, 0},
+		{"forward . 127.0.0.1 {\necs add\n}\n", false, ecsAdd, 24, 56},
+		{"forward . 127.0.0.1 {\necs passthrough 20\n}\n", false, ecsPassthrough, 20, 56},
+		{"forward . 127.0.0.1 {\necs add 32 64\n}\n", false, ecsAdd, 32, 64},
+		// negative
+		{"forward . 127.0.0.1 {\necs\n}\n", true, ecsNone, 0, 0},
+		{"forward . 127.0.0.1 {\necs strip\n}\n", true, ecsNone, 0, 0},
+		{"forward . 127.0.0.1 {\necs add 33\n}\n", true, ecsNone, 0, 0},
+		{"forward . 127.0.0.1 {\necs add 24 129\n}\n", true, ecsNone, 0, 0},
+		{"forward . 127.0.0.1 {\necs add 24 56 0\n}\n", true, ecsNone, 0, 0},
+	}
+
+	for i, test := range tests {
+		c := caddy.NewTestController("dns", test.input)
+		f, err := parseForward(c)
+
+		if test.shouldErr {
+			if err == nil {
+				t.Errorf("Test %d: expected error but found none for input %s", i, test.input)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Test %d: expected no error but found one for input %s, got: %v", i, test.input, err)
+			continue
+		}
+		if f.ecs != test.mode || f.ecsV4 != test.v4 || f.ecsV6 != test.v6 {
+			t.Errorf("Test %d: expected ecs %d %d %d, got %d %d %d", i, test.mode, test.v4, test.v6, f.ecs, f.ecsV4, f.ecsV6)
+		}
+	}
+}
+
+func TestSetupHedge(t *testing.T) {
+	tests := []struct {
+		input     string
+		shouldErr bool
+		delay     time.Duration
+		percent   int64
+	}{
+		{"forward . 127.0.0.1", false, 0, 0},
+		{"forward . 127.0.0.1 {\nhedge 50ms\n}\n", false, 50 * time.Millisecond, 10},
+		{"forward . 127.0.0.1 {\nhedge 20ms 5\n}\n", false, 20 * time.Millisecond, 5},
+		{"forward . 127.0.0.1 {\nhedge 20ms 5%\n}\n", false, 20 * time.Millisecond, 5},
+		// negative
+		{"forward . 127.0.0.1 {\nhedge\n}\n", true, 0, 0},
+		{"forward . 127.0.0.1 {\nhedge 0s\n}\n", true, 0, 0},
+		{"forward . 127.0.0.1 {\nhedge 20ms 0\n}\n", true, 0, 0},
+		{"forward . 127.0.0.1 {\nhedge 20ms 101\n}\n", true, 0, 0},
+		{"forward . 127.0.0.1 {\nhedge 20ms 5 5\n}\n", true, 0, 0},
+	}
+
+	for i, test := range tests {
+		c := caddy.NewTestController("dns", test.input)
+		f, err := parseForward(c)
+
+		if test.shouldErr {
+			if err == nil {
+				t.Errorf("Test %d: expected error but found none for input %s", i, test.input)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Test %d: expected no error but found one for input %s, got: %v", i, test.input, err)
+			continue
+		}
+		if f.hedgeDelay != test.delay {
+			t.Errorf("Test %d: expected hedge delay %s, got %s", i, test.delay, f.hedgeDelay)
+		}
+		if test.delay > 0 && f.hedgeBudget.percent != test.percent {
+			t.Errorf("Test %d: expected hedge percentage %d, got %d", i, test.percent, f.hedgeBudget.percent)
+		}
+	}
+}
+
+func TestSetupMaxConcurrent(t *testing.T) {
+	tests := []struct {
+		input     string
+		shouldErr bool
+		max       int64
+		rcode     int
+	}{
+		{"forward . 127.0.0.1", false, 0, dns.RcodeServerFailure},
+		{"forward . 127.0.0.1 {\nmax_concurrent 100\n}\n", false, 100, dns.RcodeServerFailure},
+		{"forward . 127.0.0.1 {\nmax_concurrent 100 refused\n}\n", false, 100, dns.RcodeRefused},
+		{"forward . 127.0.0.1 {\nmax_concurrent 100 servfail\n}\n", false, 100, dns.RcodeServerFailure},
+		// negative
+		{"forward . 127.0.0.1 {\nmax_concurrent\n}\n", true, 0, 0},
+		{"forward . 127.0.0.1 {\nmax_concurrent 0\n}\n", true, 0, 0},
+		{"forward . 127.0.0.1 {\nmax_concurrent many\n}\n", true, 0, 0},
+		{"forward . 127.0.0.1 {\nmax_concurrent 100 drop\n}\n", true, 0, 0},
+	}
+
+	for i, test := range tests {
+		c := caddy.NewTestController("dns", test.input)
+		f, err := parseForward(c)
+
+		if test.shouldErr {
+			if err == nil {
+				t.Errorf("Test %d: expected error but found none for input %s", i, test.input)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Test %d: expected no error but found one for input %s, got: %v", i, test.input, err)
+			continue
+		}
+		if f.maxConcurrentRcode != test.rcode {
+			t.Errorf("Test %d: expected reply %d, got %d", i, test.rcode, f.maxConcurrentRcode)
+		}
+		if x := f.proxies[0].maxConcurrent; x != test.max {
+			t.Errorf("Test %d: expected max_concurrent %d, got %d", i, test.max, x)
+		}
+	}
+}
+
 func TestSetupTLS(t *testing.T) {
 	tests := []struct {
 		input              string
@@ -172,3 +296,88 @@ nameserver 10.10.255.253`), 0666); err != nil {
 		}
 	}
 }
+
+func TestSetupDoH(t *testing.T) {
+	tests := []struct {
+		input     string
+		shouldErr bool
+		expected  []string
+	}{
+		{"forward . https://dns.example.org", false, []string{"https://dns.example.org/dns-query"}},
+		{"forward . https://dns.example.org:8443/resolve", false, []string{"https://dns.example.org:8443/resolve"}},
+		{"forward . https://dns.example.org 127.0.0.1", false, []string{"https://dns.example.org/dns-query", "127.0.0.1:53"}},
+		// negative
+		{"forward . https:///dns-query", true, nil},
+		{"forward . https://user@dns.example.org", true, nil},
+	}
+
+	for i, test := range tests {
+		c := caddy.NewTestController("dns", test.input)
+		f, err := parseForward(c)
+		if test.shouldErr {
+			if err == nil {
+				t.Errorf("Test %d: expected error but found none for input %s", i, test.input)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Test %d: expected no error but found one for input %s, got: %v", i, test.input, err)
+			continue
+		}
+		var addrs []string
+		for _, p := range f.proxies {
+			addrs = append(addrs, p.Addr())
+		}
+		if !reflect.DeepEqual(addrs, test.expected) {
+			t.Errorf("Test %d: expected %v, got %v", i, test.expected, addrs)
+		}
+		if p := f.proxies[0]; p.doh == nil {
+			t.Errorf("Test %d: expected a DNS-over-HTTPS transport", i)
+		}
+	}
+}
+
+func TestSetupGRPC(t *testing.T) {
+	tests := []struct {
+		input     string
+		shouldErr bool
+		expected  []string
+		tls       bool
+	}{
+		{"forward . grpc://10.0.0.1", false, []string{"grpc://10.0.0.1:443"}, false},
+		{"forward . grpc://10.0.0.1:8053 127.0.0.1", false, []string{"grpc://10.0.0.1:8053", "127.0.0.1:53"}, false},
+		{"forward . grpc://10.0.0.1 {\ntls_servername dns.example.org\n}", false, []string{"grpc://10.0.0.1:443"}, true},
+		{"forward . grpc://10.0.0.1 {\ntls\n}", false, []string{"grpc://10.0.0.1:443"}, true},
+		// negative
+		{"forward . grpc://dns.example.org", true, nil, false},
+	}
+
+	for i, test := range tests {
+		c := caddy.NewTestController("dns", test.input)
+		f, err := parseForward(c)
+		if test.shouldErr {
+			if err == nil {
+				t.Errorf("Test %d: expected error but found none for input %s", i, test.input)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Test %d: expected no error but found one for input %s, got: %v", i, test.input, err)
+			continue
+		}
+		var addrs []string
+		for _, p := range f.proxies {
+			addrs = append(addrs, p.Addr())
+		}
+		if !reflect.DeepEqual(addrs, test.expected) {
+			t.Errorf("Test %d: expected %v, got %v", i, test.expected, addrs)
+		}
+		p := f.proxies[0]
+		if p.grpc == nil {
+			t.Fatalf("Test %d: expected a gRPC transport", i)
+		}
+		if tls := p.grpc.creds != nil; tls != test.tls {
+			t.Errorf("Test %d: expected TLS %t, got %t", i, test.tls, tls)
+		}
+	}
+}
@@ -17,6 +17,7 @@ import (
 	"time"
 
 	"github.com/coredns/coredns/plugin"
+	"github.com/coredns/coredns/plugin/pkg/watch"
 )
 
 func parseLiteralIP(addr string) net.IP {
@@ -90,6 +91,9 @@ type Hostsfile struct {
 	// mtime and size are only read and modified by a single goroutine
 	mtime time.Time
 	size  int64
+
+	// watched names, these are notified when their addresses change
+	watched watch.Names
 }
 
 // readHosts determines if the cached data needs to be updated based on the size and modification time of the hostsfile.
@@ -111,12 +115,17 @@ func (h *Hostsfile) readHosts() {
 
 	h.Lock()
 
+	changed := h.watchedChanges(h.hmap, newMap)
 	h.hmap = newMap
 	// Update the data cache.
 	h.mtime = stat.ModTime()
 	h.size = stat.Size()
 
 	h.Unlock()
+
+	for _, name := range changed {
+		h.watched.Changed(name)
+	}
 }
 
 func (h *Hostsfile) initInline(inline []string) {
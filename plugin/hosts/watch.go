@@ -0,0 +1,67 @@
+package hosts
+
+import (
+	"net"
+
+	"github.com/coredns/coredns/plugin"
+	"github.com/coredns/coredns/plugin/pkg/dnsutil"
+	"github.com/coredns/coredns/plugin/pkg/watch"
+)
+
+// SetWatchChan implements watch.Watchable.
+func (h Hosts) SetWatchChan(c watch.Chan) { h.watched.SetWatchChan(c) }
+
+// Watch implements watch.Watchable.
+func (h Hosts) Watch(qname string) error {
+	if plugin.Zones(h.Origins).Matches(qname) == "" {
+		return nil
+	}
+	return h.watched.Watch(qname)
+}
+
+// StopWatching implements watch.Watchable.
+func (h Hosts) StopWatching(qname string) { h.watched.StopWatching(qname) }
+
+var _ watch.Watchable = Hosts{}
+
+// watchedChanges returns the watched names that resolve differently in m1 than in m, both forward
+// and reverse names are checked.
+func (h *Hostsfile) watchedChanges(m, m1 *hostsMap) []string {
+	var changed []string
+	for _, name := range h.watched.Names() {
+		if addr := dnsutil.ExtractAddressFromReverse(name); addr != "" {
+			if ip := net.ParseIP(addr); ip != nil && !equalNames(m.byAddr[ip.String()], m1.byAddr[ip.String()]) {
+				changed = append(changed, name)
+			}
+			continue
+		}
+		if !equalIPs(m.byNameV4[name], m1.byNameV4[name]) || !equalIPs(m.byNameV6[name], m1.byNameV6[name]) {
+			changed = append(changed, name)
+		}
+	}
+	return changed
+}
+
+func equalIPs(a, b []net.IP) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if !a[i].Equal(b[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+func equalNames(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
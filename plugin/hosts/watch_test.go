@@ -0,0 +1,27 @@
+package hosts
+
+import (
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/coredns/coredns/plugin/pkg/watch"
+)
+
+func TestWatchedChanges(t *testing.T) {
+	h := &Hostsfile{Origins: []string{"."}}
+	h.watched.SetWatchChan(make(watch.Chan))
+	for _, name := range []string{"a.example.org.", "b.example.org.", "c.example.org.", "1.0.0.127.in-addr.arpa.", "2.0.0.127.in-addr.arpa."} {
+		h.watched.Watch(name)
+	}
+
+	m := h.parse(strings.NewReader("127.0.0.1 a.example.org\n127.0.0.2 b.example.org\n::1 c.example.org\n"), nil)
+	m1 := h.parse(strings.NewReader("127.0.0.1 a.example.org\n127.0.0.3 b.example.org\n::2 c.example.org\n"), nil)
+
+	changed := h.watchedChanges(m, m1)
+	sort.Strings(changed)
+	expected := []string{"2.0.0.127.in-addr.arpa.", "b.example.org.", "c.example.org."}
+	if strings.Join(changed, " ") != strings.Join(expected, " ") {
+		t.Errorf("Expected changes for %v, got %v", expected, changed)
+	}
+}
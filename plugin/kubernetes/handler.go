@@ -4,6 +4,7 @@ import (
 	"context"
 
 	"github.com/coredns/coredns/plugin"
+	"github.com/coredns/coredns/plugin/pkg/ede"
 	"github.com/coredns/coredns/request"
 
 	"github.com/miekg/dns"
@@ -67,11 +68,13 @@ func (k Kubernetes) ServeDNS(ctx context.Context, w dns.ResponseWriter, r *dns.M
 		}
 		if !k.APIConn.HasSynced() {
 			// If we haven't synchronized with the kubernetes cluster, return server failure
+			ede.Set(ctx, ede.NotReady, "kubernetes API not synced")
 			return plugin.BackendError(&k, zone, dns.RcodeServerFailure, state, nil /* err */, opt)
 		}
 		return plugin.BackendError(&k, zone, dns.RcodeNameError, state, nil /* err */, opt)
 	}
 	if err != nil {
+		ede.Set(ctx, ede.Other, "kubernetes API lookup failed")
 		return dns.RcodeServerFailure, err
 	}
 
@@ -7,6 +7,7 @@ import (
 	"strings"
 	"testing"
 
+	"github.com/coredns/coredns/plugin"
 	"github.com/coredns/coredns/plugin/pkg/dnstest"
 	clog "github.com/coredns/coredns/plugin/pkg/log"
 	"github.com/coredns/coredns/plugin/pkg/response"
@@ -32,7 +33,7 @@ func TestLoggedStatus(t *testing.T) {
 		Next:  test.ErrorHandler(),
 	}
 
-	ctx := context.TODO()
+	ctx := context.WithValue(context.TODO(), plugin.ServerCtx{}, "dns://:53")
 	r := new(dns.Msg)
 	r.SetQuestion("example.org.", dns.TypeA)
 
@@ -47,6 +48,9 @@ func TestLoggedStatus(t *testing.T) {
 	if !strings.Contains(logged, "A IN example.org. udp 29 false 512") {
 		t.Errorf("Expected it to be logged. Logged string: %s", logged)
 	}
+	if !strings.Contains(logged, "server=dns://:53") {
+		t.Errorf("Expected the server to be logged. Logged string: %s", logged)
+	}
 }
 
 func TestLoggedClassDenial(t *testing.T) {
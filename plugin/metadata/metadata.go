@@ -23,17 +23,26 @@ func (m *Metadata) Name() string { return "metadata" }
 // ServeDNS implements the plugin.Handler interface.
 func (m *Metadata) ServeDNS(ctx context.Context, w dns.ResponseWriter, r *dns.Msg) (int, error) {
 
+	// The server normally has collected the metadata already, see Collect.
+	if ctx.Value(key{}) == nil {
+		ctx = m.Collect(ctx, request.Request{W: w, Req: r})
+	}
+
+	rcode, err := plugin.NextOrFailure(m.Name(), m.Next, ctx, w, r)
+
+	return rcode, err
+}
+
+// Collect implements the dnsserver.MetadataCollector interface. It goes through all Providers and
+// collects their metadata in a new context. The server calls this before a server block is
+// selected so views can use the metadata.
+func (m *Metadata) Collect(ctx context.Context, state request.Request) context.Context {
 	ctx = context.WithValue(ctx, key{}, md{})
 
-	state := request.Request{W: w, Req: r}
 	if plugin.Zones(m.Zones).Matches(state.Name()) != "" {
-		// Go through all Providers and collect metadata.
 		for _, p := range m.Providers {
 			ctx = p.Metadata(ctx, state)
 		}
 	}
-
-	rcode, err := plugin.NextOrFailure(m.Name(), m.Next, ctx, w, r)
-
-	return rcode, err
+	return ctx
 }
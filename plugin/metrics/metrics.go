@@ -52,6 +52,13 @@ func New(addr string) *Metrics {
 	met.MustRegister(vars.RequestType)
 	met.MustRegister(vars.ResponseSize)
 	met.MustRegister(vars.ResponseRcode)
+	met.MustRegister(vars.RequestTimeouts)
+	met.MustRegister(vars.TCPConnections)
+	met.MustRegister(vars.TCPConnectionsRejected)
+	met.MustRegister(vars.TCPPipelinedQueries)
+	met.MustRegister(vars.TCPTimeouts)
+	met.MustRegister(vars.UDPDrops)
+	met.MustRegister(vars.SocketBuffer)
 
 	return met
 }
@@ -60,6 +60,55 @@ var (
 		Help:      "Counter of response status codes.",
 	}, []string{"server", "zone", "rcode"})
 
+	RequestTimeouts = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: plugin.Namespace,
+		Subsystem: subsystem,
+		Name:      "request_timeouts_total",
+		Help:      "Counter of DNS requests that exceeded the timeout of the server block, per zone.",
+	}, []string{"server", "zone"})
+
+	TCPConnections = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: plugin.Namespace,
+		Subsystem: subsystem,
+		Name:      "tcp_connections",
+		Help:      "Gauge of open TCP and DNS-over-TLS connections.",
+	}, []string{"server"})
+
+	TCPConnectionsRejected = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: plugin.Namespace,
+		Subsystem: subsystem,
+		Name:      "tcp_connections_rejected_total",
+		Help:      "Counter of TCP and DNS-over-TLS connections closed because too many were open.",
+	}, []string{"server"})
+
+	TCPPipelinedQueries = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: plugin.Namespace,
+		Subsystem: subsystem,
+		Name:      "tcp_pipelined_queries",
+		Help:      "Gauge of queries that are being processed on TCP and DNS-over-TLS connections.",
+	}, []string{"server"})
+
+	TCPTimeouts = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: plugin.Namespace,
+		Subsystem: subsystem,
+		Name:      "tcp_timeouts_total",
+		Help:      "Counter of TCP and DNS-over-TLS connections closed because of a timeout, per type.",
+	}, []string{"server", "type"})
+
+	UDPDrops = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: plugin.Namespace,
+		Subsystem: subsystem,
+		Name:      "udp_socket_drops_total",
+		Help:      "Counter of UDP queries the kernel dropped because the receive buffer of a socket was full.",
+	}, []string{"server"})
+
+	SocketBuffer = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: plugin.Namespace,
+		Subsystem: subsystem,
+		Name:      "socket_buffer_bytes",
+		Help:      "Gauge of the size of the receive and send buffers of the sockets, per protocol and buffer.",
+	}, []string{"server", "proto", "buffer"})
+
 	Panic = prometheus.NewCounter(prometheus.CounterOpts{
 		Namespace: plugin.Namespace,
 		Name:      "panic_count_total",
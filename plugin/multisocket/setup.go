@@ -0,0 +1,64 @@
+package multisocket
+
+import (
+	"runtime"
+	"strconv"
+
+	"github.com/coredns/coredns/core/dnsserver"
+	"github.com/coredns/coredns/plugin"
+	"github.com/coredns/coredns/plugin/pkg/transport"
+
+	"github.com/mholt/caddy"
+)
+
+func init() {
+	caddy.RegisterPlugin("multisocket", caddy.Plugin{
+		ServerType: "dns",
+		Action:     setup,
+	})
+}
+
+func setup(c *caddy.Controller) error {
+	config := dnsserver.GetConfig(c)
+
+	if config.Transport != transport.DNS {
+		return plugin.Error("multisocket", c.Errf("only supported for DNS (TCP/UDP) servers, not %s", config.Transport))
+	}
+
+	n, err := parse(c)
+	if err != nil {
+		return plugin.Error("multisocket", err)
+	}
+	config.NumSockets = n
+	return nil
+}
+
+func parse(c *caddy.Controller) (int, error) {
+	n := runtime.GOMAXPROCS(0)
+	i := 0
+	for c.Next() {
+		if i > 0 {
+			return 0, plugin.ErrOnce
+		}
+		i++
+
+		args := c.RemainingArgs()
+		switch len(args) {
+		case 0:
+		case 1:
+			num, err := strconv.Atoi(args[0])
+			if err != nil {
+				return 0, c.Errf("invalid number of sockets '%s'", args[0])
+			}
+			if num <= 0 || num > maxSockets {
+				return 0, c.Errf("number of sockets must be between 1 and %d: %d", maxSockets, num)
+			}
+			n = num
+		default:
+			return 0, c.ArgErr()
+		}
+	}
+	return n, nil
+}
+
+const maxSockets = 1024
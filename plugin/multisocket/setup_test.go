@@ -0,0 +1,65 @@
+package multisocket
+
+import (
+	"runtime"
+	"testing"
+
+	"github.com/coredns/coredns/core/dnsserver"
+
+	"github.com/mholt/caddy"
+)
+
+func TestSetup(t *testing.T) {
+	tests := []struct {
+		input      string
+		shouldErr  bool
+		numSockets int
+	}{
+		{`multisocket`, false, runtime.GOMAXPROCS(0)},
+		{`multisocket 4`, false, 4},
+		{`multisocket 1024`, false, 1024},
+		// errors
+		{`multisocket 0`, true, 0},
+		{`multisocket 1025`, true, 0},
+		{`multisocket many`, true, 0},
+		{`multisocket 2 4`, true, 0},
+		{`multisocket 2
+		multisocket 4`, true, 0},
+	}
+
+	for i, test := range tests {
+		c := caddy.NewTestController("dns", test.input)
+		n, err := parse(c)
+		if test.shouldErr {
+			if err == nil {
+				t.Errorf("Test %d: expected error but found none for input %s", i, test.input)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Test %d: expected no error but found one for input %s, got: %v", i, test.input, err)
+			continue
+		}
+		if n != test.numSockets {
+			t.Errorf("Test %d: expected %d sockets, got %d", i, test.numSockets, n)
+		}
+	}
+}
+
+func TestSetupTransport(t *testing.T) {
+	c := caddy.NewTestController("dns", `multisocket 2`)
+	dnsserver.GetConfig(c).Transport = "tls"
+	if err := setup(c); err == nil {
+		t.Errorf("Expected error for a DNS-over-TLS server, got none")
+	}
+
+	c = caddy.NewTestController("dns", `multisocket 2`)
+	config := dnsserver.GetConfig(c)
+	config.Transport = "dns"
+	if err := setup(c); err != nil {
+		t.Fatalf("Expected no error, got %s", err)
+	}
+	if config.NumSockets != 2 {
+		t.Errorf("Expected 2 sockets, got %d", config.NumSockets)
+	}
+}
@@ -62,6 +62,15 @@ func (c *Cache) Remove(key uint64) {
 	c.shards[shard].Remove(key)
 }
 
+// RemoveFunc removes every element for which f returns true and returns the number of elements removed.
+func (c *Cache) RemoveFunc(f func(el interface{}) bool) int {
+	n := 0
+	for _, s := range c.shards {
+		n += s.RemoveFunc(f)
+	}
+	return n
+}
+
 // Len returns the number of elements in the cache.
 func (c *Cache) Len() int {
 	l := 0
@@ -93,6 +102,20 @@ func (s *shard) Remove(key uint64) {
 	s.Unlock()
 }
 
+// RemoveFunc removes the elements for which f returns true from the cache.
+func (s *shard) RemoveFunc(f func(el interface{}) bool) int {
+	n := 0
+	s.Lock()
+	for k, el := range s.items {
+		if f(el) {
+			delete(s.items, k)
+			n++
+		}
+	}
+	s.Unlock()
+	return n
+}
+
 // Evict removes a random element from the cache.
 func (s *shard) Evict() {
 	hasKey := false
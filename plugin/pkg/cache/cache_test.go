@@ -30,6 +30,24 @@ func TestCacheLen(t *testing.T) {
 	}
 }
 
+func TestCacheRemoveFunc(t *testing.T) {
+	c := New(4)
+	for i := 0; i < 10; i++ {
+		c.Add(uint64(i), i)
+	}
+
+	n := c.RemoveFunc(func(el interface{}) bool { return el.(int)%2 == 0 })
+	if n != 5 {
+		t.Fatalf("Expected %d elements removed, got %d", 5, n)
+	}
+	if l := c.Len(); l != 5 {
+		t.Fatalf("Cache size should %d, got %d", 5, l)
+	}
+	if _, found := c.Get(2); found {
+		t.Fatal("Expected removed element not to be found")
+	}
+}
+
 func BenchmarkCache(b *testing.B) {
 	b.ReportAllocs()
 
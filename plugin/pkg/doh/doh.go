@@ -7,6 +7,7 @@ import (
 	"io"
 	"io/ioutil"
 	"net/http"
+	"strings"
 
 	"github.com/miekg/dns"
 )
@@ -104,8 +105,10 @@ func toMsg(r io.ReadCloser) (*dns.Msg, error) {
 	return m, err
 }
 
+// base64ToMsg decodes the base64url encoded message. RFC 8484 forbids padding, but as some
+// clients add it anyway, it is stripped before decoding.
 func base64ToMsg(b64 string) (*dns.Msg, error) {
-	buf, err := b64Enc.DecodeString(b64)
+	buf, err := b64Enc.DecodeString(strings.TrimRight(b64, "="))
 	if err != nil {
 		return nil, err
 	}
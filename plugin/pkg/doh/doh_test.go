@@ -1,6 +1,7 @@
 package doh
 
 import (
+	"encoding/base64"
 	"net/http"
 	"testing"
 
@@ -50,3 +51,18 @@ func TestGetRequest(t *testing.T) {
 		t.Errorf("Qname expected %d, got %d", x, dns.TypeDNSKEY)
 	}
 }
+
+func TestGetRequestPadded(t *testing.T) {
+	m := new(dns.Msg)
+	m.SetQuestion("example.org.", dns.TypeA)
+	buf, _ := m.Pack()
+
+	req, _ := http.NewRequest(http.MethodGet, "https://example.org"+Path+"?dns="+base64.URLEncoding.EncodeToString(buf), nil)
+	m, err := RequestToMsg(req)
+	if err != nil {
+		t.Fatalf("Failure to get message from padded request: %s", err)
+	}
+	if x := m.Question[0].Name; x != "example.org." {
+		t.Errorf("Qname expected %s, got %s", "example.org.", x)
+	}
+}
@@ -0,0 +1,141 @@
+package doh
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/miekg/dns"
+)
+
+// JSONMimeType is the mimetype of the JSON API as served by Google and Cloudflare.
+const JSONMimeType = "application/dns-json"
+
+// JSONPath is the URL path of the JSON API as used by Google, Path is also accepted.
+const JSONPath = "/resolve"
+
+// IsJSON returns true when the request uses the JSON API. This is the case for GET requests to
+// JSONPath, and for GET requests to Path that carry a 'name' instead of a 'dns' query parameter.
+func IsJSON(req *http.Request) bool {
+	if req.Method != http.MethodGet {
+		return false
+	}
+	switch req.URL.Path {
+	case JSONPath:
+		return true
+	case Path:
+		values := req.URL.Query()
+		_, name := values["name"]
+		_, msg := values["dns"]
+		return name && !msg
+	}
+	return false
+}
+
+// RequestToMsgJSON converts a JSON API request to a dns message. The 'name' query parameter is
+// required, 'type' may be a mnemonic or a number and defaults to A. The 'cd' and 'do' parameters
+// set the CD bit and the DO bit respectively.
+func RequestToMsgJSON(req *http.Request) (*dns.Msg, error) {
+	values := req.URL.Query()
+
+	name := values.Get("name")
+	if name == "" {
+		return nil, fmt.Errorf("no 'name' query parameter found")
+	}
+	if _, ok := dns.IsDomainName(name); !ok {
+		return nil, fmt.Errorf("invalid 'name' query parameter: %s", name)
+	}
+
+	qtype := dns.TypeA
+	if t := values.Get("type"); t != "" {
+		var ok bool
+		if qtype, ok = dns.StringToType[strings.ToUpper(t)]; !ok {
+			n, err := strconv.ParseUint(t, 10, 16)
+			if err != nil {
+				return nil, fmt.Errorf("invalid 'type' query parameter: %s", t)
+			}
+			qtype = uint16(n)
+		}
+	}
+
+	m := new(dns.Msg)
+	m.SetQuestion(dns.Fqdn(name), qtype)
+	m.CheckingDisabled = jsonBool(values.Get("cd"))
+	if jsonBool(values.Get("do")) {
+		m.SetEdns0(4096, true)
+	}
+	return m, nil
+}
+
+// jsonBool parses a boolean query parameter, an empty value is false.
+func jsonBool(s string) bool {
+	switch strings.ToLower(s) {
+	case "1", "true":
+		return true
+	}
+	return false
+}
+
+// MsgToJSON converts a dns message to the JSON API format.
+func MsgToJSON(m *dns.Msg) ([]byte, error) {
+	r := jsonMsg{
+		Status: m.Rcode,
+		TC:     m.Truncated,
+		RD:     m.RecursionDesired,
+		RA:     m.RecursionAvailable,
+		AD:     m.AuthenticatedData,
+		CD:     m.CheckingDisabled,
+	}
+	for _, q := range m.Question {
+		r.Question = append(r.Question, jsonQuestion{Name: q.Name, Type: q.Qtype})
+	}
+	r.Answer = toJSONRRs(m.Answer)
+	r.Authority = toJSONRRs(m.Ns)
+	r.Additional = toJSONRRs(m.Extra)
+
+	return json.Marshal(r)
+}
+
+func toJSONRRs(rrs []dns.RR) []jsonRR {
+	var j []jsonRR
+	for _, rr := range rrs {
+		hdr := rr.Header()
+		if hdr.Rrtype == dns.TypeOPT {
+			continue
+		}
+		j = append(j, jsonRR{
+			Name: hdr.Name,
+			Type: hdr.Rrtype,
+			TTL:  hdr.Ttl,
+			Data: strings.TrimPrefix(rr.String(), hdr.String()),
+		})
+	}
+	return j
+}
+
+type jsonMsg struct {
+	Status     int
+	TC         bool
+	RD         bool
+	RA         bool
+	AD         bool
+	CD         bool
+	Question   []jsonQuestion
+	Answer     []jsonRR `json:",omitempty"`
+	Authority  []jsonRR `json:",omitempty"`
+	Additional []jsonRR `json:",omitempty"`
+}
+
+type jsonQuestion struct {
+	Name string `json:"name"`
+	Type uint16 `json:"type"`
+}
+
+type jsonRR struct {
+	Name string `json:"name"`
+	Type uint16 `json:"type"`
+	TTL  uint32 `json:"TTL"`
+	Data string `json:"data"`
+}
@@ -0,0 +1,107 @@
+package doh
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/miekg/dns"
+)
+
+func TestIsJSON(t *testing.T) {
+	tests := []struct {
+		method string
+		url    string
+		isJSON bool
+	}{
+		{http.MethodGet, "https://example.org/resolve?name=example.org", true},
+		{http.MethodGet, "https://example.org/dns-query?name=example.org&type=AAAA", true},
+		{http.MethodGet, "https://example.org/dns-query?dns=AAABAAABAAAAAAAAB2V4YW1wbGUDb3JnAAABAAE", false},
+		{http.MethodPost, "https://example.org/dns-query?name=example.org", false},
+		{http.MethodGet, "https://example.org/other?name=example.org", false},
+	}
+
+	for i, tc := range tests {
+		req, _ := http.NewRequest(tc.method, tc.url, nil)
+		if x := IsJSON(req); x != tc.isJSON {
+			t.Errorf("Test %d: expected IsJSON %t, got %t", i, tc.isJSON, x)
+		}
+	}
+}
+
+func TestRequestToMsgJSON(t *testing.T) {
+	tests := []struct {
+		url       string
+		shouldErr bool
+		qname     string
+		qtype     uint16
+		cd        bool
+		do        bool
+	}{
+		{"https://example.org/resolve?name=example.org", false, "example.org.", dns.TypeA, false, false},
+		{"https://example.org/resolve?name=example.org.&type=aaaa&cd=1", false, "example.org.", dns.TypeAAAA, true, false},
+		{"https://example.org/resolve?name=example.org&type=48&do=true", false, "example.org.", dns.TypeDNSKEY, false, true},
+		{"https://example.org/resolve", true, "", 0, false, false},
+		{"https://example.org/resolve?name=example.org&type=bogus", true, "", 0, false, false},
+	}
+
+	for i, tc := range tests {
+		req, _ := http.NewRequest(http.MethodGet, tc.url, nil)
+		m, err := RequestToMsgJSON(req)
+		if tc.shouldErr {
+			if err == nil {
+				t.Errorf("Test %d: expected error, got none", i)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Test %d: expected no error, got %s", i, err)
+			continue
+		}
+		if x := m.Question[0].Name; x != tc.qname {
+			t.Errorf("Test %d: expected qname %s, got %s", i, tc.qname, x)
+		}
+		if x := m.Question[0].Qtype; x != tc.qtype {
+			t.Errorf("Test %d: expected qtype %d, got %d", i, tc.qtype, x)
+		}
+		if m.CheckingDisabled != tc.cd {
+			t.Errorf("Test %d: expected CD %t, got %t", i, tc.cd, m.CheckingDisabled)
+		}
+		if do := m.IsEdns0() != nil && m.IsEdns0().Do(); do != tc.do {
+			t.Errorf("Test %d: expected DO %t, got %t", i, tc.do, do)
+		}
+	}
+}
+
+func TestMsgToJSON(t *testing.T) {
+	m := new(dns.Msg)
+	m.SetQuestion("example.org.", dns.TypeA)
+	m.Response = true
+	m.RecursionAvailable = true
+	a, _ := dns.NewRR("example.org. 300 IN A 127.0.0.1")
+	m.Answer = []dns.RR{a}
+	m.SetEdns0(4096, false)
+
+	buf, err := MsgToJSON(m)
+	if err != nil {
+		t.Fatalf("Failure to convert message to JSON: %s", err)
+	}
+
+	r := jsonMsg{}
+	if err := json.Unmarshal(buf, &r); err != nil {
+		t.Fatalf("Failure to unmarshal JSON %s: %s", buf, err)
+	}
+	if r.Status != dns.RcodeSuccess || !r.RD || !r.RA {
+		t.Errorf("Unexpected header in %s", buf)
+	}
+	if len(r.Question) != 1 || r.Question[0].Name != "example.org." || r.Question[0].Type != dns.TypeA {
+		t.Errorf("Unexpected question in %s", buf)
+	}
+	if len(r.Answer) != 1 || r.Answer[0].TTL != 300 || r.Answer[0].Data != "127.0.0.1" {
+		t.Errorf("Unexpected answer in %s", buf)
+	}
+	// The OPT record is not part of the JSON format.
+	if len(r.Additional) != 0 {
+		t.Errorf("Expected no additional records in %s", buf)
+	}
+}
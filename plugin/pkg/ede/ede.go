@@ -0,0 +1,150 @@
+// Package ede implements Extended DNS Errors (RFC 8914).
+//
+// A plugin attaches an extended error to the context of a request with Set; the server adds it to
+// the OPT RR of the reply, but only when the request has an OPT RR itself.
+package ede
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"sync"
+
+	"github.com/miekg/dns"
+)
+
+// OptionCode is the EDNS0 option code of an extended error.
+const OptionCode = 15
+
+// Info codes as defined in RFC 8914, Section 4.
+const (
+	Other                      uint16 = 0
+	UnsupportedDNSKEYAlgorithm uint16 = 1
+	UnsupportedDSDigestType    uint16 = 2
+	StaleAnswer                uint16 = 3
+	ForgedAnswer               uint16 = 4
+	DNSSECIndeterminate        uint16 = 5
+	DNSSECBogus                uint16 = 6
+	SignatureExpired           uint16 = 7
+	SignatureNotYetValid       uint16 = 8
+	DNSKEYMissing              uint16 = 9
+	RRSIGsMissing              uint16 = 10
+	NoZoneKeyBitSet            uint16 = 11
+	NSECMissing                uint16 = 12
+	CachedError                uint16 = 13
+	NotReady                   uint16 = 14
+	Blocked                    uint16 = 15
+	Censored                   uint16 = 16
+	Filtered                   uint16 = 17
+	Prohibited                 uint16 = 18
+	StaleNXDomainAnswer        uint16 = 19
+	NotAuthoritative           uint16 = 20
+	NotSupported               uint16 = 21
+	NoReachableAuthority       uint16 = 22
+	NetworkError               uint16 = 23
+	InvalidData                uint16 = 24
+)
+
+// Error is an extended DNS error.
+type Error struct {
+	Code uint16
+	Text string // extra text, may be empty
+}
+
+// Option returns e as an EDNS0 option.
+func (e *Error) Option() *dns.EDNS0_LOCAL {
+	data := make([]byte, 2+len(e.Text))
+	binary.BigEndian.PutUint16(data, e.Code)
+	copy(data[2:], e.Text)
+	return &dns.EDNS0_LOCAL{Code: OptionCode, Data: data}
+}
+
+type key struct{}
+
+// holder holds the extended error of a request.
+type holder struct {
+	sync.Mutex
+	e *Error
+}
+
+// NewContext returns a context that can carry an extended error. The server calls this for every
+// request, plugins don't need to.
+func NewContext(ctx context.Context) context.Context {
+	return context.WithValue(ctx, key{}, &holder{})
+}
+
+// Set attaches the extended error code with text to the request of ctx, overwriting an error that
+// was set earlier. The text is sent to the client, so don't put anything sensitive in it.
+func Set(ctx context.Context, code uint16, text string) {
+	h := fromContext(ctx)
+	if h == nil {
+		return
+	}
+	h.Lock()
+	h.e = &Error{Code: code, Text: text}
+	h.Unlock()
+}
+
+// Get returns the extended error attached to the request of ctx, or nil.
+func Get(ctx context.Context) *Error {
+	h := fromContext(ctx)
+	if h == nil {
+		return nil
+	}
+	h.Lock()
+	defer h.Unlock()
+	return h.e
+}
+
+func fromContext(ctx context.Context) *holder {
+	if ctx == nil {
+		return nil
+	}
+	h, _ := ctx.Value(key{}).(*holder)
+	return h
+}
+
+// Add adds the extended error attached to ctx to the reply m for the request req. Nothing is done
+// when there is no error, or when req doesn't have an OPT RR. Adding the same error twice is a noop.
+func Add(ctx context.Context, req, m *dns.Msg) {
+	e := Get(ctx)
+	if e == nil || req == nil || m == nil {
+		return
+	}
+	o := req.IsEdns0()
+	if o == nil {
+		return
+	}
+	mo := m.IsEdns0()
+	if mo == nil {
+		m.SetEdns0(o.UDPSize(), o.Do())
+		mo = m.IsEdns0()
+	}
+
+	opt := e.Option()
+	for _, x := range mo.Option {
+		if l, ok := x.(*dns.EDNS0_LOCAL); ok && l.Code == OptionCode && bytes.Equal(l.Data, opt.Data) {
+			return
+		}
+	}
+	mo.Option = append(mo.Option, opt)
+}
+
+// Writer is a dns.ResponseWriter that adds the extended error attached to its context to the
+// reply, see Add.
+type Writer struct {
+	dns.ResponseWriter
+	ctx context.Context
+	req *dns.Msg
+}
+
+// NewWriter returns a Writer for the request req.
+func NewWriter(ctx context.Context, req *dns.Msg, w dns.ResponseWriter) *Writer {
+	return &Writer{ResponseWriter: w, ctx: ctx, req: req}
+}
+
+// WriteMsg implements the dns.ResponseWriter interface.
+func (w *Writer) WriteMsg(m *dns.Msg) error {
+	Add(w.ctx, w.req, m)
+	return w.ResponseWriter.WriteMsg(m)
+}
@@ -0,0 +1,61 @@
+package ede
+
+import (
+	"context"
+	"testing"
+
+	"github.com/miekg/dns"
+)
+
+func TestSetGet(t *testing.T) {
+	ctx := context.Background()
+	Set(ctx, NetworkError, "no context")
+	if e := Get(ctx); e != nil {
+		t.Errorf("Expected no error without NewContext, got %v", e)
+	}
+	Set(nil, NetworkError, "nil context")
+
+	ctx = NewContext(ctx)
+	Set(ctx, NetworkError, "upstream timed out")
+	Set(ctx, NoReachableAuthority, "no healthy upstreams")
+	e := Get(ctx)
+	if e == nil || e.Code != NoReachableAuthority || e.Text != "no healthy upstreams" {
+		t.Errorf("Expected the last error to be kept, got %v", e)
+	}
+}
+
+func TestAdd(t *testing.T) {
+	ctx := NewContext(context.Background())
+	Set(ctx, NotReady, "not synced")
+
+	req := new(dns.Msg)
+	req.SetQuestion("example.org.", dns.TypeA)
+	m := new(dns.Msg)
+	m.SetRcode(req, dns.RcodeServerFailure)
+
+	Add(ctx, req, m)
+	if m.IsEdns0() != nil {
+		t.Fatalf("Expected no OPT RR when the request doesn't have one")
+	}
+
+	req.SetEdns0(4096, true)
+	Add(ctx, req, m)
+	Add(ctx, req, m)
+	o := m.IsEdns0()
+	if o == nil {
+		t.Fatalf("Expected OPT RR, got none")
+	}
+	if !o.Do() {
+		t.Errorf("Expected DO bit to be copied from the request")
+	}
+	if len(o.Option) != 1 {
+		t.Fatalf("Expected 1 option, got %d", len(o.Option))
+	}
+	l, ok := o.Option[0].(*dns.EDNS0_LOCAL)
+	if !ok || l.Code != OptionCode {
+		t.Fatalf("Expected extended error option, got %v", o.Option[0])
+	}
+	if want := "\x00\x0enot synced"; string(l.Data) != want {
+		t.Errorf("Expected option data %q, got %q", want, l.Data)
+	}
+}
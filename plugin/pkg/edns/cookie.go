@@ -0,0 +1,107 @@
+package edns
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"net"
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+// Cookie returns the client and server cookie of the DNS Cookie option (RFC 7873) of m. Both are nil
+// when m doesn't have a cookie option, server is nil when m only carries a client cookie.
+func Cookie(m *dns.Msg) (client, server []byte, err error) {
+	o := m.IsEdns0()
+	if o == nil {
+		return nil, nil, nil
+	}
+	for _, opt := range o.Option {
+		e, ok := opt.(*dns.EDNS0_COOKIE)
+		if !ok {
+			continue
+		}
+		b, err := hex.DecodeString(e.Cookie)
+		if err != nil {
+			return nil, nil, ErrCookie
+		}
+		// RFC 7873, Section 5.2.2: an 8 octet client cookie, optionally followed by an 8 to 32 octet server cookie.
+		switch {
+		case len(b) == 8:
+			return b, nil, nil
+		case len(b) >= 16 && len(b) <= 40:
+			return b[:8], b[8:], nil
+		}
+		return nil, nil, ErrCookie
+	}
+	return nil, nil, nil
+}
+
+// SetCookie sets the cookie option of m to the client and server cookie, an existing one is
+// replaced. When m doesn't have an OPT RR one is added that advertises size as the UDP buffer size.
+func SetCookie(m *dns.Msg, client, server []byte, size uint16) {
+	o := m.IsEdns0()
+	if o == nil {
+		m.SetEdns0(size, false)
+		o = m.IsEdns0()
+	}
+	removeOption(o, dns.EDNS0COOKIE)
+	b := append(append([]byte{}, client...), server...)
+	o.Option = append(o.Option, &dns.EDNS0_COOKIE{Code: dns.EDNS0COOKIE, Cookie: hex.EncodeToString(b)})
+}
+
+// RemoveCookie removes the cookie option from m.
+func RemoveCookie(m *dns.Msg) {
+	if o := m.IsEdns0(); o != nil {
+		removeOption(o, dns.EDNS0COOKIE)
+	}
+}
+
+// ServerCookie returns the server cookie for client, the client cookie of a query sent from ip at
+// time now. It uses the interoperable format of RFC 9018: servers sharing secret can verify each
+// other's cookies.
+func ServerCookie(secret [16]byte, client []byte, ip net.IP, now time.Time) []byte {
+	server := make([]byte, 16)
+	server[0] = cookieVersion
+	// server[1:4] is reserved and zero.
+	binary.BigEndian.PutUint32(server[4:8], uint32(now.Unix()))
+	binary.LittleEndian.PutUint64(server[8:], cookieHash(secret, client, server[:8], ip))
+	return server
+}
+
+// ValidServerCookie returns true when server is a server cookie created with ServerCookie for client
+// and ip under secret, that is not older than an hour and not from more than 5 minutes in the future,
+// see RFC 9018, Section 4.3.
+func ValidServerCookie(secret [16]byte, client, server []byte, ip net.IP, now time.Time) bool {
+	if len(server) != 16 || server[0] != cookieVersion {
+		return false
+	}
+	ts := time.Unix(int64(binary.BigEndian.Uint32(server[4:8])), 0)
+	if ts.Before(now.Add(-cookieLifetime)) || ts.After(now.Add(cookieClockSkew)) {
+		return false
+	}
+	return binary.LittleEndian.Uint64(server[8:]) == cookieHash(secret, client, server[:8], ip)
+}
+
+// cookieHash returns the hash of the server cookie: the SipHash-2-4 of the client cookie, the version,
+// reserved and timestamp fields, and the client's address.
+func cookieHash(secret [16]byte, client, fields []byte, ip net.IP) uint64 {
+	if ip4 := ip.To4(); ip4 != nil {
+		ip = ip4
+	}
+	b := make([]byte, 0, len(client)+len(fields)+len(ip))
+	b = append(b, client...)
+	b = append(b, fields...)
+	b = append(b, ip...)
+	return siphash(secret, b)
+}
+
+const (
+	cookieVersion   = 1
+	cookieLifetime  = time.Hour
+	cookieClockSkew = 5 * time.Minute
+)
+
+// ErrCookie is returned when a cookie option is malformed.
+var ErrCookie = errors.New("malformed cookie option")
@@ -0,0 +1,81 @@
+package edns
+
+import (
+	"bytes"
+	"encoding/hex"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+// Test vector from RFC 9018, Appendix A.1.
+func TestServerCookie(t *testing.T) {
+	var secret [16]byte
+	s, _ := hex.DecodeString("e5e973e5a6b2a43f48e7dc849e37bfcf")
+	copy(secret[:], s)
+	client, _ := hex.DecodeString("2464c4abcf10c957")
+	ip := net.ParseIP("198.51.100.100")
+	now := time.Unix(1559731985, 0)
+
+	server := ServerCookie(secret, client, ip, now)
+	if want := "010000005cf79f111f8130c3eee29480"; hex.EncodeToString(server) != want {
+		t.Fatalf("Expected server cookie %s, got %x", want, server)
+	}
+
+	tests := []struct {
+		client []byte
+		ip     net.IP
+		now    time.Time
+		valid  bool
+	}{
+		{client, ip, now, true},
+		{client, ip, now.Add(30 * time.Minute), true},
+		{client, ip, now.Add(2 * time.Hour), false},
+		{client, ip, now.Add(-10 * time.Minute), false},
+		{client, net.ParseIP("198.51.100.101"), now, false},
+		{[]byte("01234567"), ip, now, false},
+	}
+	for i, tc := range tests {
+		if got := ValidServerCookie(secret, tc.client, server, tc.ip, tc.now); got != tc.valid {
+			t.Errorf("Test %d: expected valid to be %t, got %t", i, tc.valid, got)
+		}
+	}
+}
+
+func TestCookie(t *testing.T) {
+	client := []byte("01234567")
+	server := []byte("0123456789abcdef")
+
+	m := new(dns.Msg)
+	m.SetQuestion("example.org.", dns.TypeA)
+	if c, s, err := Cookie(m); c != nil || s != nil || err != nil {
+		t.Errorf("Expected no cookie, got %x %x %v", c, s, err)
+	}
+
+	SetCookie(m, client, nil, 4096)
+	c, s, err := Cookie(m)
+	if err != nil || !bytes.Equal(c, client) || s != nil {
+		t.Errorf("Expected client cookie only, got %x %x %v", c, s, err)
+	}
+
+	SetCookie(m, client, server, 4096)
+	c, s, err = Cookie(m)
+	if err != nil || !bytes.Equal(c, client) || !bytes.Equal(s, server) {
+		t.Errorf("Expected client and server cookie, got %x %x %v", c, s, err)
+	}
+	if n := len(m.IsEdns0().Option); n != 1 {
+		t.Errorf("Expected 1 option, got %d", n)
+	}
+
+	SetCookie(m, client, []byte("short"), 4096)
+	if _, _, err := Cookie(m); err != ErrCookie {
+		t.Errorf("Expected ErrCookie, got %v", err)
+	}
+
+	RemoveCookie(m)
+	if n := len(m.IsEdns0().Option); n != 0 {
+		t.Errorf("Expected no options, got %d", n)
+	}
+}
@@ -0,0 +1,117 @@
+package edns
+
+import (
+	"context"
+	"net"
+	"sync"
+
+	"github.com/miekg/dns"
+)
+
+// Subnet returns the EDNS0 Client Subnet option (RFC 7871) of m, or nil when m doesn't have one.
+func Subnet(m *dns.Msg) *dns.EDNS0_SUBNET {
+	o := m.IsEdns0()
+	if o == nil {
+		return nil
+	}
+	for _, opt := range o.Option {
+		if e, ok := opt.(*dns.EDNS0_SUBNET); ok {
+			return e
+		}
+	}
+	return nil
+}
+
+// SetSubnet sets the client subnet option of m to e, an existing one is replaced. When m doesn't have
+// an OPT RR one is added that advertises size as the UDP buffer size.
+func SetSubnet(m *dns.Msg, e *dns.EDNS0_SUBNET, size uint16) {
+	o := m.IsEdns0()
+	if o == nil {
+		m.SetEdns0(size, false)
+		o = m.IsEdns0()
+	}
+	for i, opt := range o.Option {
+		if _, ok := opt.(*dns.EDNS0_SUBNET); ok {
+			o.Option[i] = e
+			return
+		}
+	}
+	o.Option = append(o.Option, e)
+}
+
+// RemoveSubnet removes the client subnet option from m.
+func RemoveSubnet(m *dns.Msg) {
+	if o := m.IsEdns0(); o != nil {
+		removeOption(o, dns.EDNS0SUBNET)
+	}
+}
+
+// NewSubnet returns a client subnet option for ip, with a source prefix length of v4 or v6
+// depending on the address family. The address is truncated to the source prefix length.
+func NewSubnet(ip net.IP, v4, v6 uint8) *dns.EDNS0_SUBNET {
+	e := &dns.EDNS0_SUBNET{Code: dns.EDNS0SUBNET}
+	if ip4 := ip.To4(); ip4 != nil {
+		e.Family = 1
+		e.SourceNetmask = v4
+		e.Address = ip4.Mask(net.CIDRMask(int(v4), 32))
+		return e
+	}
+	e.Family = 2
+	e.SourceNetmask = v6
+	e.Address = ip.Mask(net.CIDRMask(int(v6), 128))
+	return e
+}
+
+// EchoSubnet sets the client subnet option of reply to a copy of e, the option of the query, with
+// its scope prefix length set to scope, as RFC 7871, Section 7.2.1 requires. The scope is capped to
+// the source prefix length.
+func EchoSubnet(reply *dns.Msg, e *dns.EDNS0_SUBNET, scope uint8, size uint16) {
+	if scope > e.SourceNetmask {
+		scope = e.SourceNetmask
+	}
+	echo := &dns.EDNS0_SUBNET{
+		Code:          dns.EDNS0SUBNET,
+		Family:        e.Family,
+		SourceNetmask: e.SourceNetmask,
+		SourceScope:   scope,
+		Address:       e.Address,
+	}
+	SetSubnet(reply, echo, size)
+}
+
+type subnetKey struct{}
+
+// subnetHolder holds the client subnet option of an upstream reply.
+type subnetHolder struct {
+	sync.Mutex
+	e *dns.EDNS0_SUBNET
+}
+
+// NewSubnetContext returns a context that can carry the client subnet option of the reply from
+// upstream, for a plugin that needs its scope while the option is removed from the reply.
+func NewSubnetContext(ctx context.Context) context.Context {
+	return context.WithValue(ctx, subnetKey{}, &subnetHolder{})
+}
+
+// SetReplySubnet records e, the client subnet option a plugin removed from the reply from upstream,
+// in ctx. It is a noop when ctx doesn't come from NewSubnetContext.
+func SetReplySubnet(ctx context.Context, e *dns.EDNS0_SUBNET) {
+	h, _ := ctx.Value(subnetKey{}).(*subnetHolder)
+	if h == nil {
+		return
+	}
+	h.Lock()
+	h.e = e
+	h.Unlock()
+}
+
+// ReplySubnet returns the client subnet option recorded in ctx with SetReplySubnet, or nil.
+func ReplySubnet(ctx context.Context) *dns.EDNS0_SUBNET {
+	h, _ := ctx.Value(subnetKey{}).(*subnetHolder)
+	if h == nil {
+		return nil
+	}
+	h.Lock()
+	defer h.Unlock()
+	return h.e
+}
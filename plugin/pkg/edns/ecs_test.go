@@ -0,0 +1,89 @@
+package edns
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	"github.com/miekg/dns"
+)
+
+func TestNewSubnet(t *testing.T) {
+	tests := []struct {
+		ip      string
+		family  uint16
+		netmask uint8
+		address string
+	}{
+		{"10.240.0.1", 1, 24, "10.240.0.0"},
+		{"2001:db8:1:2:3::1", 2, 56, "2001:db8:1::"},
+	}
+	for i, tc := range tests {
+		e := NewSubnet(net.ParseIP(tc.ip), 24, 56)
+		if e.Family != tc.family {
+			t.Errorf("Test %d: expected family %d, got %d", i, tc.family, e.Family)
+		}
+		if e.SourceNetmask != tc.netmask {
+			t.Errorf("Test %d: expected source netmask %d, got %d", i, tc.netmask, e.SourceNetmask)
+		}
+		if !e.Address.Equal(net.ParseIP(tc.address)) {
+			t.Errorf("Test %d: expected address %s, got %s", i, tc.address, e.Address)
+		}
+	}
+}
+
+func TestSetSubnet(t *testing.T) {
+	m := new(dns.Msg)
+	m.SetQuestion("example.org.", dns.TypeA)
+
+	SetSubnet(m, NewSubnet(net.ParseIP("10.240.0.1"), 24, 56), 1232)
+	if o := m.IsEdns0(); o == nil || o.UDPSize() != 1232 {
+		t.Fatalf("Expected OPT RR with UDP size 1232, got %v", o)
+	}
+
+	SetSubnet(m, NewSubnet(net.ParseIP("192.0.2.1"), 24, 56), 1232)
+	if n := len(m.IsEdns0().Option); n != 1 {
+		t.Fatalf("Expected 1 option, got %d", n)
+	}
+	if e := Subnet(m); !e.Address.Equal(net.ParseIP("192.0.2.0")) {
+		t.Errorf("Expected address 192.0.2.0, got %s", e.Address)
+	}
+
+	RemoveSubnet(m)
+	if e := Subnet(m); e != nil {
+		t.Errorf("Expected no client subnet option, got %s", e)
+	}
+}
+
+func TestEchoSubnet(t *testing.T) {
+	e := NewSubnet(net.ParseIP("10.240.0.1"), 24, 56)
+	m := new(dns.Msg)
+	EchoSubnet(m, e, 32, 512)
+
+	echo := Subnet(m)
+	if echo == nil {
+		t.Fatal("Expected client subnet option, got none")
+	}
+	if echo.SourceScope != 24 {
+		t.Errorf("Expected scope to be capped to 24, got %d", echo.SourceScope)
+	}
+	if e.SourceScope != 0 {
+		t.Errorf("Expected the option of the query to be left alone, got scope %d", e.SourceScope)
+	}
+}
+
+func TestReplySubnet(t *testing.T) {
+	e := NewSubnet(net.ParseIP("10.240.0.1"), 24, 56)
+
+	ctx := context.TODO()
+	SetReplySubnet(ctx, e)
+	if r := ReplySubnet(ctx); r != nil {
+		t.Errorf("Expected no client subnet option without a subnet context, got %s", r)
+	}
+
+	ctx = NewSubnetContext(ctx)
+	SetReplySubnet(ctx, e)
+	if r := ReplySubnet(ctx); r != e {
+		t.Errorf("Expected the recorded client subnet option, got %v", r)
+	}
+}
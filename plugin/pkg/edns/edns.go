@@ -44,3 +44,31 @@ func Size(proto string, size int) int {
 	}
 	return size
 }
+
+// RemoveOPT removes the OPT RR from m, for a reply to a query that didn't have one.
+func RemoveOPT(m *dns.Msg) {
+	extra := m.Extra[:0]
+	for _, rr := range m.Extra {
+		if rr.Header().Rrtype != dns.TypeOPT {
+			extra = append(extra, rr)
+		}
+	}
+	m.Extra = extra
+}
+
+// SetExtendedRcode puts the upper 8 bits of the rcode of m in its OPT RR, which must exist. Packing
+// the message only sets the lower 4 bits in the header, the upper bits of rcodes such as BADCOOKIE
+// are lost otherwise.
+func SetExtendedRcode(m *dns.Msg) {
+	o := m.IsEdns0()
+	o.Hdr.Ttl = o.Hdr.Ttl&0x00FFFFFF | uint32(m.Rcode>>4)<<24
+}
+
+// Rcode returns the rcode of m, including the upper 8 bits from the OPT RR of m.
+func Rcode(m *dns.Msg) int {
+	o := m.IsEdns0()
+	if o == nil {
+		return m.Rcode
+	}
+	return m.Rcode | int(o.Hdr.Ttl>>24)<<4
+}
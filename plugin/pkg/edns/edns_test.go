@@ -26,6 +26,24 @@ func TestVersionNoEdns(t *testing.T) {
 	}
 }
 
+func TestExtendedRcode(t *testing.T) {
+	m := ednsMsg()
+	m.Rcode = dns.RcodeBadCookie
+	SetExtendedRcode(m)
+
+	buf, err := m.Pack()
+	if err != nil {
+		t.Fatalf("Expected no error, but got one: %s", err)
+	}
+	m = new(dns.Msg)
+	if err := m.Unpack(buf); err != nil {
+		t.Fatalf("Expected no error, but got one: %s", err)
+	}
+	if rcode := Rcode(m); rcode != dns.RcodeBadCookie {
+		t.Errorf("Expected rcode %d, but got %d", dns.RcodeBadCookie, rcode)
+	}
+}
+
 func ednsMsg() *dns.Msg {
 	m := new(dns.Msg)
 	m.SetQuestion("example.com.", dns.TypeA)
@@ -0,0 +1,54 @@
+package edns
+
+import (
+	"github.com/miekg/dns"
+)
+
+// Block sizes of the padding policy recommended by RFC 8467, Section 4.1.
+const (
+	QueryPaddingBlock = 128
+	ReplyPaddingBlock = 468
+)
+
+// Pad adds an EDNS0 Padding option (RFC 7830) to m, so its length becomes a multiple of block. It
+// pads less if that would make m larger than max. An existing padding option is replaced. If m
+// doesn't have an OPT RR it is left alone.
+func Pad(m *dns.Msg, block, max int) {
+	o := m.IsEdns0()
+	if o == nil {
+		return
+	}
+	removeOption(o, dns.EDNS0PADDING)
+
+	p := &dns.EDNS0_PADDING{}
+	o.Option = append(o.Option, p)
+
+	l := m.Len() // includes the 4 octets of the empty option
+	n := (block - l%block) % block
+	if l+n > max {
+		n = max - l
+	}
+	if n < 0 {
+		removeOption(o, dns.EDNS0PADDING)
+		return
+	}
+	p.Padding = make([]byte, n)
+}
+
+// RemovePadding removes the padding option from m.
+func RemovePadding(m *dns.Msg) {
+	if o := m.IsEdns0(); o != nil {
+		removeOption(o, dns.EDNS0PADDING)
+	}
+}
+
+// removeOption removes the options with code from o.
+func removeOption(o *dns.OPT, code uint16) {
+	opts := o.Option[:0]
+	for _, opt := range o.Option {
+		if opt.Option() != code {
+			opts = append(opts, opt)
+		}
+	}
+	o.Option = opts
+}
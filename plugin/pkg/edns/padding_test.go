@@ -0,0 +1,57 @@
+package edns
+
+import (
+	"testing"
+
+	"github.com/miekg/dns"
+)
+
+func TestPad(t *testing.T) {
+	tests := []struct {
+		block, max int
+		want       int // length of the padded message
+	}{
+		{QueryPaddingBlock, dns.MaxMsgSize, 128},
+		{ReplyPaddingBlock, dns.MaxMsgSize, 468},
+		{ReplyPaddingBlock, 100, 100},
+	}
+
+	for i, tc := range tests {
+		m := new(dns.Msg)
+		m.SetQuestion("example.org.", dns.TypeA)
+		m.SetEdns0(4096, false)
+
+		Pad(m, tc.block, tc.max)
+		if got := m.Len(); got != tc.want {
+			t.Errorf("Test %d: expected length %d, got %d", i, tc.want, got)
+		}
+		// Padding again replaces the option.
+		Pad(m, tc.block, tc.max)
+		if got := m.Len(); got != tc.want {
+			t.Errorf("Test %d: expected length %d after padding twice, got %d", i, tc.want, got)
+		}
+	}
+}
+
+func TestPadNoEdns(t *testing.T) {
+	m := new(dns.Msg)
+	m.SetQuestion("example.org.", dns.TypeA)
+
+	Pad(m, QueryPaddingBlock, dns.MaxMsgSize)
+	if m.IsEdns0() != nil {
+		t.Errorf("Expected no OPT RR to be added")
+	}
+}
+
+func TestPadTooLarge(t *testing.T) {
+	m := new(dns.Msg)
+	m.SetQuestion("example.org.", dns.TypeA)
+	m.SetEdns0(4096, false)
+
+	Pad(m, ReplyPaddingBlock, 20)
+	for _, o := range m.IsEdns0().Option {
+		if o.Option() == dns.EDNS0PADDING {
+			t.Errorf("Expected no padding option when the message doesn't fit")
+		}
+	}
+}
@@ -0,0 +1,93 @@
+package log
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// jsonLine returns msg as a JSON object.
+func jsonLine(l Level, plugin string, fields []interface{}, msg string) string {
+	b := &bytes.Buffer{}
+	b.WriteString(`{"ts":`)
+	writeValue(b, clock())
+	b.WriteString(`,"level":`)
+	writeValue(b, l.String())
+	if plugin != "" {
+		b.WriteString(`,"plugin":`)
+		writeValue(b, plugin)
+	}
+	b.WriteString(`,"msg":`)
+	writeValue(b, msg)
+	for i := 0; i < len(fields); i += 2 {
+		b.WriteByte(',')
+		writeValue(b, fmt.Sprint(fields[i]))
+		b.WriteByte(':')
+		writeValue(b, value(fields, i+1))
+	}
+	b.WriteByte('}')
+	return b.String()
+}
+
+// writeValue writes v as JSON to b, errors and Stringers are written as their string.
+func writeValue(b *bytes.Buffer, v interface{}) {
+	switch x := v.(type) {
+	case error:
+		v = x.Error()
+	case fmt.Stringer:
+		v = x.String()
+	}
+	buf, err := json.Marshal(v)
+	if err != nil {
+		buf, _ = json.Marshal(fmt.Sprint(v))
+	}
+	b.Write(buf)
+}
+
+// textFields returns the key/value pairs in fields as " key=value".
+func textFields(fields []interface{}) string {
+	if len(fields) == 0 {
+		return ""
+	}
+	b := &bytes.Buffer{}
+	for i := 0; i < len(fields); i += 2 {
+		fmt.Fprintf(b, " %v=%v", fields[i], value(fields, i+1))
+	}
+	return b.String()
+}
+
+// value returns fields[i], or an empty string if a key doesn't have a value.
+func value(fields []interface{}, i int) interface{} {
+	if i < len(fields) {
+		return fields[i]
+	}
+	return ""
+}
+
+// JSONWriter returns a writer for the std lib logger that writes the lines that aren't logged by
+// this package, for instance those of libraries, as JSON to w. A "[LEVEL] " prefix is used as the
+// level. It should be used together with SetFormat(JSON).
+func JSONWriter(w io.Writer) io.Writer { return jsonWriter{w} }
+
+type jsonWriter struct{ w io.Writer }
+
+func (j jsonWriter) Write(p []byte) (int, error) {
+	if len(p) > 0 && p[0] == '{' {
+		return j.w.Write(p)
+	}
+	msg := strings.TrimRight(string(p), "\n")
+	l := LevelInfo
+	if strings.HasPrefix(msg, "[") {
+		if i := strings.Index(msg, "] "); i > 0 {
+			if l1, err := ParseLevel(msg[1:i]); err == nil {
+				l, msg = l1, msg[i+2:]
+			}
+		}
+	}
+	if _, err := io.WriteString(j.w, jsonLine(l, "", nil, msg)+"\n"); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
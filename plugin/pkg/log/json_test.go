@@ -0,0 +1,66 @@
+package log
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	golog "log"
+	"testing"
+)
+
+func TestJSON(t *testing.T) {
+	var f bytes.Buffer
+	golog.SetFlags(0)
+	golog.SetOutput(&f)
+	SetFormat(JSON)
+	defer SetFormat(Text)
+
+	lg := NewWithPlugin("testplugin").With("server", "dns://:53", "err", errors.New("timeout"), "n", 2)
+	lg.Warningf("%s upstream", "failed")
+
+	m := map[string]interface{}{}
+	if err := json.Unmarshal(f.Bytes(), &m); err != nil {
+		t.Fatalf("Expected JSON, got %s", f.String())
+	}
+	expected := map[string]interface{}{
+		"level": "warning", "plugin": "testplugin", "msg": "failed upstream",
+		"server": "dns://:53", "err": "timeout", "n": float64(2),
+	}
+	for k, v := range expected {
+		if m[k] != v {
+			t.Errorf("Expected %s to be %v, got %v", k, v, m[k])
+		}
+	}
+	if _, ok := m["ts"]; !ok {
+		t.Errorf("Expected ts in %s", f.String())
+	}
+}
+
+func TestJSONWriter(t *testing.T) {
+	var f bytes.Buffer
+	w := JSONWriter(&f)
+
+	tests := []struct {
+		line  string
+		level string
+		msg   string
+	}{
+		{"[WARNING] Reloading\n", "warning", "Reloading"},
+		{"no level\n", "info", "no level"},
+		{"[BLA] unknown\n", "info", "[BLA] unknown"},
+		{`{"ts":"now","level":"error","msg":"as is"}` + "\n", "error", "as is"},
+	}
+	for i, tc := range tests {
+		f.Reset()
+		if _, err := w.Write([]byte(tc.line)); err != nil {
+			t.Fatalf("Test %d: Failed to write: %s", i, err)
+		}
+		m := map[string]interface{}{}
+		if err := json.Unmarshal(f.Bytes(), &m); err != nil {
+			t.Fatalf("Test %d: Expected JSON, got %s", i, f.String())
+		}
+		if m["level"] != tc.level || m["msg"] != tc.msg {
+			t.Errorf("Test %d: Expected level %s and msg %q, got %v and %q", i, tc.level, tc.msg, m["level"], m["msg"])
+		}
+	}
+}
@@ -0,0 +1,132 @@
+package log
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// Level is a log level, a logger outputs the lines on its level and above.
+type Level int
+
+// The log levels.
+const (
+	LevelDebug Level = iota
+	LevelInfo
+	LevelWarning
+	LevelError
+	LevelFatal
+)
+
+// String returns the name of the level.
+func (l Level) String() string {
+	switch l {
+	case LevelDebug:
+		return "debug"
+	case LevelInfo:
+		return "info"
+	case LevelWarning:
+		return "warning"
+	case LevelError:
+		return "error"
+	case LevelFatal:
+		return "fatal"
+	}
+	return fmt.Sprintf("level%d", int(l))
+}
+
+// prefix returns the prefix of the level for text output.
+func (l Level) prefix() string {
+	switch l {
+	case LevelDebug:
+		return debug
+	case LevelWarning:
+		return warning
+	case LevelError:
+		return err
+	case LevelFatal:
+		return fatal
+	}
+	return info
+}
+
+// ParseLevel parses the name of a level, the names are case insensitive. Fatal can't be set as a
+// level and isn't accepted.
+func ParseLevel(s string) (Level, error) {
+	switch strings.ToLower(s) {
+	case "debug":
+		return LevelDebug, nil
+	case "info":
+		return LevelInfo, nil
+	case "warning", "warn":
+		return LevelWarning, nil
+	case "error":
+		return LevelError, nil
+	}
+	return LevelInfo, fmt.Errorf("unknown log level: %q", s)
+}
+
+// levels holds the default level and the levels set for plugins. It is replaced, not changed, when
+// a level is set so it can be read without locking.
+type levels struct {
+	def     Level
+	plugins map[string]Level
+}
+
+var (
+	levelsMu sync.Mutex // serializes the setting of levels
+	current  atomic.Value
+)
+
+func init() { current.Store(&levels{def: LevelInfo, plugins: map[string]Level{}}) }
+
+// level returns the level of plugin. The level of a plugin that doesn't have one set is the default
+// level, or debug when D is true.
+func level(plugin string) Level {
+	lv := current.Load().(*levels)
+	if l, ok := lv.plugins[plugin]; ok {
+		return l
+	}
+	if D {
+		return LevelDebug
+	}
+	return lv.def
+}
+
+// SetLevel sets the level of plugin to l, when plugin is empty the default level is set. It can
+// be called at any time.
+func SetLevel(plugin string, l Level) {
+	update(func(lv *levels) {
+		if plugin == "" {
+			lv.def = l
+			return
+		}
+		lv.plugins[plugin] = l
+	})
+}
+
+// ResetLevel removes the level set for plugin, it will use the default level again.
+func ResetLevel(plugin string) {
+	update(func(lv *levels) { delete(lv.plugins, plugin) })
+}
+
+// Levels returns the default level and the levels set for plugins.
+func Levels() (Level, map[string]Level) {
+	lv := current.Load().(*levels)
+	plugins := make(map[string]Level, len(lv.plugins))
+	for p, l := range lv.plugins {
+		plugins[p] = l
+	}
+	return lv.def, plugins
+}
+
+// update calls f with a copy of the current levels and makes that copy current.
+func update(f func(*levels)) {
+	levelsMu.Lock()
+	defer levelsMu.Unlock()
+	def, plugins := Levels()
+	lv := &levels{def: def, plugins: plugins}
+	f(lv)
+	current.Store(lv)
+}
@@ -0,0 +1,71 @@
+package log
+
+import (
+	"bytes"
+	golog "log"
+	"testing"
+)
+
+func TestSetLevel(t *testing.T) {
+	var f bytes.Buffer
+	golog.SetOutput(&f)
+	D = false
+	defer SetLevel("", LevelInfo)
+
+	fwd := NewWithPlugin("forward")
+	cache := NewWithPlugin("cache")
+
+	SetLevel("", LevelWarning)
+	SetLevel("forward", LevelDebug)
+	fwd.Debug("debug")
+	cache.Info("info")
+	if f.Len() == 0 {
+		t.Errorf("Expected debug log for forward")
+	}
+	f.Reset()
+	cache.Info("info")
+	if f.Len() != 0 {
+		t.Errorf("Expected no info log for cache, got %s", f.String())
+	}
+
+	// D doesn't override the level of a plugin.
+	D = true
+	SetLevel("cache", LevelError)
+	cache.Warning("warning")
+	if f.Len() != 0 {
+		t.Errorf("Expected no warning log for cache, got %s", f.String())
+	}
+	D = false
+
+	ResetLevel("forward")
+	ResetLevel("cache")
+	def, plugins := Levels()
+	if def != LevelWarning || len(plugins) != 0 {
+		t.Errorf("Expected default level warning and no plugin levels, got %s and %v", def, plugins)
+	}
+}
+
+func TestParseLevel(t *testing.T) {
+	tests := []struct {
+		in       string
+		expected Level
+		err      bool
+	}{
+		{"debug", LevelDebug, false},
+		{"INFO", LevelInfo, false},
+		{"warn", LevelWarning, false},
+		{"warning", LevelWarning, false},
+		{"error", LevelError, false},
+		{"fatal", LevelInfo, true},
+		{"", LevelInfo, true},
+	}
+	for i, tc := range tests {
+		l, err := ParseLevel(tc.in)
+		if (err != nil) != tc.err {
+			t.Errorf("Test %d: Expected error %t, got %v", i, tc.err, err)
+		}
+		if err == nil && l != tc.expected {
+			t.Errorf("Test %d: Expected level %s, got %s", i, tc.expected, l)
+		}
+	}
+}
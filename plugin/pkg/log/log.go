@@ -6,6 +6,9 @@
 // log.Info("this is some logging"), will log on the Info level.
 //
 // log.Debug("this is debug output"), will log in the Debug level, etc.
+//
+// The logs can also be written as JSON, see SetFormat, and the level can be set for each plugin
+// at runtime, see SetLevel.
 package log
 
 import (
@@ -13,68 +16,95 @@ import (
 	"io/ioutil"
 	golog "log"
 	"os"
+	"sync/atomic"
 	"time"
 )
 
-// D controls whether we should output debug logs. If true, we do.
+// D controls whether we should output debug logs. If true, we do, for the plugins that
+// don't have their level set.
 var D bool
 
 // RFC3339Milli doesn't exist, invent it here.
 func clock() string { return time.Now().Format("2006-01-02T15:04:05.000Z07:00") }
 
-// logf calls log.Printf prefixed with level.
-func logf(level, format string, v ...interface{}) {
-	golog.Print(clock(), level, fmt.Sprintf(format, v...))
-}
+// Format is the format of the log lines.
+type Format int32
+
+const (
+	// Text writes lines as "TIME [LEVEL] plugin/NAME: MESSAGE key=value", the default.
+	Text Format = iota
+	// JSON writes a JSON object per line, with the fields ts, level, plugin, msg and the key/value
+	// pairs of the logger.
+	JSON
+)
+
+var outFormat int32
 
-// log calls log.Print prefixed with level.
-func log(level string, v ...interface{}) {
-	golog.Print(clock(), level, fmt.Sprint(v...))
+// SetFormat sets the format of the log lines.
+func SetFormat(f Format) { atomic.StoreInt32(&outFormat, int32(f)) }
+
+// logf formats v and logs it on level l for plugin, if that level is enabled. Plugin is empty
+// when the logging isn't done by a plugin.
+func logf(l Level, plugin string, fields []interface{}, format string, v ...interface{}) {
+	if l < level(plugin) {
+		return
+	}
+	write(l, plugin, fields, fmt.Sprintf(format, v...))
 }
 
-// Debug is equivalent to log.Print(), but prefixed with "[DEBUG] ". It only outputs something
-// if D is true.
-func Debug(v ...interface{}) {
-	if !D {
+// log is like logf, but formats v as fmt.Sprint does.
+func log(l Level, plugin string, fields []interface{}, v ...interface{}) {
+	if l < level(plugin) {
 		return
 	}
-	log(debug, v...)
+	write(l, plugin, fields, fmt.Sprint(v...))
 }
 
-// Debugf is equivalent to log.Printf(), but prefixed with "[DEBUG] ". It only outputs something
-// if D is true.
-func Debugf(format string, v ...interface{}) {
-	if !D {
+// write writes msg in the current format.
+func write(l Level, plugin string, fields []interface{}, msg string) {
+	if Format(atomic.LoadInt32(&outFormat)) == JSON {
+		golog.Print(jsonLine(l, plugin, fields, msg))
 		return
 	}
-	logf(debug, format, v...)
+	if plugin != "" {
+		plugin = "plugin/" + plugin + ": "
+	}
+	golog.Print(clock(), l.prefix(), plugin, msg, textFields(fields))
 }
 
+// Debug is equivalent to log.Print(), but prefixed with "[DEBUG] ". It only outputs something
+// if D is true or the level is set to debug.
+func Debug(v ...interface{}) { log(LevelDebug, "", nil, v...) }
+
+// Debugf is equivalent to log.Printf(), but prefixed with "[DEBUG] ". It only outputs something
+// if D is true or the level is set to debug.
+func Debugf(format string, v ...interface{}) { logf(LevelDebug, "", nil, format, v...) }
+
 // Info is equivalent to log.Print, but prefixed with "[INFO] ".
-func Info(v ...interface{}) { log(info, v...) }
+func Info(v ...interface{}) { log(LevelInfo, "", nil, v...) }
 
 // Infof is equivalent to log.Printf, but prefixed with "[INFO] ".
-func Infof(format string, v ...interface{}) { logf(info, format, v...) }
+func Infof(format string, v ...interface{}) { logf(LevelInfo, "", nil, format, v...) }
 
 // Warning is equivalent to log.Print, but prefixed with "[WARNING] ".
-func Warning(v ...interface{}) { log(warning, v...) }
+func Warning(v ...interface{}) { log(LevelWarning, "", nil, v...) }
 
 // Warningf is equivalent to log.Printf, but prefixed with "[WARNING] ".
-func Warningf(format string, v ...interface{}) { logf(warning, format, v...) }
+func Warningf(format string, v ...interface{}) { logf(LevelWarning, "", nil, format, v...) }
 
 // Error is equivalent to log.Print, but prefixed with "[ERROR] ".
-func Error(v ...interface{}) { log(err, v...) }
+func Error(v ...interface{}) { log(LevelError, "", nil, v...) }
 
 // Errorf is equivalent to log.Printf, but prefixed with "[ERROR] ".
-func Errorf(format string, v ...interface{}) { logf(err, format, v...) }
+func Errorf(format string, v ...interface{}) { logf(LevelError, "", nil, format, v...) }
 
 // Fatal is equivalent to log.Print, but prefixed with "[FATAL] ", and calling
 // os.Exit(1).
-func Fatal(v ...interface{}) { log(fatal, v...); os.Exit(1) }
+func Fatal(v ...interface{}) { log(LevelFatal, "", nil, v...); os.Exit(1) }
 
 // Fatalf is equivalent to log.Printf, but prefixed with "[FATAL] ", and calling
 // os.Exit(1)
-func Fatalf(format string, v ...interface{}) { logf(fatal, format, v...); os.Exit(1) }
+func Fatalf(format string, v ...interface{}) { logf(LevelFatal, "", nil, format, v...); os.Exit(1) }
 
 // Discard sets the log output to /dev/null.
 func Discard() { golog.SetOutput(ioutil.Discard) }
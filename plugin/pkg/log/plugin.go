@@ -1,63 +1,63 @@
 package log
 
-import (
-	"fmt"
-	"os"
-)
+import "os"
 
 // P is a logger that includes the plugin doing the logging.
 type P struct {
 	plugin string
+	fields []interface{}
 }
 
 // NewWithPlugin returns a logger that includes "plugin/name: " in the log message.
 // I.e [INFO] plugin/<name>: message.
-func NewWithPlugin(name string) P { return P{"plugin/" + name + ": "} }
+func NewWithPlugin(name string) P { return P{plugin: name} }
+
+// With returns a logger, not tied to a plugin, that adds the key/value pairs in keyvals to the log
+// messages. The server uses this to log the address it serves on as "server".
+func With(keyvals ...interface{}) P { return P{fields: keyvals} }
+
+// With returns a logger that adds the key/value pairs in keyvals to the log messages,
+// I.e. log.With("server", "dns://:53").Info("message").
+func (p P) With(keyvals ...interface{}) P {
+	fields := make([]interface{}, 0, len(p.fields)+len(keyvals))
+	fields = append(fields, p.fields...)
+	return P{plugin: p.plugin, fields: append(fields, keyvals...)}
+}
 
-func (p P) logf(level, format string, v ...interface{}) {
-	log(level, p.plugin, fmt.Sprintf(format, v...))
+func (p P) logf(level Level, format string, v ...interface{}) {
+	logf(level, p.plugin, p.fields, format, v...)
 }
 
-func (p P) log(level string, v ...interface{}) {
-	log(level+p.plugin, v...)
+func (p P) log(level Level, v ...interface{}) {
+	log(level, p.plugin, p.fields, v...)
 }
 
 // Debug logs as log.Debug.
-func (p P) Debug(v ...interface{}) {
-	if !D {
-		return
-	}
-	p.log(debug, v...)
-}
+func (p P) Debug(v ...interface{}) { p.log(LevelDebug, v...) }
 
 // Debugf logs as log.Debugf.
-func (p P) Debugf(format string, v ...interface{}) {
-	if !D {
-		return
-	}
-	p.logf(debug, format, v...)
-}
+func (p P) Debugf(format string, v ...interface{}) { p.logf(LevelDebug, format, v...) }
 
 // Info logs as log.Info.
-func (p P) Info(v ...interface{}) { p.log(info, v...) }
+func (p P) Info(v ...interface{}) { p.log(LevelInfo, v...) }
 
 // Infof logs as log.Infof.
-func (p P) Infof(format string, v ...interface{}) { p.logf(info, format, v...) }
+func (p P) Infof(format string, v ...interface{}) { p.logf(LevelInfo, format, v...) }
 
 // Warning logs as log.Warning.
-func (p P) Warning(v ...interface{}) { p.log(warning, v...) }
+func (p P) Warning(v ...interface{}) { p.log(LevelWarning, v...) }
 
 // Warningf logs as log.Warningf.
-func (p P) Warningf(format string, v ...interface{}) { p.logf(warning, format, v...) }
+func (p P) Warningf(format string, v ...interface{}) { p.logf(LevelWarning, format, v...) }
 
 // Error logs as log.Error.
-func (p P) Error(v ...interface{}) { p.log(err, v...) }
+func (p P) Error(v ...interface{}) { p.log(LevelError, v...) }
 
 // Errorf logs as log.Errorf.
-func (p P) Errorf(format string, v ...interface{}) { p.logf(err, format, v...) }
+func (p P) Errorf(format string, v ...interface{}) { p.logf(LevelError, format, v...) }
 
 // Fatal logs as log.Fatal and calls os.Exit(1).
-func (p P) Fatal(v ...interface{}) { p.log(fatal, v...); os.Exit(1) }
+func (p P) Fatal(v ...interface{}) { p.log(LevelFatal, v...); os.Exit(1) }
 
 // Fatalf logs as log.Fatalf and calls os.Exit(1).
-func (p P) Fatalf(format string, v ...interface{}) { p.logf(fatal, format, v...); os.Exit(1) }
+func (p P) Fatalf(format string, v ...interface{}) { p.logf(LevelFatal, format, v...); os.Exit(1) }
@@ -35,3 +35,24 @@ func TestPluginsDateTime(t *testing.T) {
 		t.Errorf("Expected date got %s...", str[:15])
 	}
 }
+
+func TestPluginsWith(t *testing.T) {
+	var f bytes.Buffer
+	golog.SetOutput(&f)
+
+	lg := NewWithPlugin("testplugin").With("server", "dns://:53")
+	lg.With("zone", "example.org.").Info("test")
+	if x := f.String(); !strings.HasSuffix(x, info+"plugin/testplugin: test server=dns://:53 zone=example.org.\n") {
+		t.Errorf("Expected log with key/value pairs, got %s", x)
+	}
+}
+
+func TestWith(t *testing.T) {
+	var f bytes.Buffer
+	golog.SetOutput(&f)
+
+	With("server", "dns://:53").Warning("test")
+	if x := f.String(); !strings.HasSuffix(x, warning+"test server=dns://:53\n") {
+		t.Errorf("Expected log with key/value pairs and without plugin, got %s", x)
+	}
+}
@@ -0,0 +1,27 @@
+// Package mmsg reads and writes batches of UDP messages with a single system call. On Linux
+// recvmmsg(2) and sendmmsg(2) are used, other platforms are not supported.
+//
+// The local address a message was sent to is returned with it, so replies can be sent from that
+// address when the socket is bound to a wildcard address. The number of messages the kernel
+// dropped because the receive buffer of the socket was full is kept as well.
+package mmsg
+
+import (
+	"errors"
+	"net"
+)
+
+// Message is a UDP message.
+type Message struct {
+	// Buf holds the message in Buf[:N]. When reading, the message is read into Buf.
+	Buf []byte
+	N   int
+	// Addr is the remote address.
+	Addr *net.UDPAddr
+	// Dst is the local address the message was sent to, when writing the message is sent from
+	// it. It is nil when unknown.
+	Dst net.IP
+}
+
+// ErrNotSupported is returned by New when batches can't be read and written on this platform.
+var ErrNotSupported = errors.New("batched UDP I/O not supported")
@@ -0,0 +1,293 @@
+// +build linux
+
+package mmsg
+
+import (
+	"net"
+	"os"
+	"strconv"
+	"sync/atomic"
+	"syscall"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+// Conn reads and writes batches of messages on a UDP socket. ReadBatch and WriteBatch may be
+// called concurrently with each other, but not with themselves.
+type Conn struct {
+	*net.UDPConn
+	rc     syscall.RawConn
+	family int    // address family of the socket
+	drops  uint32 // the kernel's drop counter of the socket, updated atomically
+
+	r, w batch
+}
+
+// mmsghdr is struct mmsghdr of recvmmsg(2).
+type mmsghdr struct {
+	hdr unix.Msghdr
+	len uint32
+}
+
+// batch holds the headers of a batch of messages, they are reused between calls.
+type batch struct {
+	hdrs  []mmsghdr
+	iovs  []unix.Iovec
+	names []unix.RawSockaddrAny
+	oobs  [][oobSize]byte
+}
+
+// grow makes sure b has room for n messages.
+func (b *batch) grow(n int) {
+	if len(b.hdrs) >= n {
+		return
+	}
+	b.hdrs = make([]mmsghdr, n)
+	b.iovs = make([]unix.Iovec, n)
+	b.names = make([]unix.RawSockaddrAny, n)
+	b.oobs = make([][oobSize]byte, n)
+}
+
+// New returns a Conn for c.
+func New(c *net.UDPConn) (*Conn, error) {
+	rc, err := c.SyscallConn()
+	if err != nil {
+		return nil, err
+	}
+
+	var family int
+	var serr error
+	err = rc.Control(func(fd uintptr) {
+		family, serr = unix.GetsockoptInt(int(fd), unix.SOL_SOCKET, unix.SO_DOMAIN)
+		if serr != nil {
+			return
+		}
+		// Learn the local address of the messages. A dual-stack IPv6 socket also receives IPv4
+		// messages, so set both. Errors are ignored, without these the local address is unknown.
+		unix.SetsockoptInt(int(fd), unix.IPPROTO_IP, unix.IP_PKTINFO, 1)
+		if family == unix.AF_INET6 {
+			unix.SetsockoptInt(int(fd), unix.IPPROTO_IPV6, unix.IPV6_RECVPKTINFO, 1)
+		}
+		unix.SetsockoptInt(int(fd), unix.SOL_SOCKET, unix.SO_RXQ_OVFL, 1)
+	})
+	if err != nil {
+		return nil, err
+	}
+	if serr != nil {
+		return nil, os.NewSyscallError("getsockopt", serr)
+	}
+	if family != unix.AF_INET && family != unix.AF_INET6 {
+		return nil, ErrNotSupported
+	}
+	return &Conn{UDPConn: c, rc: rc, family: family}, nil
+}
+
+// ReadBatch reads at least one message, it blocks until one is available, and at most len(ms). It
+// returns the number of messages read.
+func (c *Conn) ReadBatch(ms []Message) (int, error) {
+	if len(ms) == 0 {
+		return 0, nil
+	}
+	b := &c.r
+	b.grow(len(ms))
+	for i := range ms {
+		b.iovs[i].Base = &ms[i].Buf[0]
+		b.iovs[i].SetLen(len(ms[i].Buf))
+		h := &b.hdrs[i].hdr
+		h.Name = (*byte)(unsafe.Pointer(&b.names[i]))
+		h.Namelen = unix.SizeofSockaddrAny
+		h.Iov = &b.iovs[i]
+		h.Iovlen = 1
+		h.Control = &b.oobs[i][0]
+		h.SetControllen(oobSize)
+		b.hdrs[i].len = 0
+	}
+
+	var n int
+	var errno syscall.Errno
+	err := c.rc.Read(func(fd uintptr) bool {
+		r, _, e := unix.Syscall6(unix.SYS_RECVMMSG, fd, uintptr(unsafe.Pointer(&b.hdrs[0])), uintptr(len(ms)), 0, 0, 0)
+		if e == unix.EAGAIN || e == unix.EINTR {
+			return false
+		}
+		n, errno = int(r), e
+		return true
+	})
+	if err != nil {
+		return 0, err
+	}
+	if errno != 0 {
+		return 0, os.NewSyscallError("recvmmsg", errno)
+	}
+
+	for i := 0; i < n; i++ {
+		ms[i].N = int(b.hdrs[i].len)
+		ms[i].Addr = udpAddr(&b.names[i])
+		ms[i].Dst = nil
+		c.parseControl(&ms[i], b.oobs[i][:int(b.hdrs[i].hdr.Controllen)])
+	}
+	return n, nil
+}
+
+// WriteBatch writes the messages in ms and returns the number of messages written. It only returns
+// less than len(ms) on error.
+func (c *Conn) WriteBatch(ms []Message) (int, error) {
+	if len(ms) == 0 {
+		return 0, nil
+	}
+	b := &c.w
+	b.grow(len(ms))
+	for i := range ms {
+		b.iovs[i].Base = &ms[i].Buf[0]
+		b.iovs[i].SetLen(ms[i].N)
+		h := &b.hdrs[i].hdr
+		h.Name = (*byte)(unsafe.Pointer(&b.names[i]))
+		h.Namelen = c.putAddr(&b.names[i], ms[i].Addr)
+		h.Iov = &b.iovs[i]
+		h.Iovlen = 1
+		h.Control = nil
+		h.SetControllen(0)
+		if l := putPktinfo(b.oobs[i][:], ms[i].Dst); l > 0 {
+			h.Control = &b.oobs[i][0]
+			h.SetControllen(l)
+		}
+		b.hdrs[i].len = 0
+	}
+
+	sent := 0
+	for sent < len(ms) {
+		var n int
+		var errno syscall.Errno
+		err := c.rc.Write(func(fd uintptr) bool {
+			r, _, e := unix.Syscall6(unix.SYS_SENDMMSG, fd, uintptr(unsafe.Pointer(&b.hdrs[sent])), uintptr(len(ms)-sent), 0, 0, 0)
+			if e == unix.EAGAIN || e == unix.EINTR {
+				return false
+			}
+			n, errno = int(r), e
+			return true
+		})
+		if err != nil {
+			return sent, err
+		}
+		if errno != 0 {
+			return sent, os.NewSyscallError("sendmmsg", errno)
+		}
+		sent += n
+	}
+	return sent, nil
+}
+
+// Drops returns the number of messages the kernel dropped because the receive buffer of the socket
+// was full, as of the last message read. It is 0 when the kernel doesn't report it.
+func (c *Conn) Drops() uint32 { return atomic.LoadUint32(&c.drops) }
+
+// parseControl sets the local address of m from its control messages, and records the drop counter.
+func (c *Conn) parseControl(m *Message, oob []byte) {
+	cmsgs, err := unix.ParseSocketControlMessage(oob)
+	if err != nil {
+		return
+	}
+	for _, cm := range cmsgs {
+		switch {
+		case cm.Header.Level == unix.IPPROTO_IP && cm.Header.Type == unix.IP_PKTINFO && len(cm.Data) >= unix.SizeofInet4Pktinfo:
+			pi := (*unix.Inet4Pktinfo)(unsafe.Pointer(&cm.Data[0]))
+			m.Dst = net.IPv4(pi.Addr[0], pi.Addr[1], pi.Addr[2], pi.Addr[3])
+		case cm.Header.Level == unix.IPPROTO_IPV6 && cm.Header.Type == unix.IPV6_PKTINFO && len(cm.Data) >= unix.SizeofInet6Pktinfo:
+			pi := (*unix.Inet6Pktinfo)(unsafe.Pointer(&cm.Data[0]))
+			m.Dst = make(net.IP, net.IPv6len)
+			copy(m.Dst, pi.Addr[:])
+		case cm.Header.Level == unix.SOL_SOCKET && cm.Header.Type == unix.SO_RXQ_OVFL && len(cm.Data) >= 4:
+			atomic.StoreUint32(&c.drops, *(*uint32)(unsafe.Pointer(&cm.Data[0])))
+		}
+	}
+}
+
+// putAddr writes addr as a socket address of the socket's family to sa and returns its length.
+func (c *Conn) putAddr(sa *unix.RawSockaddrAny, addr *net.UDPAddr) uint32 {
+	if c.family == unix.AF_INET {
+		sa4 := (*unix.RawSockaddrInet4)(unsafe.Pointer(sa))
+		*sa4 = unix.RawSockaddrInet4{Family: unix.AF_INET}
+		putPort(&sa4.Port, addr.Port)
+		copy(sa4.Addr[:], addr.IP.To4())
+		return unix.SizeofSockaddrInet4
+	}
+	sa6 := (*unix.RawSockaddrInet6)(unsafe.Pointer(sa))
+	*sa6 = unix.RawSockaddrInet6{Family: unix.AF_INET6}
+	putPort(&sa6.Port, addr.Port)
+	copy(sa6.Addr[:], addr.IP.To16())
+	sa6.Scope_id = zoneIndex(addr.Zone)
+	return unix.SizeofSockaddrInet6
+}
+
+// udpAddr returns the UDP address in sa.
+func udpAddr(sa *unix.RawSockaddrAny) *net.UDPAddr {
+	switch sa.Addr.Family {
+	case unix.AF_INET:
+		sa4 := (*unix.RawSockaddrInet4)(unsafe.Pointer(sa))
+		return &net.UDPAddr{IP: net.IPv4(sa4.Addr[0], sa4.Addr[1], sa4.Addr[2], sa4.Addr[3]), Port: port(&sa4.Port)}
+	case unix.AF_INET6:
+		sa6 := (*unix.RawSockaddrInet6)(unsafe.Pointer(sa))
+		addr := &net.UDPAddr{IP: make(net.IP, net.IPv6len), Port: port(&sa6.Port)}
+		copy(addr.IP, sa6.Addr[:])
+		if sa6.Scope_id != 0 {
+			addr.Zone = strconv.Itoa(int(sa6.Scope_id))
+		}
+		return addr
+	}
+	return &net.UDPAddr{}
+}
+
+// putPktinfo writes a control message that sends a message from dst to b, and returns its length.
+// Nothing is written when dst is nil.
+func putPktinfo(b []byte, dst net.IP) int {
+	if dst == nil {
+		return 0
+	}
+	h := (*unix.Cmsghdr)(unsafe.Pointer(&b[0]))
+	if ip4 := dst.To4(); ip4 != nil {
+		h.Level = unix.IPPROTO_IP
+		h.Type = unix.IP_PKTINFO
+		h.SetLen(unix.CmsgLen(unix.SizeofInet4Pktinfo))
+		pi := (*unix.Inet4Pktinfo)(unsafe.Pointer(&b[unix.CmsgLen(0)]))
+		*pi = unix.Inet4Pktinfo{}
+		copy(pi.Spec_dst[:], ip4)
+		return unix.CmsgSpace(unix.SizeofInet4Pktinfo)
+	}
+	h.Level = unix.IPPROTO_IPV6
+	h.Type = unix.IPV6_PKTINFO
+	h.SetLen(unix.CmsgLen(unix.SizeofInet6Pktinfo))
+	pi := (*unix.Inet6Pktinfo)(unsafe.Pointer(&b[unix.CmsgLen(0)]))
+	*pi = unix.Inet6Pktinfo{}
+	copy(pi.Addr[:], dst.To16())
+	return unix.CmsgSpace(unix.SizeofInet6Pktinfo)
+}
+
+// port returns the port p, which is in network byte order.
+func port(p *uint16) int {
+	b := (*[2]byte)(unsafe.Pointer(p))
+	return int(b[0])<<8 | int(b[1])
+}
+
+// putPort sets p to port in network byte order.
+func putPort(p *uint16, port int) {
+	b := (*[2]byte)(unsafe.Pointer(p))
+	b[0], b[1] = byte(port>>8), byte(port)
+}
+
+// zoneIndex returns the interface index of an IPv6 zone, which is an index or an interface name.
+func zoneIndex(zone string) uint32 {
+	if zone == "" {
+		return 0
+	}
+	if i, err := strconv.Atoi(zone); err == nil {
+		return uint32(i)
+	}
+	if ifi, err := net.InterfaceByName(zone); err == nil {
+		return uint32(ifi.Index)
+	}
+	return 0
+}
+
+// oobSize is the room for the control messages of a message: the packet info and the drop counter.
+const oobSize = 128
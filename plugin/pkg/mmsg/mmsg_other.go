@@ -0,0 +1,22 @@
+// +build !linux
+
+package mmsg
+
+import "net"
+
+// Conn reads and writes batches of messages on a UDP socket.
+type Conn struct {
+	*net.UDPConn
+}
+
+// New returns ErrNotSupported.
+func New(c *net.UDPConn) (*Conn, error) { return nil, ErrNotSupported }
+
+// ReadBatch returns ErrNotSupported.
+func (c *Conn) ReadBatch(ms []Message) (int, error) { return 0, ErrNotSupported }
+
+// WriteBatch returns ErrNotSupported.
+func (c *Conn) WriteBatch(ms []Message) (int, error) { return 0, ErrNotSupported }
+
+// Drops returns 0.
+func (c *Conn) Drops() uint32 { return 0 }
@@ -0,0 +1,101 @@
+package mmsg
+
+import (
+	"bytes"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestBatch(t *testing.T) {
+	tests := []struct {
+		network string
+		laddr   string
+		dst     string
+	}{
+		{"udp4", "127.0.0.1:0", "127.0.0.1"},
+		{"udp4", "0.0.0.0:0", "127.0.0.1"},
+		{"udp6", "[::1]:0", "::1"},
+	}
+
+	for i, tc := range tests {
+		srv, err := net.ListenPacket(tc.network, tc.laddr)
+		if err != nil {
+			t.Logf("Test %d: skipping, failed to listen on %s: %s", i, tc.laddr, err)
+			continue
+		}
+		defer srv.Close()
+
+		c, err := New(srv.(*net.UDPConn))
+		if err == ErrNotSupported {
+			t.Skip("batched UDP I/O not supported")
+		}
+		if err != nil {
+			t.Fatalf("Test %d: expected no error, got %s", i, err)
+		}
+
+		port := srv.LocalAddr().(*net.UDPAddr).Port
+		to := &net.UDPAddr{IP: net.ParseIP(tc.dst), Port: port}
+		clnt, err := net.DialUDP(tc.network, nil, to)
+		if err != nil {
+			t.Fatalf("Test %d: failed to dial: %s", i, err)
+		}
+		defer clnt.Close()
+
+		payloads := [][]byte{[]byte("one"), []byte("two"), []byte("three")}
+		for _, p := range payloads {
+			if _, err := clnt.Write(p); err != nil {
+				t.Fatalf("Test %d: failed to write: %s", i, err)
+			}
+		}
+
+		ms := make([]Message, 8)
+		for j := range ms {
+			ms[j].Buf = make([]byte, 512)
+		}
+		got := 0
+		srv.SetReadDeadline(time.Now().Add(2 * time.Second))
+		for got < len(payloads) {
+			n, err := c.ReadBatch(ms[got:])
+			if err != nil {
+				t.Fatalf("Test %d: expected no error, got %s", i, err)
+			}
+			got += n
+		}
+		for j, p := range payloads {
+			if !bytes.Equal(ms[j].Buf[:ms[j].N], p) {
+				t.Errorf("Test %d: expected message %q, got %q", i, p, ms[j].Buf[:ms[j].N])
+			}
+			if ms[j].Addr.Port != clnt.LocalAddr().(*net.UDPAddr).Port {
+				t.Errorf("Test %d: expected port %d, got %d", i, clnt.LocalAddr().(*net.UDPAddr).Port, ms[j].Addr.Port)
+			}
+			if !ms[j].Dst.Equal(to.IP) {
+				t.Errorf("Test %d: expected destination %s, got %s", i, to.IP, ms[j].Dst)
+			}
+		}
+
+		// Echo the messages back, uppercased.
+		for j := range payloads {
+			copy(ms[j].Buf, bytes.ToUpper(ms[j].Buf[:ms[j].N]))
+		}
+		n, err := c.WriteBatch(ms[:len(payloads)])
+		if err != nil {
+			t.Fatalf("Test %d: expected no error, got %s", i, err)
+		}
+		if n != len(payloads) {
+			t.Fatalf("Test %d: expected %d messages written, got %d", i, len(payloads), n)
+		}
+
+		clnt.SetReadDeadline(time.Now().Add(2 * time.Second))
+		buf := make([]byte, 512)
+		for _, p := range payloads {
+			n, err := clnt.Read(buf)
+			if err != nil {
+				t.Fatalf("Test %d: failed to read: %s", i, err)
+			}
+			if !bytes.Equal(buf[:n], bytes.ToUpper(p)) {
+				t.Errorf("Test %d: expected reply %q, got %q", i, bytes.ToUpper(p), buf[:n])
+			}
+		}
+	}
+}
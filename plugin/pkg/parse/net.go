@@ -0,0 +1,47 @@
+package parse
+
+import (
+	"fmt"
+	"net"
+	"strings"
+)
+
+// Nets parses the networks in s, in CIDR notation. A plain address is taken as a host network
+// and "*" stands for all IPv4 and IPv6 addresses.
+func Nets(s ...string) ([]*net.IPNet, error) {
+	if len(s) == 0 {
+		return nil, fmt.Errorf("no network given")
+	}
+	nets := make([]*net.IPNet, 0, len(s))
+	for _, a := range s {
+		if a == "*" {
+			nets = append(nets, all...)
+			continue
+		}
+		cidr := a
+		if !strings.Contains(a, "/") {
+			ip := net.ParseIP(a)
+			if ip == nil {
+				return nil, fmt.Errorf("invalid network %q", a)
+			}
+			if ip.To4() != nil {
+				cidr += "/32"
+			} else {
+				cidr += "/128"
+			}
+		}
+		_, n, err := net.ParseCIDR(cidr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid network %q", a)
+		}
+		nets = append(nets, n)
+	}
+	return nets, nil
+}
+
+// all holds the networks that cover all IPv4 and IPv6 addresses.
+var all = func() []*net.IPNet {
+	_, v4, _ := net.ParseCIDR("0.0.0.0/0")
+	_, v6, _ := net.ParseCIDR("::/0")
+	return []*net.IPNet{v4, v6}
+}()
@@ -0,0 +1,42 @@
+package parse
+
+import (
+	"testing"
+)
+
+func TestNets(t *testing.T) {
+	for i, test := range []struct {
+		input     []string
+		expected  []string
+		shouldErr bool
+	}{
+		{[]string{"10.0.0.0/8"}, []string{"10.0.0.0/8"}, false},
+		{[]string{"10.1.2.3"}, []string{"10.1.2.3/32"}, false},
+		{[]string{"2001:db8::1", "2001:db8::/32"}, []string{"2001:db8::1/128", "2001:db8::/32"}, false},
+		{[]string{"*"}, []string{"0.0.0.0/0", "::/0"}, false},
+		{[]string{}, nil, true},
+		{[]string{"example.org"}, nil, true},
+		{[]string{"10.0.0.0/33"}, nil, true},
+	} {
+		nets, err := Nets(test.input...)
+		if test.shouldErr {
+			if err == nil {
+				t.Errorf("Test %d: Expected error for %v, got none", i, test.input)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Test %d: Expected no error, got %s", i, err)
+			continue
+		}
+		if len(nets) != len(test.expected) {
+			t.Errorf("Test %d: Expected %d networks, got %d", i, len(test.expected), len(nets))
+			continue
+		}
+		for j, n := range nets {
+			if n.String() != test.expected[j] {
+				t.Errorf("Test %d: Expected network %s, got %s", i, test.expected[j], n)
+			}
+		}
+	}
+}
@@ -0,0 +1,102 @@
+package proxyproto
+
+import (
+	"bufio"
+	"net"
+	"sync"
+	"time"
+)
+
+// Listener is a net.Listener that reads the PROXY header of the connections it accepts.
+type Listener struct {
+	net.Listener
+	config *Config
+}
+
+// NewListener returns a Listener that reads PROXY headers of connections made from the trusted
+// sources in config.
+func NewListener(l net.Listener, config *Config) *Listener {
+	return &Listener{Listener: l, config: config}
+}
+
+// Accept implements the net.Listener interface. The PROXY header is read when the connection is
+// first used, so a slow client doesn't block accepting connections.
+func (l *Listener) Accept() (net.Conn, error) {
+	c, err := l.Listener.Accept()
+	if err != nil {
+		return c, err
+	}
+	return &Conn{Conn: c, config: l.config}, nil
+}
+
+// Conn is a net.Conn that returns the client's address from the PROXY header as its remote address.
+type Conn struct {
+	net.Conn
+	config *Config
+
+	once  sync.Once
+	r     *bufio.Reader
+	raddr net.Addr
+	err   error
+
+	mu       sync.Mutex
+	deadline time.Time // read deadline set by the user of the connection
+}
+
+// Read implements the net.Conn interface.
+func (c *Conn) Read(b []byte) (int, error) {
+	c.once.Do(c.readHeader)
+	if c.err != nil {
+		return 0, c.err
+	}
+	return c.r.Read(b)
+}
+
+// RemoteAddr implements the net.Conn interface.
+func (c *Conn) RemoteAddr() net.Addr {
+	c.once.Do(c.readHeader)
+	if c.raddr != nil {
+		return c.raddr
+	}
+	return c.Conn.RemoteAddr()
+}
+
+// SetDeadline implements the net.Conn interface.
+func (c *Conn) SetDeadline(t time.Time) error {
+	c.mu.Lock()
+	c.deadline = t
+	c.mu.Unlock()
+	return c.Conn.SetDeadline(t)
+}
+
+// SetReadDeadline implements the net.Conn interface.
+func (c *Conn) SetReadDeadline(t time.Time) error {
+	c.mu.Lock()
+	c.deadline = t
+	c.mu.Unlock()
+	return c.Conn.SetReadDeadline(t)
+}
+
+// readHeader reads the PROXY header when the connection comes from a trusted source. When this
+// fails the connection is closed, as the client's address is unknown.
+func (c *Conn) readHeader() {
+	c.r = bufio.NewReader(c.Conn)
+	if !c.config.Trusted(c.Conn.RemoteAddr()) {
+		return
+	}
+
+	c.Conn.SetReadDeadline(time.Now().Add(c.config.Timeout))
+	ip, port, err := readHeader(c.r)
+	c.mu.Lock()
+	c.Conn.SetReadDeadline(c.deadline)
+	c.mu.Unlock()
+
+	if err != nil {
+		c.err = err
+		c.Conn.Close()
+		return
+	}
+	if ip != nil {
+		c.raddr = &net.TCPAddr{IP: ip, Port: port}
+	}
+}
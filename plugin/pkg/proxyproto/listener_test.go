@@ -0,0 +1,60 @@
+package proxyproto
+
+import (
+	"io/ioutil"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestListener(t *testing.T) {
+	local := &Config{Allow: []*net.IPNet{{IP: net.ParseIP("127.0.0.0"), Mask: net.CIDRMask(8, 32)}}, Timeout: time.Second}
+
+	tests := []struct {
+		send   string
+		config *Config
+		raddr  string // expected remote address, empty for the real one
+		data   string // expected data, empty when the connection should be closed
+	}{
+		{"PROXY TCP4 192.0.2.1 192.0.2.53 53124 53\r\nquery", local, "192.0.2.1:53124", "query"},
+		{"query", local, "", ""},
+		// Untrusted sources are served as is.
+		{"PROXY TCP4 192.0.2.1 192.0.2.53 53124 53\r\nquery", &Config{Allow: []*net.IPNet{{IP: net.ParseIP("10.0.0.0"), Mask: net.CIDRMask(8, 32)}}, Timeout: time.Second}, "", "PROXY TCP4 192.0.2.1 192.0.2.53 53124 53\r\nquery"},
+	}
+
+	for i, tc := range tests {
+		l, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatalf("Failed to listen: %s", err)
+		}
+		pl := NewListener(l, tc.config)
+
+		go func() {
+			c, err := net.Dial("tcp", l.Addr().String())
+			if err != nil {
+				return
+			}
+			c.Write([]byte(tc.send))
+			c.Close()
+		}()
+
+		c, err := pl.Accept()
+		if err != nil {
+			t.Fatalf("Test %d: failed to accept: %s", i, err)
+		}
+		raddr := c.RemoteAddr().String()
+		data, _ := ioutil.ReadAll(c)
+		c.Close()
+		pl.Close()
+
+		if tc.raddr != "" && raddr != tc.raddr {
+			t.Errorf("Test %d: expected remote address %s, got %s", i, tc.raddr, raddr)
+		}
+		if tc.raddr == "" && raddr == "192.0.2.1:53124" {
+			t.Errorf("Test %d: expected the real remote address, got %s", i, raddr)
+		}
+		if string(data) != tc.data {
+			t.Errorf("Test %d: expected data %q, got %q", i, tc.data, data)
+		}
+	}
+}
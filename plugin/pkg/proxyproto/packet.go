@@ -0,0 +1,24 @@
+package proxyproto
+
+import (
+	"net"
+)
+
+// Packet strips the version 2 PROXY header from the UDP packet b received from addr, version 1
+// isn't defined for datagrams. It returns the packet without the header and the address of the
+// client, which is addr itself when addr isn't trusted or the header doesn't carry an address.
+// When a trusted source sent a packet without a (valid) header, false is returned and the packet
+// must be dropped.
+func (c *Config) Packet(b []byte, addr *net.UDPAddr) ([]byte, *net.UDPAddr, bool) {
+	if !c.Trusted(addr) {
+		return b, addr, true
+	}
+	ip, port, n, err := parseV2(b)
+	if err != nil {
+		return nil, nil, false
+	}
+	if ip == nil {
+		return b[n:], addr, true
+	}
+	return b[n:], &net.UDPAddr{IP: ip, Port: port}, true
+}
@@ -0,0 +1,46 @@
+package proxyproto
+
+import (
+	"net"
+	"testing"
+)
+
+func TestPacket(t *testing.T) {
+	c := &Config{Allow: []*net.IPNet{{IP: net.ParseIP("10.0.0.0"), Mask: net.CIDRMask(8, 32)}}}
+	proxy := &net.UDPAddr{IP: net.ParseIP("10.0.0.1"), Port: 4242}
+	other := &net.UDPAddr{IP: net.ParseIP("192.0.2.2"), Port: 4242}
+	header := v2Header(&net.TCPAddr{IP: net.ParseIP("192.0.2.1"), Port: 53124}, true)
+	local := append(append([]byte{}, v2Signature...), 0x20, 0x00, 0x00, 0x00)
+
+	tests := []struct {
+		packet []byte
+		addr   *net.UDPAddr
+		ok     bool
+		raddr  string
+	}{
+		{append(header, "query"...), proxy, true, "192.0.2.1:53124"},
+		{append(local, "query"...), proxy, true, "10.0.0.1:4242"},
+		// Trusted sources must send a header.
+		{[]byte("query"), proxy, false, ""},
+		{append(append([]byte{}, header[:20]...), "query"...), proxy, false, ""},
+		// Other sources are served as is.
+		{[]byte("query"), other, true, "192.0.2.2:4242"},
+	}
+
+	for i, tc := range tests {
+		b, raddr, ok := c.Packet(tc.packet, tc.addr)
+		if ok != tc.ok {
+			t.Errorf("Test %d: expected ok %t, got %t", i, tc.ok, ok)
+			continue
+		}
+		if !ok {
+			continue
+		}
+		if string(b) != "query" {
+			t.Errorf("Test %d: expected %q, got %q", i, "query", b)
+		}
+		if raddr.String() != tc.raddr {
+			t.Errorf("Test %d: expected remote address %s, got %s", i, tc.raddr, raddr)
+		}
+	}
+}
@@ -0,0 +1,174 @@
+// Package proxyproto implements the receiving side of the HAProxy PROXY protocol, version 1 and 2.
+// The PROXY header is sent by a load balancer in front of CoreDNS and carries the address of the
+// client, which otherwise would be the address of the load balancer.
+//
+// See https://www.haproxy.org/download/1.8/doc/proxy-protocol.txt.
+package proxyproto
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Config holds the PROXY protocol configuration of a server.
+type Config struct {
+	// Allow holds the networks of the trusted sources, only those are expected to send a PROXY
+	// header. When empty no source is trusted.
+	Allow []*net.IPNet
+	// Timeout is the time allowed to read the PROXY header of a connection.
+	Timeout time.Duration
+}
+
+// DefaultTimeout is the default time allowed to read the PROXY header of a connection.
+const DefaultTimeout = 5 * time.Second
+
+// Trusted returns true when addr is a trusted source.
+func (c *Config) Trusted(addr net.Addr) bool {
+	var ip net.IP
+	switch a := addr.(type) {
+	case *net.TCPAddr:
+		ip = a.IP
+	case *net.UDPAddr:
+		ip = a.IP
+	default:
+		h, _, err := net.SplitHostPort(addr.String())
+		if err != nil {
+			return false
+		}
+		ip = net.ParseIP(h)
+	}
+	for _, n := range c.Allow {
+		if n.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+var (
+	// ErrNoHeader is returned when a trusted source didn't send a PROXY header.
+	ErrNoHeader = errors.New("no PROXY header")
+	// ErrInvalidHeader is returned when the PROXY header can not be parsed.
+	ErrInvalidHeader = errors.New("invalid PROXY header")
+)
+
+var (
+	v1Prefix    = []byte("PROXY ")
+	v2Signature = []byte("\r\n\r\n\x00\r\nQUIT\n")
+)
+
+const (
+	v1MaxLength = 107
+	v2HeaderLen = 16
+)
+
+// readHeader reads a version 1 or version 2 PROXY header from r. It returns the source address
+// and port of the client, the address is nil when the header doesn't carry one, as is the case
+// for the LOCAL command and the UNKNOWN protocol.
+func readHeader(r *bufio.Reader) (net.IP, int, error) {
+	b, err := r.Peek(len(v1Prefix))
+	if err != nil {
+		return nil, 0, err
+	}
+	if bytes.Equal(b, v1Prefix) {
+		return readV1(r)
+	}
+	if !bytes.Equal(b, v2Signature[:len(b)]) {
+		return nil, 0, ErrNoHeader
+	}
+
+	b, err = r.Peek(v2HeaderLen)
+	if err != nil {
+		return nil, 0, err
+	}
+	n := v2HeaderLen + int(binary.BigEndian.Uint16(b[14:16]))
+	if b, err = r.Peek(n); err != nil {
+		return nil, 0, err
+	}
+	ip, port, _, err := parseV2(b)
+	if err != nil {
+		return nil, 0, err
+	}
+	_, err = r.Discard(n)
+	return ip, port, err
+}
+
+// readV1 reads the human readable version 1 header, i.e. "PROXY TCP4 192.0.2.1 192.0.2.2 53124 53\r\n".
+func readV1(r *bufio.Reader) (net.IP, int, error) {
+	var line []byte
+	for len(line) < v1MaxLength {
+		c, err := r.ReadByte()
+		if err != nil {
+			return nil, 0, err
+		}
+		line = append(line, c)
+		if c == '\n' {
+			break
+		}
+	}
+	if !bytes.HasSuffix(line, []byte("\r\n")) {
+		return nil, 0, ErrInvalidHeader
+	}
+
+	fields := strings.Split(string(line[:len(line)-2]), " ")
+	if len(fields) >= 2 && fields[1] == "UNKNOWN" {
+		return nil, 0, nil
+	}
+	if len(fields) != 6 || (fields[1] != "TCP4" && fields[1] != "TCP6") {
+		return nil, 0, ErrInvalidHeader
+	}
+	ip := net.ParseIP(fields[2])
+	if ip == nil {
+		return nil, 0, ErrInvalidHeader
+	}
+	port, err := strconv.ParseUint(fields[4], 10, 16)
+	if err != nil {
+		return nil, 0, ErrInvalidHeader
+	}
+	return ip, int(port), nil
+}
+
+// parseV2 parses the binary version 2 header at the start of b. It returns the source address
+// and port, and the length of the header.
+func parseV2(b []byte) (net.IP, int, int, error) {
+	if len(b) < v2HeaderLen || !bytes.Equal(b[:len(v2Signature)], v2Signature) {
+		return nil, 0, 0, ErrNoHeader
+	}
+	if b[12]>>4 != 2 {
+		return nil, 0, 0, ErrInvalidHeader
+	}
+	n := v2HeaderLen + int(binary.BigEndian.Uint16(b[14:16]))
+	if len(b) < n {
+		return nil, 0, 0, ErrInvalidHeader
+	}
+
+	switch b[12] & 0xF {
+	case 0: // LOCAL, the connection was made by the proxy itself.
+		return nil, 0, n, nil
+	case 1: // PROXY
+	default:
+		return nil, 0, 0, ErrInvalidHeader
+	}
+
+	addr := b[v2HeaderLen:n]
+	switch b[13] >> 4 {
+	case 1: // AF_INET
+		if len(addr) < 12 {
+			return nil, 0, 0, ErrInvalidHeader
+		}
+		return net.IP(append([]byte{}, addr[:4]...)), int(binary.BigEndian.Uint16(addr[8:10])), n, nil
+	case 2: // AF_INET6
+		if len(addr) < 36 {
+			return nil, 0, 0, ErrInvalidHeader
+		}
+		return net.IP(append([]byte{}, addr[:16]...)), int(binary.BigEndian.Uint16(addr[32:34])), n, nil
+	}
+	// AF_UNSPEC and AF_UNIX don't carry an address we can use.
+	return nil, 0, n, nil
+}
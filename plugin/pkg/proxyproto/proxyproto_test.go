@@ -0,0 +1,97 @@
+package proxyproto
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"net"
+	"strings"
+	"testing"
+)
+
+// v2Header returns a version 2 PROXY header for a TCP (or UDP when dgram is true) connection from src.
+func v2Header(src *net.TCPAddr, dgram bool) []byte {
+	b := append([]byte{}, v2Signature...)
+	proto := byte(0x1)
+	if dgram {
+		proto = 0x2
+	}
+	var addr []byte
+	port := make([]byte, 2)
+	binary.BigEndian.PutUint16(port, uint16(src.Port))
+	if ip4 := src.IP.To4(); ip4 != nil {
+		b = append(b, 0x21, 0x10|proto)
+		addr = append(append(append(append(addr, ip4...), 192, 0, 2, 53), port...), 0, 53)
+	} else {
+		b = append(b, 0x21, 0x20|proto)
+		addr = append(append(append(append(addr, src.IP.To16()...), net.ParseIP("2001:db8::53")...), port...), 0, 53)
+	}
+	l := make([]byte, 2)
+	binary.BigEndian.PutUint16(l, uint16(len(addr)))
+	return append(append(b, l...), addr...)
+}
+
+func TestReadHeader(t *testing.T) {
+	tests := []struct {
+		header    []byte
+		shouldErr bool
+		ip        string
+		port      int
+	}{
+		{[]byte("PROXY TCP4 192.0.2.1 192.0.2.53 53124 53\r\n"), false, "192.0.2.1", 53124},
+		{[]byte("PROXY TCP6 2001:db8::1 2001:db8::53 53124 53\r\n"), false, "2001:db8::1", 53124},
+		{[]byte("PROXY UNKNOWN\r\n"), false, "", 0},
+		{v2Header(&net.TCPAddr{IP: net.ParseIP("192.0.2.1"), Port: 53124}, false), false, "192.0.2.1", 53124},
+		{v2Header(&net.TCPAddr{IP: net.ParseIP("2001:db8::1"), Port: 53124}, false), false, "2001:db8::1", 53124},
+		{append(append([]byte{}, v2Signature...), 0x20, 0x00, 0x00, 0x00), false, "", 0}, // LOCAL
+		// errors
+		{[]byte("PROXY TCP4 192.0.2.1 192.0.2.53 53124\r\n"), true, "", 0},
+		{[]byte("PROXY TCP4 192.0.2.1 192.0.2.53 53124 53\n"), true, "", 0},
+		{[]byte("PROXY TCP4 " + strings.Repeat("1", 120) + "\r\n"), true, "", 0},
+		{[]byte("\x00\x1d\x00\x00\x01\x00"), true, "", 0},
+		{append(append([]byte{}, v2Signature...), 0x10, 0x11, 0x00, 0x00), true, "", 0}, // version 1 in binary
+	}
+
+	for i, tc := range tests {
+		r := bufio.NewReader(bytes.NewReader(append(tc.header, "query"...)))
+		ip, port, err := readHeader(r)
+		if tc.shouldErr {
+			if err == nil {
+				t.Errorf("Test %d: expected error, got none", i)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Test %d: expected no error, got %s", i, err)
+			continue
+		}
+		if tc.ip == "" && ip != nil {
+			t.Errorf("Test %d: expected no address, got %s", i, ip)
+		}
+		if tc.ip != "" && !ip.Equal(net.ParseIP(tc.ip)) {
+			t.Errorf("Test %d: expected address %s, got %s", i, tc.ip, ip)
+		}
+		if port != tc.port {
+			t.Errorf("Test %d: expected port %d, got %d", i, tc.port, port)
+		}
+		// The header must be consumed completely.
+		if rest, _ := r.ReadString(0); rest != "query" {
+			t.Errorf("Test %d: expected %q after the header, got %q", i, "query", rest)
+		}
+	}
+}
+
+func TestTrusted(t *testing.T) {
+	_, n, _ := net.ParseCIDR("10.0.0.0/8")
+	c := &Config{Allow: []*net.IPNet{n}}
+
+	if !c.Trusted(&net.TCPAddr{IP: net.ParseIP("10.1.2.3")}) {
+		t.Errorf("Expected 10.1.2.3 to be trusted")
+	}
+	if c.Trusted(&net.UDPAddr{IP: net.ParseIP("192.0.2.1")}) {
+		t.Errorf("Expected 192.0.2.1 not to be trusted")
+	}
+	if (&Config{}).Trusted(&net.UDPAddr{IP: net.ParseIP("192.0.2.1")}) {
+		t.Errorf("Expected no source to be trusted without allowed networks")
+	}
+}
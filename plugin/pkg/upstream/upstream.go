@@ -36,8 +36,13 @@ func New(dests []string) (Upstream, error) {
 	return u, nil
 }
 
-// Lookup routes lookups to our selves or forward to a remote.
+// Lookup routes lookups to our selves or forward to a remote. It returns the error of the context
+// of state once that is canceled, for instance because the query took too long.
 func (u Upstream) Lookup(state request.Request, name string, typ uint16) (*dns.Msg, error) {
+	if state.Context != nil && state.Context.Err() != nil {
+		return nil, state.Context.Err()
+	}
+
 	if u.self {
 		req := new(dns.Msg)
 		req.SetQuestion(name, typ)
@@ -46,12 +51,19 @@ func (u Upstream) Lookup(state request.Request, name string, typ uint16) (*dns.M
 		server := state.Context.Value(dnsserver.Key{}).(*dnsserver.Server)
 
 		server.ServeDNS(state.Context, nw, req)
+		if err := state.Context.Err(); err != nil {
+			return nil, err
+		}
 
 		return nw.Msg, nil
 	}
 
 	if u.Forward != nil {
-		return u.Forward.Lookup(state, name, typ)
+		m, err := u.Forward.Lookup(state, name, typ)
+		if state.Context != nil && state.Context.Err() != nil {
+			return nil, state.Context.Err()
+		}
+		return m, err
 	}
 
 	return nil, nil
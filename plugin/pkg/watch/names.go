@@ -0,0 +1,77 @@
+package watch
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/miekg/dns"
+)
+
+// Names keeps track of the names that are watched in a plugin and of the channel their changes are
+// sent to. It implements the SetWatchChan, Watch and StopWatching methods of Watchable, so plugins
+// only need to find out which names have changed. The zero value is ready to use.
+type Names struct {
+	mu      sync.RWMutex
+	c       Chan
+	watched map[string]struct{}
+}
+
+// SetWatchChan implements part of the Watchable interface.
+func (n *Names) SetWatchChan(c Chan) {
+	n.mu.Lock()
+	n.c = c
+	n.mu.Unlock()
+}
+
+// Watch implements part of the Watchable interface.
+func (n *Names) Watch(qname string) error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if n.c == nil {
+		return fmt.Errorf("cannot start watch because the channel has not been set")
+	}
+	if n.watched == nil {
+		n.watched = make(map[string]struct{})
+	}
+	n.watched[qname] = struct{}{}
+	return nil
+}
+
+// StopWatching implements part of the Watchable interface.
+func (n *Names) StopWatching(qname string) {
+	n.mu.Lock()
+	delete(n.watched, qname)
+	n.mu.Unlock()
+}
+
+// Names returns the watched names.
+func (n *Names) Names() []string {
+	n.mu.RLock()
+	defer n.mu.RUnlock()
+	names := make([]string, 0, len(n.watched))
+	for name := range n.watched {
+		names = append(names, name)
+	}
+	return names
+}
+
+// Changed signals that the data of name, and of all names below it, may have changed. The name is
+// only sent down the watch channel when it, or a name below it, is watched.
+func (n *Names) Changed(name string) {
+	n.mu.RLock()
+	c := n.c
+	watched := false
+	for w := range n.watched {
+		if dns.IsSubDomain(name, w) {
+			watched = true
+			break
+		}
+	}
+	n.mu.RUnlock()
+
+	// Don't hold the lock while sending, StopWatching may be called while the watch manager
+	// waits for its own lock.
+	if c != nil && watched {
+		c <- name
+	}
+}
@@ -0,0 +1,44 @@
+package watch
+
+import (
+	"testing"
+	"time"
+)
+
+func TestNames(t *testing.T) {
+	n := &Names{}
+	if err := n.Watch("www.example.org."); err == nil {
+		t.Errorf("Expected error when watching without a channel, got none")
+	}
+
+	c := make(Chan, 10)
+	n.SetWatchChan(c)
+	if err := n.Watch("www.example.org."); err != nil {
+		t.Fatalf("Expected no error, got %s", err)
+	}
+
+	n.Changed("example.com.")
+	n.Changed("a.www.example.org.")
+	n.Changed("www.example.org.")
+	n.Changed("example.org.")
+
+	for _, expected := range []string{"www.example.org.", "example.org."} {
+		select {
+		case name := <-c:
+			if name != expected {
+				t.Errorf("Expected change for %s, got %s", expected, name)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("Expected change for %s, got none", expected)
+		}
+	}
+	if len(c) != 0 {
+		t.Errorf("Expected no more changes, got %d", len(c))
+	}
+
+	n.StopWatching("www.example.org.")
+	n.Changed("example.org.")
+	if len(c) != 0 || len(n.Names()) != 0 {
+		t.Errorf("Expected no changes after stopping the watch")
+	}
+}
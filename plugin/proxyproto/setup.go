@@ -0,0 +1,82 @@
+package proxyproto
+
+import (
+	"time"
+
+	"github.com/coredns/coredns/core/dnsserver"
+	"github.com/coredns/coredns/plugin"
+	"github.com/coredns/coredns/plugin/pkg/parse"
+	"github.com/coredns/coredns/plugin/pkg/proxyproto"
+
+	"github.com/mholt/caddy"
+)
+
+func init() {
+	caddy.RegisterPlugin("proxyproto", caddy.Plugin{
+		ServerType: "dns",
+		Action:     setup,
+	})
+}
+
+func setup(c *caddy.Controller) error {
+	config := dnsserver.GetConfig(c)
+
+	if config.ProxyProtocol != nil {
+		return plugin.Error("proxyproto", c.Errf("PROXY protocol already configured for this server instance"))
+	}
+
+	pp, err := parseProxyProto(c)
+	if err != nil {
+		return plugin.Error("proxyproto", err)
+	}
+	config.ProxyProtocol = pp
+	return nil
+}
+
+func parseProxyProto(c *caddy.Controller) (*proxyproto.Config, error) {
+	pp := &proxyproto.Config{Timeout: proxyproto.DefaultTimeout}
+	i := 0
+	for c.Next() {
+		if i > 0 {
+			return nil, plugin.ErrOnce
+		}
+		i++
+
+		if len(c.RemainingArgs()) != 0 {
+			return nil, c.ArgErr()
+		}
+		for c.NextBlock() {
+			switch c.Val() {
+			case "allow":
+				args := c.RemainingArgs()
+				if len(args) == 0 {
+					return nil, c.ArgErr()
+				}
+				nets, err := parse.Nets(args...)
+				if err != nil {
+					return nil, c.Err(err.Error())
+				}
+				pp.Allow = append(pp.Allow, nets...)
+			case "timeout":
+				args := c.RemainingArgs()
+				if len(args) != 1 {
+					return nil, c.ArgErr()
+				}
+				d, err := time.ParseDuration(args[0])
+				if err != nil {
+					return nil, c.Errf("invalid timeout '%s': %s", args[0], err)
+				}
+				if d <= 0 {
+					return nil, c.Errf("timeout must be positive: %s", args[0])
+				}
+				pp.Timeout = d
+			default:
+				return nil, c.Errf("unknown property '%s'", c.Val())
+			}
+		}
+	}
+	if len(pp.Allow) == 0 {
+		return nil, c.Err("no trusted sources, use allow")
+	}
+	return pp, nil
+}
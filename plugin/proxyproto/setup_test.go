@@ -0,0 +1,75 @@
+package proxyproto
+
+import (
+	"testing"
+	"time"
+
+	"github.com/coredns/coredns/plugin/pkg/proxyproto"
+
+	"github.com/mholt/caddy"
+)
+
+func TestSetup(t *testing.T) {
+	tests := []struct {
+		input     string
+		shouldErr bool
+		allow     int
+		timeout   time.Duration
+	}{
+		{`proxyproto {
+			allow 10.0.0.0/8 192.0.2.1 2001:db8::/32
+			timeout 1s
+		}`, false, 3, time.Second},
+		{`proxyproto {
+			allow 10.0.0.0/8
+			allow 2001:db8::1
+		}`, false, 2, proxyproto.DefaultTimeout},
+		// errors
+		{`proxyproto`, true, 0, 0},
+		{`proxyproto {
+			timeout 1s
+		}`, true, 0, 0},
+		{`proxyproto 10.0.0.0/8`, true, 0, 0},
+		{`proxyproto {
+			allow
+		}`, true, 0, 0},
+		{`proxyproto {
+			allow 10.0.0.0/33
+		}`, true, 0, 0},
+		{`proxyproto {
+			allow 10.0.0.0/8
+			timeout 0s
+		}`, true, 0, 0},
+		{`proxyproto {
+			allow 10.0.0.0/8
+			timeout soon
+		}`, true, 0, 0},
+		{`proxyproto {
+			allow 10.0.0.0/8
+			version 2
+		}`, true, 0, 0},
+		{`proxyproto
+		proxyproto`, true, 0, 0},
+	}
+
+	for i, test := range tests {
+		c := caddy.NewTestController("dns", test.input)
+		pp, err := parseProxyProto(c)
+		if test.shouldErr {
+			if err == nil {
+				t.Errorf("Test %d: expected error but found none for input %s", i, test.input)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Test %d: expected no error but found one for input %s, got: %v", i, test.input, err)
+			continue
+		}
+		if len(pp.Allow) != test.allow {
+			t.Errorf("Test %d: expected %d allowed networks, got %d", i, test.allow, len(pp.Allow))
+		}
+		if pp.Timeout != test.timeout {
+			t.Errorf("Test %d: expected timeout %s, got %s", i, test.timeout, pp.Timeout)
+		}
+	}
+}
@@ -0,0 +1,61 @@
+package ready
+
+import (
+	"sort"
+	"strings"
+	"sync"
+)
+
+// list is a list of plugins that implement Readiness.
+type list struct {
+	sync.Mutex
+	rs    []Readiness
+	names []string
+}
+
+// Append adds a new readiness to l.
+func (l *list) Append(r Readiness, name string) {
+	l.Lock()
+	defer l.Unlock()
+	l.rs = append(l.rs, r)
+	l.names = append(l.names, name)
+}
+
+// Ready return true when all plugins ready, if the returned value is false the string
+// contains a comma separated list of plugins that are not ready.
+func (l *list) Ready() (bool, string) {
+	l.Lock()
+	defer l.Unlock()
+	ok := true
+	s := []string{}
+	for i, r := range l.rs {
+		if r == nil {
+			continue
+		}
+		if !r.Ready() {
+			ok = false
+			s = append(s, l.names[i])
+			continue
+		}
+		// A plugin that is ready is not asked again.
+		l.rs[i] = nil
+	}
+	if ok {
+		return true, ""
+	}
+	sort.Strings(s)
+	return false, strings.Join(dedup(s), ",")
+}
+
+// dedup removes the duplicates from the sorted slice s, a plugin may be used in several server blocks.
+func dedup(s []string) []string {
+	j := 0
+	for i := range s {
+		if i > 0 && s[i] == s[j-1] {
+			continue
+		}
+		s[j] = s[i]
+		j++
+	}
+	return s[:j]
+}
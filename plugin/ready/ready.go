@@ -0,0 +1,86 @@
+// Package ready is used to signal readiness of the CoreDNS process. Once all plugins that implement
+// Readiness have signalled they are ready, the HTTP handler (on port 8181) returns 200 OK. Until then
+// it returns a 503 and the names of the plugins that aren't ready yet.
+package ready
+
+import (
+	"io"
+	"net"
+	"net/http"
+	"sync"
+
+	clog "github.com/coredns/coredns/plugin/pkg/log"
+	"github.com/coredns/coredns/plugin/pkg/uniq"
+)
+
+var (
+	log      = clog.NewWithPlugin("ready")
+	uniqAddr = uniq.New()
+)
+
+type ready struct {
+	Addr string
+
+	plugins *list
+
+	sync.Mutex
+	ln   net.Listener
+	done bool
+	mux  *http.ServeMux
+}
+
+// newReady returns a new initialized ready.
+func newReady(addr string) *ready {
+	return &ready{Addr: addr, plugins: &list{}}
+}
+
+func (rd *ready) onStartup() error {
+	ln, err := net.Listen("tcp", rd.Addr)
+	if err != nil {
+		return err
+	}
+
+	rd.Lock()
+	rd.ln = ln
+	rd.mux = http.NewServeMux()
+	rd.done = true
+	rd.Unlock()
+
+	rd.mux.HandleFunc(path, func(w http.ResponseWriter, _ *http.Request) {
+		ok, todo := rd.plugins.Ready()
+		if ok {
+			w.WriteHeader(http.StatusOK)
+			io.WriteString(w, http.StatusText(http.StatusOK))
+			return
+		}
+		log.Infof("Still waiting on: %q", todo)
+		w.WriteHeader(http.StatusServiceUnavailable)
+		io.WriteString(w, todo)
+	})
+
+	go func() { http.Serve(rd.ln, rd.mux) }()
+
+	return nil
+}
+
+func (rd *ready) onRestart() error {
+	uniqAddr.Unset(rd.Addr)
+	return rd.onFinalShutdown()
+}
+
+func (rd *ready) onFinalShutdown() error {
+	rd.Lock()
+	defer rd.Unlock()
+	if !rd.done {
+		return nil
+	}
+
+	rd.ln.Close()
+	rd.done = false
+	return nil
+}
+
+const (
+	defAddr = ":8181"
+	path    = "/ready"
+)
@@ -0,0 +1,60 @@
+package ready
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"testing"
+)
+
+type readiness struct{ ok bool }
+
+func (r *readiness) Ready() bool { return r.ok }
+
+func TestReady(t *testing.T) {
+	rd := newReady(":0")
+	k, f1, f2 := &readiness{}, &readiness{}, &readiness{}
+	rd.plugins.Append(k, "kubernetes")
+	rd.plugins.Append(f1, "file")
+	rd.plugins.Append(f2, "file") // the same plugin in another server block
+
+	if err := rd.onStartup(); err != nil {
+		t.Fatalf("Unable to startup the readiness server: %v", err)
+	}
+	defer rd.onFinalShutdown()
+
+	address := fmt.Sprintf("http://%s%s", rd.ln.Addr().String(), path)
+
+	response, err := http.Get(address)
+	if err != nil {
+		t.Fatalf("Unable to query %s: %v", address, err)
+	}
+	if response.StatusCode != 503 {
+		t.Errorf("Invalid status code: expecting '503', got '%d'", response.StatusCode)
+	}
+	content, _ := ioutil.ReadAll(response.Body)
+	response.Body.Close()
+	if string(content) != "file,kubernetes" {
+		t.Errorf("Invalid response body: expecting 'file,kubernetes', got '%s'", content)
+	}
+
+	k.ok = true
+	rd.plugins.Ready()
+	// Once ready a plugin is not asked again.
+	k.ok = false
+
+	ok, todo := rd.plugins.Ready()
+	if ok || todo != "file" {
+		t.Errorf("Expecting not ready on 'file', got %t and '%s'", ok, todo)
+	}
+
+	f1.ok, f2.ok = true, true
+	response, err = http.Get(address)
+	if err != nil {
+		t.Fatalf("Unable to query %s: %v", address, err)
+	}
+	if response.StatusCode != 200 {
+		t.Errorf("Invalid status code: expecting '200', got '%d'", response.StatusCode)
+	}
+	response.Body.Close()
+}
@@ -0,0 +1,82 @@
+package ready
+
+import (
+	"net"
+
+	"github.com/coredns/coredns/core/dnsserver"
+	"github.com/coredns/coredns/plugin"
+
+	"github.com/mholt/caddy"
+)
+
+func init() {
+	caddy.RegisterPlugin("ready", caddy.Plugin{
+		ServerType: "dns",
+		Action:     setup,
+	})
+}
+
+func setup(c *caddy.Controller) error {
+	addr, err := parse(c)
+	if err != nil {
+		return plugin.Error("ready", err)
+	}
+
+	rd := newReady(addr)
+	// Only one ready per address, the first one collects the plugins of all server blocks.
+	obj := uniqAddr.Set(addr, rd.onStartup, rd)
+	if rd != obj.(*ready) {
+		return nil
+	}
+
+	c.OnStartup(func() error {
+		for _, config := range dnsserver.GetConfigs(c) {
+			for _, p := range config.Handlers() {
+				if r, ok := p.(Readiness); ok {
+					rd.plugins.Append(r, p.Name())
+				}
+			}
+		}
+		return nil
+	})
+
+	c.OncePerServerBlock(func() error {
+		c.OnStartup(func() error {
+			return uniqAddr.ForEach()
+		})
+		return nil
+	})
+
+	c.OnRestart(rd.onRestart)
+	c.OnFinalShutdown(rd.onFinalShutdown)
+
+	// Don't do AddPlugin, as ready is not *really* a plugin just a separate webserver running.
+	return nil
+}
+
+func parse(c *caddy.Controller) (string, error) {
+	addr := defAddr
+	i := 0
+	for c.Next() {
+		if i > 0 {
+			return "", plugin.ErrOnce
+		}
+		i++
+		args := c.RemainingArgs()
+
+		switch len(args) {
+		case 0:
+		case 1:
+			addr = args[0]
+			if _, _, e := net.SplitHostPort(addr); e != nil {
+				return "", e
+			}
+		default:
+			return "", c.ArgErr()
+		}
+		if c.NextBlock() {
+			return "", c.ArgErr()
+		}
+	}
+	return addr, nil
+}
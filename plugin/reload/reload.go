@@ -1,10 +1,15 @@
 package reload
 
 import (
+	"bytes"
 	"crypto/md5"
+	"strings"
 	"time"
 
+	"github.com/coredns/coredns/core/dnsserver"
+
 	"github.com/mholt/caddy"
+	"github.com/mholt/caddy/caddyfile"
 )
 
 // reload periodically checks if the Corefile has changed, and reloads if so
@@ -51,10 +56,19 @@ func hook(event caddy.EventName, info interface{}) error {
 				if s != md5sum {
 					// Let not try to restart with the same file, even though it is wrong.
 					md5sum = s
+					changed, err := diff(instance.Caddyfile(), corefile)
+					if err == nil && len(changed) == 0 {
+						log.Infof("Corefile changed, but its configuration didn't; not reloading")
+						continue
+					}
+					if len(changed) > 0 {
+						// All server blocks are restarted, not only the changed ones.
+						log.Infof("Reloading all server blocks, changed: %s", strings.Join(changed, ", "))
+					}
 					// now lets consider that plugin will not be reload, unless appear in next config file
 					// change status iof usage will be reset in setup if the plugin appears in config file
 					r.usage = maybeUsed
-					_, err := instance.Restart(corefile)
+					_, err = instance.Restart(corefile)
 					if err != nil {
 						log.Errorf("Corefile changed but reload failed: %s\n", err)
 						continue
@@ -73,3 +87,54 @@ func hook(event caddy.EventName, info interface{}) error {
 
 	return nil
 }
+
+// diff compares the server blocks of the running Corefile with those of corefile. It returns the keys
+// of the server blocks that were added, changed or removed; nothing when only comments or formatting
+// changed.
+func diff(running, corefile caddy.Input) ([]string, error) {
+	old, err := caddyfile.Parse(running.Path(), bytes.NewReader(running.Body()), dnsserver.Directives)
+	if err != nil {
+		return nil, err
+	}
+	sbs, err := caddyfile.Parse(corefile.Path(), bytes.NewReader(corefile.Body()), dnsserver.Directives)
+	if err != nil {
+		return nil, err
+	}
+
+	same := len(old) == len(sbs)
+	fps := make(map[string]bool)
+	for i, sb := range old {
+		fp := dnsserver.Fingerprint(sb)
+		fps[fp] = true
+		if same && fp != dnsserver.Fingerprint(sbs[i]) {
+			same = false
+		}
+	}
+	if same {
+		return nil, nil
+	}
+
+	var changed []string
+	keys := make(map[string]bool)
+	newfps := make(map[string]bool)
+	for _, sb := range sbs {
+		fp := dnsserver.Fingerprint(sb)
+		newfps[fp] = true
+		if !fps[fp] {
+			k := strings.Join(sb.Keys, " ")
+			keys[k] = true
+			changed = append(changed, k)
+		}
+	}
+	for _, sb := range old {
+		// A server block that was changed is only listed once.
+		if k := strings.Join(sb.Keys, " "); !newfps[dnsserver.Fingerprint(sb)] && !keys[k] {
+			changed = append(changed, k+" (removed)")
+		}
+	}
+	if len(changed) == 0 {
+		// Only the order of the server blocks changed.
+		changed = append(changed, "order of the server blocks")
+	}
+	return changed, nil
+}
@@ -0,0 +1,41 @@
+package reload
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/mholt/caddy"
+)
+
+func TestDiff(t *testing.T) {
+	running := `example.org {
+    whoami
+}
+example.net {
+    erratic
+}`
+	tests := []struct {
+		corefile string
+		changed  []string
+	}{
+		{running, nil},
+		{"# comment\nexample.org {\n  whoami\n}\n\nexample.net {\n  erratic\n}", nil},
+		{"example.org {\n  whoami\n}\nexample.net {\n  erratic\n  log\n}", []string{"example.net"}},
+		{"example.org {\n  whoami\n}", []string{"example.net (removed)"}},
+		{"example.net {\n  erratic\n}\nexample.org {\n  whoami\n}", []string{"order of the server blocks"}},
+	}
+
+	for i, tc := range tests {
+		changed, err := diff(input(running), input(tc.corefile))
+		if err != nil {
+			t.Fatalf("Test %d: expected no error, got %s", i, err)
+		}
+		if !reflect.DeepEqual(changed, tc.changed) {
+			t.Errorf("Test %d: expected changed %v, got %v", i, tc.changed, changed)
+		}
+	}
+}
+
+func input(s string) caddy.Input {
+	return caddy.CaddyfileInput{Contents: []byte(s), Filepath: "Corefile", ServerTypeName: "dns"}
+}
@@ -96,7 +96,7 @@ func (h *Route53) Run(ctx context.Context) error {
 
 // ServeDNS implements the plugin.Handler.ServeDNS.
 func (h *Route53) ServeDNS(ctx context.Context, w dns.ResponseWriter, r *dns.Msg) (int, error) {
-	state := request.Request{W: w, Req: r}
+	state := request.Request{W: w, Req: r, Context: ctx}
 	qname := state.Name()
 
 	zName := plugin.Zones(h.zoneNames).Matches(qname)
@@ -119,6 +119,10 @@ func (h *Route53) ServeDNS(ctx context.Context, w dns.ResponseWriter, r *dns.Msg
 		if len(m.Answer) != 0 {
 			break
 		}
+		// Looking up CNAME targets may have taken up the time of the query.
+		if err := ctx.Err(); err != nil {
+			return dns.RcodeServerFailure, err
+		}
 	}
 
 	if len(m.Answer) == 0 && h.Fall.Through(qname) {
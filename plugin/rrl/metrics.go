@@ -0,0 +1,31 @@
+package rrl
+
+import (
+	"github.com/coredns/coredns/plugin"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	// ResponsesExceeded is the number of responses that exceeded the rate limit.
+	ResponsesExceeded = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: plugin.Namespace,
+		Subsystem: "rrl",
+		Name:      "responses_exceeded_total",
+		Help:      "Counter of responses that exceeded the rate limit.",
+	}, []string{"server", "class"})
+	// ResponsesDropped is the number of rate limited responses that were dropped.
+	ResponsesDropped = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: plugin.Namespace,
+		Subsystem: "rrl",
+		Name:      "responses_dropped_total",
+		Help:      "Counter of rate limited responses that were dropped.",
+	}, []string{"server", "class"})
+	// ResponsesSlipped is the number of rate limited responses that were replaced by a truncated response.
+	ResponsesSlipped = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: plugin.Namespace,
+		Subsystem: "rrl",
+		Name:      "responses_slipped_total",
+		Help:      "Counter of rate limited responses that were replaced by a truncated response.",
+	}, []string{"server", "class"})
+)
@@ -0,0 +1,234 @@
+// Package rrl implements Response Rate Limiting (RRL) as found in BIND. It limits the rate of
+// identical responses sent to a client network to make reflection attacks less effective.
+package rrl
+
+import (
+	"context"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/coredns/coredns/plugin"
+	"github.com/coredns/coredns/plugin/metrics"
+	"github.com/coredns/coredns/plugin/pkg/cache"
+	clog "github.com/coredns/coredns/plugin/pkg/log"
+	"github.com/coredns/coredns/plugin/pkg/response"
+	"github.com/coredns/coredns/request"
+
+	"github.com/miekg/dns"
+)
+
+var log = clog.NewWithPlugin("rrl")
+
+// RRL performs response rate limiting on UDP responses.
+type RRL struct {
+	Next  plugin.Handler
+	Zones []string
+
+	window     float64 // seconds
+	ipv4Prefix int
+	ipv6Prefix int
+
+	// rates holds the allowed responses per second for each response class, 0 means no limit.
+	rates map[response.Class]float64
+
+	slip    int
+	logOnly bool
+
+	table *cache.Cache
+	now   func() time.Time
+}
+
+// New returns a new RRL with the default settings.
+func New() *RRL {
+	return &RRL{
+		window:     defaultWindow,
+		ipv4Prefix: defaultIPv4Prefix,
+		ipv6Prefix: defaultIPv6Prefix,
+		rates:      make(map[response.Class]float64),
+		slip:       defaultSlip,
+		table:      cache.New(defaultMaxTableSize),
+		now:        time.Now,
+	}
+}
+
+// bucket holds the balance of a single response token, the balance is the number of responses
+// that may still be sent and becomes negative when the rate limit is exceeded.
+type bucket struct {
+	sync.Mutex
+	balance float64
+	last    time.Time
+	slipped int
+}
+
+// ServeDNS implements the plugin.Handler interface.
+func (rl *RRL) ServeDNS(ctx context.Context, w dns.ResponseWriter, r *dns.Msg) (int, error) {
+	state := request.Request{W: w, Req: r}
+
+	// Only UDP is subject to rate limiting, a TCP client can not spoof its address.
+	if state.Proto() != "udp" || plugin.Zones(rl.Zones).Matches(state.Name()) == "" {
+		return plugin.NextOrFailure(rl.Name(), rl.Next, ctx, w, r)
+	}
+
+	rw := &ResponseWriter{ResponseWriter: w, ctx: ctx, rrl: rl, state: state}
+	return plugin.NextOrFailure(rl.Name(), rl.Next, ctx, rw, r)
+}
+
+// Name implements the plugin.Handler interface.
+func (rl *RRL) Name() string { return "rrl" }
+
+// ResponseWriter rate limits the responses it writes.
+type ResponseWriter struct {
+	dns.ResponseWriter
+	ctx   context.Context
+	rrl   *RRL
+	state request.Request
+}
+
+// WriteMsg implements the dns.ResponseWriter interface. When the rate limit is exceeded the
+// response is either dropped or, depending on the slip ratio, replaced by a truncated response.
+func (w *ResponseWriter) WriteMsg(res *dns.Msg) error {
+	class, tok := w.rrl.token(w.state, res)
+	exceeded, slip := w.rrl.debit(class, tok)
+	if !exceeded {
+		return w.ResponseWriter.WriteMsg(res)
+	}
+
+	server := metrics.WithServer(w.ctx)
+	ResponsesExceeded.WithLabelValues(server, class.String()).Inc()
+	if w.rrl.logOnly {
+		log.Infof("Rate limit exceeded for %s (%s): %s", w.state.IP(), class, tok)
+		return w.ResponseWriter.WriteMsg(res)
+	}
+
+	if !slip {
+		ResponsesDropped.WithLabelValues(server, class.String()).Inc()
+		return nil
+	}
+
+	// Slip a truncated response, this makes legitimate clients retry over TCP.
+	ResponsesSlipped.WithLabelValues(server, class.String()).Inc()
+	m := new(dns.Msg)
+	m.SetReply(w.state.Req)
+	m.Rcode = res.Rcode
+	m.Truncated = true
+	return w.ResponseWriter.WriteMsg(m)
+}
+
+// Write implements the dns.ResponseWriter interface.
+func (w *ResponseWriter) Write(buf []byte) (int, error) {
+	res := new(dns.Msg)
+	if err := res.Unpack(buf); err != nil {
+		return w.ResponseWriter.Write(buf)
+	}
+	if err := w.WriteMsg(res); err != nil {
+		return 0, err
+	}
+	return len(buf), nil
+}
+
+// token returns the class of the response and the token that identifies it: the client's network,
+// the class, the query type and the name the response is about.
+func (rl *RRL) token(state request.Request, res *dns.Msg) (response.Class, string) {
+	typ, _ := response.Typify(res, rl.now().UTC())
+	class := response.Classify(typ)
+
+	name := ""
+	switch typ {
+	case response.NoError:
+		name = responseName(state, res)
+	case response.NoData:
+		name = state.Name()
+	case response.NameError, response.Delegation:
+		// Use the zone (or delegation point) so random names can't be used to avoid the limit.
+		if len(res.Ns) > 0 {
+			name = res.Ns[0].Header().Name
+		}
+	}
+
+	return class, rl.prefix(state.IP()) + "/" + class.String() + "/" + strconv.Itoa(int(state.QType())) + "/" + name
+}
+
+// responseName returns the name of the positive response. When the answer was synthesized from
+// a wildcard, as signalled by the labels of the RRSIG, the wildcard is returned.
+func responseName(state request.Request, res *dns.Msg) string {
+	for _, rr := range res.Answer {
+		sig, ok := rr.(*dns.RRSIG)
+		if !ok {
+			continue
+		}
+		labels := dns.SplitDomainName(sig.Header().Name)
+		if int(sig.Labels) < len(labels) {
+			return "*." + dns.Fqdn(strings.Join(labels[len(labels)-int(sig.Labels):], "."))
+		}
+	}
+	return state.Name()
+}
+
+// prefix returns the network of the client address, masked with the configured prefix length.
+func (rl *RRL) prefix(addr string) string {
+	ip := net.ParseIP(addr)
+	if ip == nil {
+		return addr
+	}
+	if ip4 := ip.To4(); ip4 != nil {
+		return ip4.Mask(net.CIDRMask(rl.ipv4Prefix, 32)).String()
+	}
+	return ip.Mask(net.CIDRMask(rl.ipv6Prefix, 128)).String()
+}
+
+// debit charges one response to the bucket of tok. It returns true when the rate limit is exceeded,
+// and if so, whether a truncated response should be slipped.
+func (rl *RRL) debit(class response.Class, tok string) (exceeded, slip bool) {
+	rate := rl.rates[class]
+	if rate == 0 {
+		return false, false
+	}
+
+	key := cache.Hash([]byte(tok))
+	now := rl.now()
+
+	var b *bucket
+	if el, ok := rl.table.Get(key); ok {
+		b = el.(*bucket)
+	} else {
+		// Two concurrent responses may both create a bucket, this only resets the balance once.
+		b = &bucket{balance: rate, last: now}
+		rl.table.Add(key, b)
+	}
+
+	b.Lock()
+	defer b.Unlock()
+
+	// Credit the time elapsed since the last response, the balance is capped at the rate
+	// and the debt at window seconds worth of responses.
+	b.balance += now.Sub(b.last).Seconds() * rate
+	if b.balance > rate {
+		b.balance = rate
+	}
+	b.last = now
+	b.balance--
+	if b.balance < -rl.window*rate {
+		b.balance = -rl.window * rate
+	}
+
+	if b.balance >= 0 {
+		return false, false
+	}
+
+	if rl.slip == 0 {
+		return true, false
+	}
+	b.slipped++
+	return true, b.slipped%rl.slip == 0
+}
+
+const (
+	defaultWindow       = 15
+	defaultIPv4Prefix   = 24
+	defaultIPv6Prefix   = 56
+	defaultSlip         = 2
+	defaultMaxTableSize = 100000
+)
@@ -0,0 +1,136 @@
+package rrl
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/coredns/coredns/plugin/pkg/dnstest"
+	"github.com/coredns/coredns/plugin/pkg/response"
+	"github.com/coredns/coredns/plugin/test"
+	"github.com/coredns/coredns/request"
+
+	"github.com/miekg/dns"
+)
+
+func TestRRL(t *testing.T) {
+	// The backend answers www.example.org with an A record, and all other names with NXDOMAIN.
+	backend := test.HandlerFunc(func(ctx context.Context, w dns.ResponseWriter, r *dns.Msg) (int, error) {
+		m := new(dns.Msg)
+		m.SetReply(r)
+		if r.Question[0].Name == "www.example.org." {
+			m.Answer = []dns.RR{test.A("www.example.org. 3600 IN A 127.0.0.53")}
+		} else {
+			m.Rcode = dns.RcodeNameError
+			m.Ns = []dns.RR{test.SOA("example.org. 3600 IN SOA ns.example.org. admin.example.org. 1 3600 900 604800 3600")}
+		}
+		w.WriteMsg(m)
+		return m.Rcode, nil
+	})
+
+	www := "www.example.org."
+	tests := []struct {
+		rate     float64
+		slip     int
+		logOnly  bool
+		tcp      bool
+		qnames   []string
+		expected string // for each query: a(nswered), d(ropped) or t(runcated)
+	}{
+		{2, 0, false, false, []string{www, www, www}, "aad"},
+		// Other tokens have their own balance.
+		{2, 0, false, false, []string{www, www, "a.example.org."}, "aaa"},
+		// Random names share the same token for NXDOMAIN: the zone.
+		{2, 0, false, false, []string{"a.example.org.", "b.example.org.", "c.example.org.", "d.example.org."}, "aadd"},
+		// Names outside of the zones are not limited.
+		{2, 0, false, false, []string{"www.example.com.", "www.example.com.", "www.example.com."}, "aaa"},
+		// TCP is not limited.
+		{2, 0, false, true, []string{www, www, www}, "aaa"},
+		{1, 2, false, false, []string{www, www, www, www, www}, "adtdt"},
+		{1, 0, true, false, []string{www, www, www}, "aaa"},
+	}
+
+	ctx := context.TODO()
+	now := time.Unix(1000000, 0)
+
+	for i, tc := range tests {
+		rl := New()
+		rl.Zones = []string{"example.org."}
+		rl.rates[response.Success] = tc.rate
+		rl.rates[response.Denial] = tc.rate
+		rl.slip = tc.slip
+		rl.logOnly = tc.logOnly
+		rl.now = func() time.Time { return now }
+		rl.Next = backend
+
+		actual := ""
+		for _, qname := range tc.qnames {
+			req := new(dns.Msg)
+			req.SetQuestion(qname, dns.TypeA)
+			rec := dnstest.NewRecorder(&test.ResponseWriter{TCP: tc.tcp})
+			rl.ServeDNS(ctx, rec, req)
+
+			switch {
+			case rec.Msg == nil:
+				actual += "d"
+			case rec.Msg.Truncated && len(rec.Msg.Answer) == 0:
+				actual += "t"
+			default:
+				actual += "a"
+			}
+		}
+		if actual != tc.expected {
+			t.Errorf("Test %d: Expected responses %q, but got %q", i, tc.expected, actual)
+		}
+	}
+}
+
+func TestRRLWindow(t *testing.T) {
+	now := time.Unix(1000000, 0)
+	rl := New()
+	rl.Zones = []string{"example.org."}
+	rl.rates[response.Error] = 2
+	rl.now = func() time.Time { return now }
+	rl.Next = test.ErrorHandler()
+
+	req := new(dns.Msg)
+	req.SetQuestion("www.example.org.", dns.TypeA)
+	for i := 0; i < 3; i++ {
+		rl.ServeDNS(context.TODO(), dnstest.NewRecorder(&test.ResponseWriter{}), req)
+	}
+
+	// The balance is -1 after the third response, one second is enough to recover.
+	now = now.Add(1 * time.Second)
+	rec := dnstest.NewRecorder(&test.ResponseWriter{})
+	rl.ServeDNS(context.TODO(), rec, req)
+	if rec.Msg == nil {
+		t.Errorf("Expected response to be written after one second")
+	}
+}
+
+func TestToken(t *testing.T) {
+	rl := New()
+
+	m := new(dns.Msg)
+	m.SetQuestion("a.b.example.org.", dns.TypeA)
+	state := request.Request{W: &test.ResponseWriter{}, Req: m}
+
+	res := new(dns.Msg)
+	res.SetReply(m)
+	res.Answer = []dns.RR{
+		test.A("a.b.example.org. 3600 IN A 127.0.0.53"),
+		test.RRSIG("a.b.example.org. 3600 IN RRSIG A 8 2 3600 20190101000000 20180101000000 12345 example.org. deadbeef"),
+	}
+	class, tok := rl.token(state, res)
+	if class != response.Success {
+		t.Errorf("Expected class %s, got %s", response.Success, class)
+	}
+	if expected := "10.240.0.0/success/1/*.example.org."; tok != expected {
+		t.Errorf("Expected token %q, got %q", expected, tok)
+	}
+
+	res.Answer = res.Answer[:1]
+	if _, tok := rl.token(state, res); tok != "10.240.0.0/success/1/a.b.example.org." {
+		t.Errorf("Expected token for a.b.example.org., got %q", tok)
+	}
+}
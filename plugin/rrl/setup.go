@@ -0,0 +1,175 @@
+package rrl
+
+import (
+	"strconv"
+
+	"github.com/coredns/coredns/core/dnsserver"
+	"github.com/coredns/coredns/plugin"
+	"github.com/coredns/coredns/plugin/metrics"
+	"github.com/coredns/coredns/plugin/pkg/cache"
+	"github.com/coredns/coredns/plugin/pkg/response"
+
+	"github.com/mholt/caddy"
+)
+
+func init() {
+	caddy.RegisterPlugin("rrl", caddy.Plugin{
+		ServerType: "dns",
+		Action:     setup,
+	})
+}
+
+func setup(c *caddy.Controller) error {
+	rl, err := parse(c)
+	if err != nil {
+		return plugin.Error("rrl", err)
+	}
+
+	dnsserver.GetConfig(c).AddPlugin(func(next plugin.Handler) plugin.Handler {
+		rl.Next = next
+		return rl
+	})
+
+	c.OnStartup(func() error {
+		metrics.MustRegister(c, ResponsesExceeded, ResponsesDropped, ResponsesSlipped)
+		return nil
+	})
+	return nil
+}
+
+func parse(c *caddy.Controller) (*RRL, error) {
+	rl := New()
+	i := 0
+	for c.Next() {
+		if i > 0 {
+			return nil, plugin.ErrOnce
+		}
+		i++
+
+		rl.Zones = c.RemainingArgs()
+		if len(rl.Zones) == 0 {
+			rl.Zones = make([]string, len(c.ServerBlockKeys))
+			copy(rl.Zones, c.ServerBlockKeys)
+		}
+		for i := range rl.Zones {
+			rl.Zones[i] = plugin.Host(rl.Zones[i]).Normalize()
+		}
+
+		responses := -1.0
+		denials, errors := -1.0, -1.0
+		for c.NextBlock() {
+			switch c.Val() {
+			case "window":
+				n, err := intArg(c, 1)
+				if err != nil {
+					return nil, err
+				}
+				rl.window = float64(n)
+			case "ipv4-prefix-length":
+				n, err := intArg(c, 1)
+				if err != nil {
+					return nil, err
+				}
+				if n > 32 {
+					return nil, c.Errf("ipv4-prefix-length must be between 1 and 32: %d", n)
+				}
+				rl.ipv4Prefix = n
+			case "ipv6-prefix-length":
+				n, err := intArg(c, 1)
+				if err != nil {
+					return nil, err
+				}
+				if n > 128 {
+					return nil, c.Errf("ipv6-prefix-length must be between 1 and 128: %d", n)
+				}
+				rl.ipv6Prefix = n
+			case "responses-per-second":
+				f, err := rateArg(c)
+				if err != nil {
+					return nil, err
+				}
+				responses = f
+			case "denials-per-second":
+				f, err := rateArg(c)
+				if err != nil {
+					return nil, err
+				}
+				denials = f
+			case "errors-per-second":
+				f, err := rateArg(c)
+				if err != nil {
+					return nil, err
+				}
+				errors = f
+			case "slip":
+				n, err := intArg(c, 0)
+				if err != nil {
+					return nil, err
+				}
+				rl.slip = n
+			case "log-only":
+				if c.NextArg() {
+					return nil, c.ArgErr()
+				}
+				rl.logOnly = true
+			case "max-table-size":
+				n, err := intArg(c, 1)
+				if err != nil {
+					return nil, err
+				}
+				rl.table = cache.New(n)
+			default:
+				return nil, c.Errf("unknown property '%s'", c.Val())
+			}
+		}
+
+		if responses < 0 {
+			return nil, c.Err("responses-per-second is required")
+		}
+		// The denial and error rates default to the rate of the responses.
+		if denials < 0 {
+			denials = responses
+		}
+		if errors < 0 {
+			errors = responses
+		}
+		rl.rates[response.Success] = responses
+		rl.rates[response.Denial] = denials
+		rl.rates[response.Error] = errors
+	}
+	return rl, nil
+}
+
+// intArg parses the single argument of a property as an integer of at least min.
+func intArg(c *caddy.Controller, min int) (int, error) {
+	property := c.Val()
+	args := c.RemainingArgs()
+	if len(args) != 1 {
+		return 0, c.ArgErr()
+	}
+	n, err := strconv.Atoi(args[0])
+	if err != nil {
+		return 0, c.Errf("invalid %s value '%s': %s", property, args[0], err)
+	}
+	if n < min {
+		return 0, c.Errf("%s can not be less than %d: %d", property, min, n)
+	}
+	return n, nil
+}
+
+// rateArg parses the single argument of a property as a rate in responses per second.
+func rateArg(c *caddy.Controller) (float64, error) {
+	property := c.Val()
+	args := c.RemainingArgs()
+	if len(args) != 1 {
+		return 0, c.ArgErr()
+	}
+	f, err := strconv.ParseFloat(args[0], 64)
+	if err != nil {
+		return 0, c.Errf("invalid %s value '%s': %s", property, args[0], err)
+	}
+	if f < 0 {
+		return 0, c.Errf("%s can not be negative: %s", property, args[0])
+	}
+	return f, nil
+}
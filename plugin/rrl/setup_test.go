@@ -0,0 +1,107 @@
+package rrl
+
+import (
+	"testing"
+
+	"github.com/coredns/coredns/plugin/pkg/response"
+
+	"github.com/mholt/caddy"
+)
+
+func TestSetup(t *testing.T) {
+	tests := []struct {
+		input     string
+		shouldErr bool
+		responses float64
+		denials   float64
+		errors    float64
+		window    float64
+		slip      int
+		logOnly   bool
+	}{
+		{`rrl {
+			responses-per-second 10
+		}`, false, 10, 10, 10, defaultWindow, defaultSlip, false},
+		{`rrl example.org {
+			responses-per-second 10
+			denials-per-second 5
+			errors-per-second 2.5
+			window 5
+			slip 0
+			log-only
+			ipv4-prefix-length 32
+			ipv6-prefix-length 64
+			max-table-size 1000
+		}`, false, 10, 5, 2.5, 5, 0, true},
+		// errors
+		{`rrl`, true, 0, 0, 0, 0, 0, false},
+		{`rrl {
+			window 5
+		}`, true, 0, 0, 0, 0, 0, false},
+		{`rrl {
+			responses-per-second -1
+		}`, true, 0, 0, 0, 0, 0, false},
+		{`rrl {
+			responses-per-second ten
+		}`, true, 0, 0, 0, 0, 0, false},
+		{`rrl {
+			responses-per-second 10
+			window 0
+		}`, true, 0, 0, 0, 0, 0, false},
+		{`rrl {
+			responses-per-second 10
+			ipv4-prefix-length 33
+		}`, true, 0, 0, 0, 0, 0, false},
+		{`rrl {
+			responses-per-second 10
+			ipv6-prefix-length 129
+		}`, true, 0, 0, 0, 0, 0, false},
+		{`rrl {
+			responses-per-second 10
+			log-only yes
+		}`, true, 0, 0, 0, 0, 0, false},
+		{`rrl {
+			responses-per-second 10
+			referrals-per-second 10
+		}`, true, 0, 0, 0, 0, 0, false},
+		{`rrl {
+			responses-per-second 10
+		}
+		rrl {
+			responses-per-second 10
+		}`, true, 0, 0, 0, 0, 0, false},
+	}
+
+	for i, test := range tests {
+		c := caddy.NewTestController("dns", test.input)
+		rl, err := parse(c)
+		if test.shouldErr {
+			if err == nil {
+				t.Errorf("Test %d: expected error but found none for input %s", i, test.input)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Test %d: expected no error but found one for input %s, got: %v", i, test.input, err)
+			continue
+		}
+		if x := rl.rates[response.Success]; x != test.responses {
+			t.Errorf("Test %d: expected responses-per-second %f, got %f", i, test.responses, x)
+		}
+		if x := rl.rates[response.Denial]; x != test.denials {
+			t.Errorf("Test %d: expected denials-per-second %f, got %f", i, test.denials, x)
+		}
+		if x := rl.rates[response.Error]; x != test.errors {
+			t.Errorf("Test %d: expected errors-per-second %f, got %f", i, test.errors, x)
+		}
+		if rl.window != test.window {
+			t.Errorf("Test %d: expected window %f, got %f", i, test.window, rl.window)
+		}
+		if rl.slip != test.slip {
+			t.Errorf("Test %d: expected slip %d, got %d", i, test.slip, rl.slip)
+		}
+		if rl.logOnly != test.logOnly {
+			t.Errorf("Test %d: expected log-only %t, got %t", i, test.logOnly, rl.logOnly)
+		}
+	}
+}
@@ -0,0 +1,121 @@
+package tcp
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/coredns/coredns/core/dnsserver"
+	"github.com/coredns/coredns/plugin"
+
+	"github.com/mholt/caddy"
+)
+
+func init() {
+	caddy.RegisterPlugin("tcp", caddy.Plugin{
+		ServerType: "dns",
+		Action:     setup,
+	})
+}
+
+func setup(c *caddy.Controller) error {
+	config := dnsserver.GetConfig(c)
+
+	if config.TCP != nil {
+		return plugin.Error("tcp", c.Errf("TCP connection handling already configured for this server instance"))
+	}
+
+	tc, err := parse(c)
+	if err != nil {
+		return plugin.Error("tcp", err)
+	}
+	config.TCP = tc
+	return nil
+}
+
+func parse(c *caddy.Controller) (*dnsserver.TCPConfig, error) {
+	tc := &dnsserver.TCPConfig{
+		Pipeline:    defaultPipeline,
+		IdleTimeout: defaultIdleTimeout,
+		ReadTimeout: defaultReadTimeout,
+	}
+	i := 0
+	for c.Next() {
+		if i > 0 {
+			return nil, plugin.ErrOnce
+		}
+		i++
+
+		if len(c.RemainingArgs()) != 0 {
+			return nil, c.ArgErr()
+		}
+		for c.NextBlock() {
+			switch c.Val() {
+			case "pipeline":
+				n, err := positiveInt(c)
+				if err != nil {
+					return nil, err
+				}
+				tc.Pipeline = n
+			case "max_connections":
+				n, err := positiveInt(c)
+				if err != nil {
+					return nil, err
+				}
+				tc.MaxConnections = n
+			case "idle_timeout":
+				d, err := positiveDuration(c)
+				if err != nil {
+					return nil, err
+				}
+				tc.IdleTimeout = d
+			case "read_timeout":
+				d, err := positiveDuration(c)
+				if err != nil {
+					return nil, err
+				}
+				tc.ReadTimeout = d
+			default:
+				return nil, c.Errf("unknown property '%s'", c.Val())
+			}
+		}
+	}
+	return tc, nil
+}
+
+func positiveInt(c *caddy.Controller) (int, error) {
+	prop := c.Val()
+	args := c.RemainingArgs()
+	if len(args) != 1 {
+		return 0, c.ArgErr()
+	}
+	n, err := strconv.Atoi(args[0])
+	if err != nil {
+		return 0, c.Errf("invalid number '%s'", args[0])
+	}
+	if n <= 0 {
+		return 0, c.Errf("%s must be positive: %d", prop, n)
+	}
+	return n, nil
+}
+
+func positiveDuration(c *caddy.Controller) (time.Duration, error) {
+	prop := c.Val()
+	args := c.RemainingArgs()
+	if len(args) != 1 {
+		return 0, c.ArgErr()
+	}
+	d, err := time.ParseDuration(args[0])
+	if err != nil {
+		return 0, c.Errf("invalid duration '%s': %s", args[0], err)
+	}
+	if d <= 0 {
+		return 0, c.Errf("%s must be positive: %s", prop, args[0])
+	}
+	return d, nil
+}
+
+const (
+	defaultPipeline    = 16
+	defaultIdleTimeout = 10 * time.Second
+	defaultReadTimeout = 2 * time.Second
+)
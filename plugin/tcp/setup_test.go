@@ -0,0 +1,79 @@
+package tcp
+
+import (
+	"testing"
+	"time"
+
+	"github.com/mholt/caddy"
+)
+
+func TestSetup(t *testing.T) {
+	tests := []struct {
+		input          string
+		shouldErr      bool
+		pipeline       int
+		idleTimeout    time.Duration
+		readTimeout    time.Duration
+		maxConnections int
+	}{
+		{`tcp`, false, defaultPipeline, defaultIdleTimeout, defaultReadTimeout, 0},
+		{`tcp {
+			pipeline 100
+			idle_timeout 30s
+			read_timeout 1s
+			max_connections 1000
+		}`, false, 100, 30 * time.Second, time.Second, 1000},
+		{`tcp {
+			max_connections 10
+		}`, false, defaultPipeline, defaultIdleTimeout, defaultReadTimeout, 10},
+		// errors
+		{`tcp 10`, true, 0, 0, 0, 0},
+		{`tcp {
+			pipeline
+		}`, true, 0, 0, 0, 0},
+		{`tcp {
+			pipeline 0
+		}`, true, 0, 0, 0, 0},
+		{`tcp {
+			max_connections many
+		}`, true, 0, 0, 0, 0},
+		{`tcp {
+			idle_timeout 0s
+		}`, true, 0, 0, 0, 0},
+		{`tcp {
+			read_timeout soon
+		}`, true, 0, 0, 0, 0},
+		{`tcp {
+			write_timeout 2s
+		}`, true, 0, 0, 0, 0},
+		{`tcp
+		tcp`, true, 0, 0, 0, 0},
+	}
+
+	for i, test := range tests {
+		c := caddy.NewTestController("dns", test.input)
+		tc, err := parse(c)
+		if test.shouldErr {
+			if err == nil {
+				t.Errorf("Test %d: expected error but found none for input %s", i, test.input)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Test %d: expected no error but found one for input %s, got: %v", i, test.input, err)
+			continue
+		}
+		if tc.Pipeline != test.pipeline {
+			t.Errorf("Test %d: expected pipeline %d, got %d", i, test.pipeline, tc.Pipeline)
+		}
+		if tc.IdleTimeout != test.idleTimeout {
+			t.Errorf("Test %d: expected idle timeout %s, got %s", i, test.idleTimeout, tc.IdleTimeout)
+		}
+		if tc.ReadTimeout != test.readTimeout {
+			t.Errorf("Test %d: expected read timeout %s, got %s", i, test.readTimeout, tc.ReadTimeout)
+		}
+		if tc.MaxConnections != test.maxConnections {
+			t.Errorf("Test %d: expected max connections %d, got %d", i, test.maxConnections, tc.MaxConnections)
+		}
+	}
+}
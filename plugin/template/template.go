@@ -85,8 +85,13 @@ func (h Handler) ServeDNS(ctx context.Context, w dns.ResponseWriter, r *dns.Msg)
 			}
 			msg.Answer = append(msg.Answer, rr)
 			if template.upstream != nil && (state.QType() == dns.TypeA || state.QType() == dns.TypeAAAA) && rr.Header().Rrtype == dns.TypeCNAME {
-				up, _ := template.upstream.Lookup(state, rr.(*dns.CNAME).Target, state.QType())
-				msg.Answer = append(msg.Answer, up.Answer...)
+				up, err := template.upstream.Lookup(state, rr.(*dns.CNAME).Target, state.QType())
+				if err != nil && ctx.Err() != nil {
+					return dns.RcodeServerFailure, err
+				}
+				if up != nil {
+					msg.Answer = append(msg.Answer, up.Answer...)
+				}
 			}
 		}
 		for _, additional := range template.additional {
@@ -9,6 +9,7 @@ import (
 
 	"github.com/coredns/coredns/plugin/pkg/dnstest"
 	"github.com/coredns/coredns/plugin/pkg/fall"
+	"github.com/coredns/coredns/plugin/pkg/upstream"
 	"github.com/coredns/coredns/plugin/test"
 
 	"github.com/mholt/caddy"
@@ -461,3 +462,38 @@ func TestMultiSection(t *testing.T) {
 }
 
 const rcodeFallthrough = 3841 // reserved for private use, used to indicate a fallthrough
+
+func TestCanceledUpstream(t *testing.T) {
+	u, err := upstream.New(nil)
+	if err != nil {
+		t.Fatalf("Expected no error, got %s", err)
+	}
+	h := Handler{
+		Zones: []string{"example."},
+		Templates: []template{{
+			regex:    []*regexp.Regexp{regexp.MustCompile(".*")},
+			answer:   []*gotmpl.Template{gotmpl.Must(gotmpl.New("answer").Parse("{{ .Name }} 60 IN CNAME target.example."))},
+			qclass:   dns.ClassANY,
+			qtype:    dns.TypeANY,
+			zones:    []string{"example."},
+			upstream: &u,
+		}},
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	r := new(dns.Msg)
+	r.SetQuestion("www.example.", dns.TypeA)
+	rec := dnstest.NewRecorder(&test.ResponseWriter{})
+	rcode, err := h.ServeDNS(ctx, rec, r)
+	if err != context.Canceled {
+		t.Errorf("Expected %s, got %v", context.Canceled, err)
+	}
+	if rcode != dns.RcodeServerFailure {
+		t.Errorf("Expected rcode %d, got %d", dns.RcodeServerFailure, rcode)
+	}
+	if rec.Msg != nil {
+		t.Errorf("Expected no reply to be written, got %s", rec.Msg)
+	}
+}
@@ -0,0 +1,51 @@
+package timeout
+
+import (
+	"time"
+
+	"github.com/coredns/coredns/core/dnsserver"
+	"github.com/coredns/coredns/plugin"
+
+	"github.com/mholt/caddy"
+)
+
+func init() {
+	caddy.RegisterPlugin("timeout", caddy.Plugin{
+		ServerType: "dns",
+		Action:     setup,
+	})
+}
+
+func setup(c *caddy.Controller) error {
+	d, err := parse(c)
+	if err != nil {
+		return plugin.Error("timeout", err)
+	}
+	dnsserver.GetConfig(c).Timeout = d
+	return nil
+}
+
+func parse(c *caddy.Controller) (time.Duration, error) {
+	var d time.Duration
+	i := 0
+	for c.Next() {
+		if i > 0 {
+			return 0, plugin.ErrOnce
+		}
+		i++
+
+		args := c.RemainingArgs()
+		if len(args) != 1 {
+			return 0, c.ArgErr()
+		}
+		var err error
+		d, err = time.ParseDuration(args[0])
+		if err != nil {
+			return 0, c.Errf("invalid duration '%s': %s", args[0], err)
+		}
+		if d <= 0 {
+			return 0, c.Errf("timeout must be positive: %s", args[0])
+		}
+	}
+	return d, nil
+}
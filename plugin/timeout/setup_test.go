@@ -0,0 +1,47 @@
+package timeout
+
+import (
+	"testing"
+	"time"
+
+	"github.com/coredns/coredns/core/dnsserver"
+
+	"github.com/mholt/caddy"
+)
+
+func TestSetup(t *testing.T) {
+	tests := []struct {
+		input     string
+		shouldErr bool
+		timeout   time.Duration
+	}{
+		{`timeout 2s`, false, 2 * time.Second},
+		{`timeout 500ms`, false, 500 * time.Millisecond},
+		// errors
+		{`timeout`, true, 0},
+		{`timeout 0s`, true, 0},
+		{`timeout -1s`, true, 0},
+		{`timeout soon`, true, 0},
+		{`timeout 1s 2s`, true, 0},
+		{`timeout 1s
+		timeout 2s`, true, 0},
+	}
+
+	for i, test := range tests {
+		c := caddy.NewTestController("dns", test.input)
+		err := setup(c)
+		if test.shouldErr {
+			if err == nil {
+				t.Errorf("Test %d: expected error but found none for input %s", i, test.input)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Test %d: expected no error but found one for input %s, got: %v", i, test.input, err)
+			continue
+		}
+		if x := dnsserver.GetConfig(c).Timeout; x != test.timeout {
+			t.Errorf("Test %d: expected timeout %s, got %s", i, test.timeout, x)
+		}
+	}
+}
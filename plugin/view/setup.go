@@ -0,0 +1,91 @@
+package view
+
+import (
+	"regexp"
+
+	"github.com/coredns/coredns/core/dnsserver"
+	"github.com/coredns/coredns/plugin"
+	"github.com/coredns/coredns/plugin/metadata"
+	"github.com/coredns/coredns/plugin/pkg/parse"
+
+	"github.com/mholt/caddy"
+)
+
+func init() {
+	caddy.RegisterPlugin("view", caddy.Plugin{
+		ServerType: "dns",
+		Action:     setup,
+	})
+}
+
+func setup(c *caddy.Controller) error {
+	config := dnsserver.GetConfig(c)
+	if config.ViewFunc != nil {
+		return plugin.Error("view", c.Errf("view already defined for this server block: %s", config.ViewName))
+	}
+
+	v, err := parseView(c)
+	if err != nil {
+		return plugin.Error("view", err)
+	}
+
+	config.ViewName = v.Name
+	config.ViewFunc = v.Filter
+	return nil
+}
+
+func parseView(c *caddy.Controller) (*View, error) {
+	v := &View{}
+	i := 0
+	for c.Next() {
+		if i > 0 {
+			return nil, plugin.ErrOnce
+		}
+		i++
+
+		args := c.RemainingArgs()
+		if len(args) != 1 {
+			return nil, c.ArgErr()
+		}
+		v.Name = args[0]
+
+		for c.NextBlock() {
+			switch c.Val() {
+			case "net", "ecs":
+				property := c.Val()
+				args := c.RemainingArgs()
+				if len(args) == 0 {
+					return nil, c.ArgErr()
+				}
+				nets, err := parse.Nets(args...)
+				if err != nil {
+					return nil, c.Err(err.Error())
+				}
+				if property == "net" {
+					v.conditions = append(v.conditions, netCondition(nets))
+				} else {
+					v.conditions = append(v.conditions, ecsCondition(nets))
+				}
+			case "metadata":
+				args := c.RemainingArgs()
+				if len(args) != 2 {
+					return nil, c.ArgErr()
+				}
+				if !metadata.IsLabel(args[0]) {
+					return nil, c.Errf("invalid metadata label: %s", args[0])
+				}
+				re, err := regexp.Compile(args[1])
+				if err != nil {
+					return nil, c.Err(err.Error())
+				}
+				v.conditions = append(v.conditions, metadataCondition(args[0], re))
+			default:
+				return nil, c.Errf("unknown property '%s'", c.Val())
+			}
+		}
+		if len(v.conditions) == 0 {
+			return nil, c.Errf("view %s has no conditions", v.Name)
+		}
+	}
+	return v, nil
+}
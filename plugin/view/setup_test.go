@@ -0,0 +1,61 @@
+package view
+
+import (
+	"testing"
+
+	"github.com/mholt/caddy"
+)
+
+func TestSetup(t *testing.T) {
+	tests := []struct {
+		input     string
+		shouldErr bool
+	}{
+		{`view internal {
+			net 10.0.0.0/8 192.168.0.1
+		}`, false},
+		{`view internal {
+			net 10.0.0.0/8
+			ecs 2001:db8::/32
+			metadata kubernetes/client-namespace ^default$
+		}`, false},
+		// errors
+		{`view`, true},
+		{`view internal`, true},
+		{`view internal external {
+			net 10.0.0.0/8
+		}`, true},
+		{`view internal {
+			net 10.0.0.0/33
+		}`, true},
+		{`view internal {
+			net
+		}`, true},
+		{`view internal {
+			metadata client-namespace default
+		}`, true},
+		{`view internal {
+			metadata kubernetes/client-namespace (
+		}`, true},
+		{`view internal {
+			source 10.0.0.0/8
+		}`, true},
+		{`view internal {
+			net 10.0.0.0/8
+		}
+		view external {
+			net 0.0.0.0/0
+		}`, true},
+	}
+
+	for i, test := range tests {
+		c := caddy.NewTestController("dns", test.input)
+		err := setup(c)
+		if test.shouldErr && err == nil {
+			t.Errorf("Test %d: expected error but found none for input %s", i, test.input)
+		}
+		if !test.shouldErr && err != nil {
+			t.Errorf("Test %d: expected no error but found one for input %s, got: %v", i, test.input, err)
+		}
+	}
+}
@@ -0,0 +1,82 @@
+// Package view implements a plugin that selects the server block for a query based on the client's
+// source address, its EDNS0 client subnet or metadata. This allows different server blocks (views)
+// to serve the same zone on the same address.
+package view
+
+import (
+	"context"
+	"net"
+	"regexp"
+
+	"github.com/coredns/coredns/plugin/metadata"
+	"github.com/coredns/coredns/request"
+
+	"github.com/miekg/dns"
+)
+
+// View holds the conditions a query must match to be handled by the server block the view is
+// defined in.
+type View struct {
+	Name       string
+	conditions []condition
+}
+
+// condition is a single predicate of a view.
+type condition func(ctx context.Context, state request.Request) bool
+
+// Filter returns true when all conditions of the view match the query.
+func (v *View) Filter(ctx context.Context, state request.Request) bool {
+	for _, c := range v.conditions {
+		if !c(ctx, state) {
+			return false
+		}
+	}
+	return true
+}
+
+// netCondition matches if the client's source address is in one of nets.
+func netCondition(nets []*net.IPNet) condition {
+	return func(ctx context.Context, state request.Request) bool {
+		return contains(nets, net.ParseIP(state.IP()))
+	}
+}
+
+// ecsCondition matches if the query carries an EDNS0 client subnet option whose address is in one of nets.
+func ecsCondition(nets []*net.IPNet) condition {
+	return func(ctx context.Context, state request.Request) bool {
+		o := state.Req.IsEdns0()
+		if o == nil {
+			return false
+		}
+		for _, opt := range o.Option {
+			if e, ok := opt.(*dns.EDNS0_SUBNET); ok {
+				return contains(nets, e.Address)
+			}
+		}
+		return false
+	}
+}
+
+// metadataCondition matches if the value of the metadata label matches re. The metadata plugin
+// must be enabled for this to work.
+func metadataCondition(label string, re *regexp.Regexp) condition {
+	return func(ctx context.Context, state request.Request) bool {
+		f := metadata.ValueFunc(ctx, label)
+		if f == nil {
+			return false
+		}
+		return re.MatchString(f())
+	}
+}
+
+func contains(nets []*net.IPNet, ip net.IP) bool {
+	if ip == nil {
+		return false
+	}
+	for _, n := range nets {
+		if n.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,89 @@
+package view
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	"github.com/coredns/coredns/plugin/metadata"
+	"github.com/coredns/coredns/plugin/test"
+	"github.com/coredns/coredns/request"
+
+	"github.com/mholt/caddy"
+	"github.com/miekg/dns"
+)
+
+type testProvider map[string]metadata.Func
+
+func (tp testProvider) Metadata(ctx context.Context, state request.Request) context.Context {
+	for k, v := range tp {
+		metadata.SetValueFunc(ctx, k, v)
+	}
+	return ctx
+}
+
+func TestFilter(t *testing.T) {
+	tests := []struct {
+		input    string
+		ecs      string
+		expected bool
+	}{
+		// test.ResponseWriter uses 10.240.0.1 as the client address.
+		{`view internal {
+			net 10.0.0.0/8
+		}`, "", true},
+		{`view internal {
+			net 192.168.0.0/16 10.240.0.1
+		}`, "", true},
+		{`view internal {
+			net 192.168.0.0/16
+		}`, "", false},
+		{`view internal {
+			ecs 192.168.0.0/16
+		}`, "", false},
+		{`view internal {
+			ecs 192.168.0.0/16
+		}`, "192.168.1.0", true},
+		{`view internal {
+			net 10.0.0.0/8
+			ecs 192.168.0.0/16
+		}`, "172.16.1.0", false},
+		{`view internal {
+			metadata test/namespace ^default$
+		}`, "", true},
+		{`view internal {
+			metadata test/namespace ^kube-system$
+		}`, "", false},
+		{`view internal {
+			metadata test/unknown .*
+		}`, "", false},
+	}
+
+	md := &metadata.Metadata{Zones: []string{"."}, Providers: []metadata.Provider{
+		testProvider{"test/namespace": func() string { return "default" }},
+	}}
+
+	for i, tc := range tests {
+		c := caddy.NewTestController("dns", tc.input)
+		v, err := parseView(c)
+		if err != nil {
+			t.Fatalf("Test %d: failed to parse: %s", i, err)
+		}
+
+		m := new(dns.Msg)
+		m.SetQuestion("example.org.", dns.TypeA)
+		if tc.ecs != "" {
+			o := new(dns.OPT)
+			o.Hdr.Name = "."
+			o.Hdr.Rrtype = dns.TypeOPT
+			o.Option = append(o.Option, &dns.EDNS0_SUBNET{Code: dns.EDNS0SUBNET, Family: 1, SourceNetmask: 24, Address: net.ParseIP(tc.ecs)})
+			m.Extra = append(m.Extra, o)
+		}
+		state := request.Request{W: &test.ResponseWriter{}, Req: m}
+		ctx := md.Collect(context.TODO(), state)
+
+		if got := v.Filter(ctx, state); got != tc.expected {
+			t.Errorf("Test %d: expected %t, got %t", i, tc.expected, got)
+		}
+	}
+}
@@ -36,9 +36,9 @@ type Request struct {
 }
 
 // NewWithQuestion returns a new request based on the old, but with a new question
-// section in the request.
+// section in the request. The context of the old request is kept.
 func (r *Request) NewWithQuestion(name string, typ uint16) Request {
-	req1 := Request{W: r.W, Req: r.Req.Copy()}
+	req1 := Request{W: r.W, Req: r.Req.Copy(), Context: r.Context}
 	req1.Req.Question[0] = dns.Question{Name: dns.Fqdn(name), Qclass: dns.ClassINET, Qtype: typ}
 	return req1
 }
@@ -329,6 +329,19 @@ func (r *Request) Scrub(reply *dns.Msg) *dns.Msg {
 	return reply
 }
 
+// Pad pads the reply to a multiple of 468 octets as recommended in RFC 8467, but never beyond the
+// client's buffer size. Nothing is done when the request doesn't have an OPT RR. An OPT RR is added
+// to the reply when it doesn't have one.
+func (r *Request) Pad(reply *dns.Msg) {
+	if r.Req.IsEdns0() == nil {
+		return
+	}
+	if reply.IsEdns0() == nil {
+		reply.SetEdns0(uint16(r.Size()), r.Do())
+	}
+	edns.Pad(reply, edns.ReplyPaddingBlock, r.Size())
+}
+
 // Type returns the type of the question as a string. If the request is malformed the empty string is returned.
 func (r *Request) Type() string {
 	if r.Req == nil {
@@ -207,6 +207,28 @@ func TestRequestScrubAnswerExact(t *testing.T) {
 	}
 }
 
+func TestRequestPad(t *testing.T) {
+	m := new(dns.Msg)
+	m.SetQuestion("example.com.", dns.TypeA)
+	req := Request{W: &test.ResponseWriter{TCP: true}, Req: m}
+
+	reply := new(dns.Msg)
+	reply.SetReply(m)
+	reply.Answer = append(reply.Answer, test.A("example.com. 10 IN A 127.0.0.1"))
+
+	req.Pad(reply)
+	if reply.IsEdns0() != nil {
+		t.Errorf("Want no padding when the request doesn't have an OPT RR")
+	}
+
+	m.SetEdns0(4096, false)
+	req = Request{W: &test.ResponseWriter{TCP: true}, Req: m}
+	req.Pad(reply)
+	if got := reply.Len(); got%468 != 0 {
+		t.Errorf("Want reply padded to a multiple of 468 bytes, got %d bytes", got)
+	}
+}
+
 func TestRequestMatch(t *testing.T) {
 	st := testRequest()
 	reply := new(dns.Msg)
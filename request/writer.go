@@ -1,20 +1,38 @@
 package request
 
-import "github.com/miekg/dns"
+import (
+	"github.com/coredns/coredns/plugin/pkg/edns"
+
+	"github.com/miekg/dns"
+)
 
 // ScrubWriter will, when writing the message, call scrub to make it fit the client's buffer.
 type ScrubWriter struct {
 	dns.ResponseWriter
 	req *dns.Msg // original request
+	pad bool     // pad replies, see NewPaddingScrubWriter
 }
 
 // NewScrubWriter returns a new and initialized ScrubWriter.
-func NewScrubWriter(req *dns.Msg, w dns.ResponseWriter) *ScrubWriter { return &ScrubWriter{w, req} }
+func NewScrubWriter(req *dns.Msg, w dns.ResponseWriter) *ScrubWriter {
+	return &ScrubWriter{w, req, false}
+}
+
+// NewPaddingScrubWriter returns a ScrubWriter that also pads the replies to requests that have an
+// OPT RR, as described in RFC 7830 and RFC 8467. This should only be used on encrypted transports.
+func NewPaddingScrubWriter(req *dns.Msg, w dns.ResponseWriter) *ScrubWriter {
+	return &ScrubWriter{w, req, true}
+}
 
 // WriteMsg overrides the default implementation of the underlaying dns.ResponseWriter and calls
-// scrub on the message m and will then write it to the client.
+// scrub on the message m and will then write it to the client. Padding options are hop-by-hop, one
+// in m is removed, and, when padding, a new one is added.
 func (s *ScrubWriter) WriteMsg(m *dns.Msg) error {
 	state := Request{Req: s.req, W: s.ResponseWriter}
+	edns.RemovePadding(m)
 	n := state.Scrub(m)
+	if s.pad {
+		state.Pad(n)
+	}
 	return s.ResponseWriter.WriteMsg(n)
 }
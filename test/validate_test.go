@@ -0,0 +1,60 @@
+package test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/coredns/coredns/core/dnsserver"
+)
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		corefile string
+		err      string // substring of the expected error, empty when valid
+	}{
+		// Validate doesn't start any servers, so the privileged port 53 can be used.
+		{`example.org:53 {
+    whoami
+}`, ""},
+		{`example.org:53 {
+    whoami
+    cache 3600 {
+        success 10
+    }
+}`, ""},
+		// The error points at the line of the invalid directive.
+		{`example.org:53 {
+    cache 3600
+    whoami extra
+}`, "Corefile:3"},
+		{`example.org:53 {
+    whoamy
+}`, "Corefile:2"},
+		{`example.org:53 {
+    whoami
+}
+example.org:53 {
+    whoami
+}`, "example.org."},
+		{`quic://example.org {
+    whoami
+}`, "no TLS configuration"},
+	}
+
+	for i, tc := range tests {
+		err := dnsserver.Validate(NewInput(tc.corefile))
+		if tc.err == "" {
+			if err != nil {
+				t.Errorf("Test %d: expected no error, got %s", i, err)
+			}
+			continue
+		}
+		if err == nil {
+			t.Errorf("Test %d: expected error containing %q, got none", i, tc.err)
+			continue
+		}
+		if !strings.Contains(err.Error(), tc.err) {
+			t.Errorf("Test %d: expected error containing %q, got %s", i, tc.err, err)
+		}
+	}
+}